@@ -2,36 +2,92 @@ package receiver
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/achawki/webhook-receiver/internal/handler"
+	"github.com/achawki/webhook-receiver/internal/model"
 	"github.com/achawki/webhook-receiver/internal/storage"
 )
 
 const (
-	defaultStorePath      = "webhook-receiver.db"
-	defaultListenAddr     = ":8080"
-	webhookCleanupPeriod  = time.Minute
-	defaultShutdownGrace  = 5 * time.Second
-	encryptionKeyEnvName  = "WEBHOOK_RECEIVER_ENCRYPTION_KEY"
-	publicBaseURLEnvName  = "WEBHOOK_RECEIVER_PUBLIC_BASE_URL"
-	clientIPHeaderEnvName = "WEBHOOK_RECEIVER_CLIENT_IP_HEADER"
+	storeDriverSQLite   = "sqlite"
+	storeDriverPostgres = "postgres"
+	storeDriverMemory   = "memory"
+)
+
+const (
+	defaultStorePath       = "webhook-receiver.db"
+	defaultListenAddr      = ":8080"
+	webhookCleanupPeriod   = time.Minute
+	defaultShutdownGrace   = 5 * time.Second
+	encryptionKeyEnvName   = "WEBHOOK_RECEIVER_ENCRYPTION_KEY"
+	previousKeysEnvName    = "WEBHOOK_RECEIVER_PREVIOUS_ENCRYPTION_KEYS"
+	encryptMessagesEnvName = "WEBHOOK_RECEIVER_ENCRYPT_MESSAGES"
+	publicBaseURLEnvName   = "WEBHOOK_RECEIVER_PUBLIC_BASE_URL"
+	clientIPHeaderEnvName  = "WEBHOOK_RECEIVER_CLIENT_IP_HEADER"
+	privateTargetsEnvName  = "WEBHOOK_RECEIVER_ALLOW_PRIVATE_TARGETS"
+	storeDriverEnvName     = "WEBHOOK_RECEIVER_STORE_DRIVER"
+	storeDSNEnvName        = "WEBHOOK_RECEIVER_STORE_DSN"
+	maxWebhookTTLEnvName   = "WEBHOOK_RECEIVER_MAX_WEBHOOK_TTL"
+	maxMessagesEnvName     = "WEBHOOK_RECEIVER_MAX_MESSAGES_PER_WEBHOOK"
+	tlsCertFileEnvName     = "WEBHOOK_RECEIVER_TLS_CERT_FILE"
+	tlsKeyFileEnvName      = "WEBHOOK_RECEIVER_TLS_KEY_FILE"
+	tlsClientCertsEnvName  = "WEBHOOK_RECEIVER_TLS_CLIENT_CERTS"
+	tlsClientCAFileEnvName = "WEBHOOK_RECEIVER_TLS_CLIENT_CA_FILE"
+	dropHeadersEnvName     = "WEBHOOK_RECEIVER_DROP_HEADERS"
+	maskHeadersEnvName     = "WEBHOOK_RECEIVER_MASK_HEADERS"
 )
 
 // Config contains runtime configuration for the webhook receiver.
 type Config struct {
-	ListenAddr     string
-	StorePath      string
-	EncryptionKey  string
-	PublicBaseURL  string
-	ClientIPHeader string
+	ListenAddr string
+	// StoreDriver selects the storage backend: "sqlite" (default), "postgres"
+	// or "memory". The memory store keeps nothing across restarts and needs
+	// neither a store path nor an encryption key.
+	StoreDriver string
+	// StoreDSN is the connection string of the postgres driver.
+	StoreDSN      string
+	StorePath     string
+	EncryptionKey string
+	// PreviousEncryptionKeys are retired keys that still decrypt stored secrets
+	// until they are re-encrypted with RotateKeys.
+	PreviousEncryptionKeys []string
+	// EncryptMessages encrypts captured payloads and headers at rest.
+	EncryptMessages     bool
+	PublicBaseURL       string
+	ClientIPHeader      string
+	AllowPrivateTargets bool
+	// MaxWebhookTTL bounds the TTL a webhook may request. Zero keeps the 48 hour default.
+	MaxWebhookTTL time.Duration
+	// MaxMessagesPerWebhook bounds the message cap a webhook may request. Zero keeps the default of 100.
+	MaxMessagesPerWebhook int
+	// TLSCertFile and TLSKeyFile serve HTTPS instead of plain HTTP when both are set.
+	TLSCertFile string
+	TLSKeyFile  string
+	// TLSClientCerts asks clients for a certificate during the TLS handshake
+	// without verifying it, so webhooks can pin their own CA or fingerprint.
+	TLSClientCerts bool
+	// TLSClientCAFile rejects handshakes with client certificates that were
+	// not issued by one of its PEM certificates. It implies TLSClientCerts.
+	TLSClientCAFile string
+	// DropHeaders lists header names or "Prefix-*" patterns that are not
+	// captured. Nil keeps the default list of proxy headers; an empty list
+	// captures all of them.
+	DropHeaders []string
+	// MaskHeaders lists header patterns whose captured values keep only their
+	// last four characters.
+	MaskHeaders []string
 }
 
 // Server holds the HTTP handler stack and persistent resources.
@@ -39,7 +95,7 @@ type Server struct {
 	handler     *handler.Handler
 	mux         *http.ServeMux
 	httpServer  *http.Server
-	store       *storage.SQLiteStore
+	store       storage.Store
 	cleanupStop chan struct{}
 	cleanupDone chan struct{}
 	closeOnce   sync.Once
@@ -58,11 +114,24 @@ func Setup() *Server {
 // LoadConfigFromEnv reads runtime configuration from environment variables.
 func LoadConfigFromEnv() Config {
 	return Config{
-		ListenAddr:     strings.TrimSpace(os.Getenv("WEBHOOK_RECEIVER_LISTEN_ADDR")),
-		StorePath:      persistentStorePath(),
-		EncryptionKey:  persistentStoreEncryptionKey(),
-		PublicBaseURL:  strings.TrimSpace(os.Getenv(publicBaseURLEnvName)),
-		ClientIPHeader: strings.TrimSpace(os.Getenv(clientIPHeaderEnvName)),
+		ListenAddr:             strings.TrimSpace(os.Getenv("WEBHOOK_RECEIVER_LISTEN_ADDR")),
+		StoreDriver:            strings.TrimSpace(os.Getenv(storeDriverEnvName)),
+		StoreDSN:               strings.TrimSpace(os.Getenv(storeDSNEnvName)),
+		StorePath:              persistentStorePath(),
+		EncryptionKey:          persistentStoreEncryptionKey(),
+		PreviousEncryptionKeys: envList(previousKeysEnvName),
+		EncryptMessages:        envBool(encryptMessagesEnvName),
+		PublicBaseURL:          strings.TrimSpace(os.Getenv(publicBaseURLEnvName)),
+		ClientIPHeader:         strings.TrimSpace(os.Getenv(clientIPHeaderEnvName)),
+		AllowPrivateTargets:    envBool(privateTargetsEnvName),
+		MaxWebhookTTL:          envDuration(maxWebhookTTLEnvName),
+		MaxMessagesPerWebhook:  envInt(maxMessagesEnvName),
+		TLSCertFile:            strings.TrimSpace(os.Getenv(tlsCertFileEnvName)),
+		TLSKeyFile:             strings.TrimSpace(os.Getenv(tlsKeyFileEnvName)),
+		TLSClientCerts:         envBool(tlsClientCertsEnvName),
+		TLSClientCAFile:        strings.TrimSpace(os.Getenv(tlsClientCAFileEnvName)),
+		DropHeaders:            envOptionalList(dropHeadersEnvName),
+		MaskHeaders:            envList(maskHeadersEnvName),
 	}
 }
 
@@ -70,47 +139,180 @@ func LoadConfigFromEnv() Config {
 func NewServer(config Config) (*Server, error) {
 	log.Println("Setting up webhook receiver")
 
-	if strings.TrimSpace(config.EncryptionKey) == "" {
-		return nil, errors.New(encryptionKeyEnvName + " must be set to a base64 or hex encoded 32-byte key; generate one with: openssl rand -base64 32")
-	}
-
 	listenAddr := strings.TrimSpace(config.ListenAddr)
 	if listenAddr == "" {
 		listenAddr = defaultListenAddr
 	}
 
-	storePath := strings.TrimSpace(config.StorePath)
-	if storePath == "" {
-		storePath = defaultStorePath
+	publicBaseURL, err := normalizePublicBaseURL(config.PublicBaseURL)
+	if err != nil {
+		return nil, err
 	}
 
-	publicBaseURL, err := normalizePublicBaseURL(config.PublicBaseURL)
+	if config.MaxWebhookTTL < 0 {
+		return nil, errors.New(maxWebhookTTLEnvName + " must not be negative")
+	}
+	if config.MaxMessagesPerWebhook < 0 {
+		return nil, errors.New(maxMessagesEnvName + " must not be negative")
+	}
+
+	tlsConfig, err := newTLSConfig(config)
 	if err != nil {
 		return nil, err
 	}
 
+	dropHeaders := config.DropHeaders
+	if dropHeaders == nil {
+		dropHeaders = model.DefaultDroppedHeaders()
+	}
+	if err := model.ValidateHeaderPatterns(model.NewHeaderPatterns(dropHeaders)); err != nil {
+		return nil, fmt.Errorf("%s: %w", dropHeadersEnvName, err)
+	}
+	if err := model.ValidateHeaderPatterns(model.NewHeaderPatterns(config.MaskHeaders)); err != nil {
+		return nil, fmt.Errorf("%s: %w", maskHeadersEnvName, err)
+	}
+
 	server := &Server{mux: http.NewServeMux()}
-	persistentStore, err := storage.NewSQLiteStore(storePath, config.EncryptionKey)
+	store, err := openStore(config)
 	if err != nil {
 		return nil, err
 	}
-	log.Printf("Using persistent store at %s", storePath)
-	server.store = persistentStore
+	server.store = store
 
 	handlerOptions := []handler.Option{
 		handler.WithPublicBaseURL(publicBaseURL),
 		handler.WithClientIPHeader(config.ClientIPHeader),
+		handler.WithPrivateOutboundTargets(config.AllowPrivateTargets),
+		handler.WithHeaderRedaction(dropHeaders, config.MaskHeaders),
+		handler.WithRetentionLimits(model.RetentionLimits{
+			MaxTTL:      config.MaxWebhookTTL,
+			MaxMessages: config.MaxMessagesPerWebhook,
+		}),
 	}
-	server.handler = handler.NewHandler(persistentStore, handlerOptions...)
+	server.handler = handler.NewHandler(store, handlerOptions...)
 	server.handler.Register(server.mux)
 	server.httpServer = &http.Server{
-		Addr:    listenAddr,
-		Handler: server.mux,
+		Addr:      listenAddr,
+		Handler:   server.mux,
+		TLSConfig: tlsConfig,
 	}
+	server.httpServer.RegisterOnShutdown(server.handler.Close)
 
 	return server, nil
 }
 
+// newTLSConfig loads the server certificate and the client certificate
+// settings. It returns nil when the receiver serves plain HTTP.
+func newTLSConfig(config Config) (*tls.Config, error) {
+	certFile := strings.TrimSpace(config.TLSCertFile)
+	keyFile := strings.TrimSpace(config.TLSKeyFile)
+	clientCAFile := strings.TrimSpace(config.TLSClientCAFile)
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New(tlsCertFileEnvName + " and " + tlsKeyFileEnvName + " must be both set or both empty")
+	}
+	if certFile == "" {
+		if config.TLSClientCerts || clientCAFile != "" {
+			return nil, errors.New("client certificates require " + tlsCertFileEnvName + " and " + tlsKeyFileEnvName)
+		}
+		return nil, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load TLS certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{certificate},
+	}
+
+	switch {
+	case clientCAFile != "":
+		caPEM, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read TLS client CA file: %w", err)
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caPEM) {
+			return nil, errors.New(tlsClientCAFileEnvName + " must contain at least one PEM certificate")
+		}
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	case config.TLSClientCerts:
+		tlsConfig.ClientAuth = tls.RequestClientCert
+	}
+
+	return tlsConfig, nil
+}
+
+// openStore creates the storage backend selected by the configuration. The
+// persistent stores require an encryption key for HMAC secrets.
+func openStore(config Config) (storage.Store, error) {
+	driver := strings.ToLower(strings.TrimSpace(config.StoreDriver))
+	if driver == storeDriverMemory {
+		log.Println("Using in-memory store; captured data is lost on restart")
+		return storage.NewMemoryStore(), nil
+	}
+
+	if strings.TrimSpace(config.EncryptionKey) == "" {
+		return nil, errors.New(encryptionKeyEnvName + " must be set to a base64 or hex encoded 32-byte key; generate one with: openssl rand -base64 32")
+	}
+
+	storeOptions := []storage.StoreOption{
+		storage.WithPreviousEncryptionKeys(config.PreviousEncryptionKeys...),
+		storage.WithMessageEncryption(config.EncryptMessages),
+	}
+	switch driver {
+	case "", storeDriverSQLite:
+		storePath := strings.TrimSpace(config.StorePath)
+		if storePath == "" {
+			storePath = defaultStorePath
+		}
+
+		store, err := storage.NewSQLiteStore(storePath, config.EncryptionKey, storeOptions...)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Using persistent store at %s", storePath)
+		return store, nil
+	case storeDriverPostgres:
+		if strings.TrimSpace(config.StoreDSN) == "" {
+			return nil, errors.New(storeDSNEnvName + " must be set when " + storeDriverEnvName + " is postgres")
+		}
+
+		store, err := storage.NewPostgresStore(config.StoreDSN, config.EncryptionKey, storeOptions...)
+		if err != nil {
+			return nil, err
+		}
+		log.Println("Using PostgreSQL store")
+		return store, nil
+	default:
+		return nil, fmt.Errorf("%s must be %q, %q or %q, got %q", storeDriverEnvName, storeDriverSQLite, storeDriverPostgres, storeDriverMemory, config.StoreDriver)
+	}
+}
+
+// RotateKeys re-encrypts every stored secret and encrypted message of the
+// configured store with the current encryption key. Afterwards the previous keys can be removed.
+func RotateKeys(config Config) (int, error) {
+	store, err := openStore(config)
+	if err != nil {
+		return 0, err
+	}
+
+	rotator, ok := store.(storage.KeyRotator)
+	if !ok {
+		closeErr := store.Close()
+		return 0, errors.Join(fmt.Errorf("the %s store does not encrypt data at rest", strings.TrimSpace(config.StoreDriver)), closeErr)
+	}
+
+	rotated, err := rotator.RotateEncryptionKeys()
+	if closeErr := store.Close(); err == nil {
+		err = closeErr
+	}
+
+	return rotated, err
+}
+
 // Start starts the webhook receiver
 func (s *Server) Start() {
 	if err := s.Run(context.Background()); err != nil {
@@ -128,6 +330,11 @@ func (s *Server) Run(ctx context.Context) error {
 
 	errCh := make(chan error, 1)
 	go func() {
+		if s.httpServer.TLSConfig != nil {
+			log.Printf("Starting webhook receiver with TLS on %s...", s.httpServer.Addr)
+			errCh <- s.httpServer.ListenAndServeTLS("", "")
+			return
+		}
 		log.Printf("Starting webhook receiver on %s...", s.httpServer.Addr)
 		errCh <- s.httpServer.ListenAndServe()
 	}()
@@ -241,6 +448,68 @@ func persistentStoreEncryptionKey() string {
 	return strings.TrimSpace(os.Getenv(encryptionKeyEnvName))
 }
 
+func envBool(name string) bool {
+	enabled, err := strconv.ParseBool(strings.TrimSpace(os.Getenv(name)))
+	return err == nil && enabled
+}
+
+// envDuration parses a Go duration such as "168h". Invalid values are logged and ignored.
+func envDuration(name string) time.Duration {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return 0
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Ignoring invalid %s %q: %s", name, value, err)
+		return 0
+	}
+
+	return duration
+}
+
+// envList parses a comma-separated setting, dropping empty entries.
+func envList(name string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(name), ",") {
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			values = append(values, trimmed)
+		}
+	}
+
+	return values
+}
+
+// envOptionalList parses a comma-separated setting like envList, but tells an
+// unset variable (nil) apart from one set to an empty list.
+func envOptionalList(name string) []string {
+	if _, ok := os.LookupEnv(name); !ok {
+		return nil
+	}
+	if values := envList(name); values != nil {
+		return values
+	}
+
+	return []string{}
+}
+
+// envInt parses an integer setting. Invalid values are logged and ignored.
+func envInt(name string) int {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return 0
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Ignoring invalid %s %q: %s", name, value, err)
+		return 0
+	}
+
+	return number
+}
+
 func normalizePublicBaseURL(publicBaseURL string) (string, error) {
 	trimmedBaseURL := strings.TrimRight(strings.TrimSpace(publicBaseURL), "/")
 	if trimmedBaseURL == "" {
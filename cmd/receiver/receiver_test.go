@@ -2,19 +2,32 @@ package receiver
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/achawki/webhook-receiver/internal/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 const testEncryptionKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+const otherEncryptionKey = "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="
 
 func TestLoadConfigFromEnvAndSetup(t *testing.T) {
 	storePath := filepath.Join(t.TempDir(), "webhook-receiver.db")
@@ -23,6 +36,15 @@ func TestLoadConfigFromEnvAndSetup(t *testing.T) {
 	t.Setenv(encryptionKeyEnvName, "  "+testEncryptionKey+"  ")
 	t.Setenv(publicBaseURLEnvName, " https://hooks.example.com/base/ ")
 	t.Setenv(clientIPHeaderEnvName, " Fly-Client-IP ")
+	t.Setenv(privateTargetsEnvName, " true ")
+	t.Setenv(maxWebhookTTLEnvName, " 168h ")
+	t.Setenv(maxMessagesEnvName, " 5000 ")
+	t.Setenv(storeDriverEnvName, " sqlite ")
+	t.Setenv(encryptMessagesEnvName, " true ")
+	t.Setenv(previousKeysEnvName, " "+otherEncryptionKey+" , ,"+testEncryptionKey+" ")
+	t.Setenv(storeDSNEnvName, " postgres://localhost/webhooks ")
+	t.Setenv(dropHeadersEnvName, " , ")
+	t.Setenv(maskHeadersEnvName, " X-Api-Key, X-Secret-* ")
 
 	config := LoadConfigFromEnv()
 	assert.Equal(t, "127.0.0.1:0", config.ListenAddr)
@@ -30,6 +52,15 @@ func TestLoadConfigFromEnvAndSetup(t *testing.T) {
 	assert.Equal(t, testEncryptionKey, config.EncryptionKey)
 	assert.Equal(t, "https://hooks.example.com/base/", config.PublicBaseURL)
 	assert.Equal(t, "Fly-Client-IP", config.ClientIPHeader)
+	assert.True(t, config.AllowPrivateTargets)
+	assert.Equal(t, 168*time.Hour, config.MaxWebhookTTL)
+	assert.Equal(t, 5000, config.MaxMessagesPerWebhook)
+	assert.Equal(t, "sqlite", config.StoreDriver)
+	assert.True(t, config.EncryptMessages)
+	assert.Equal(t, []string{otherEncryptionKey, testEncryptionKey}, config.PreviousEncryptionKeys)
+	assert.Equal(t, "postgres://localhost/webhooks", config.StoreDSN)
+	assert.Equal(t, []string{}, config.DropHeaders)
+	assert.Equal(t, []string{"X-Api-Key", "X-Secret-*"}, config.MaskHeaders)
 
 	server := Setup()
 	require.NotNil(t, server)
@@ -66,6 +97,92 @@ func TestNewServerRejectsInvalidConfig(t *testing.T) {
 	})
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), publicBaseURLEnvName)
+
+	_, err = NewServer(Config{
+		EncryptionKey: testEncryptionKey,
+		StoreDriver:   "postgres",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), storeDSNEnvName)
+
+	_, err = NewServer(Config{
+		EncryptionKey: testEncryptionKey,
+		StoreDriver:   "mysql",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), storeDriverEnvName)
+
+	_, err = NewServer(Config{
+		StorePath:             filepath.Join(tempDir, "negative-cap.db"),
+		EncryptionKey:         testEncryptionKey,
+		MaxMessagesPerWebhook: -1,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), maxMessagesEnvName)
+
+	_, err = NewServer(Config{
+		StoreDriver: "memory",
+		MaskHeaders: []string{"X Secret"},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), maskHeadersEnvName)
+
+	_, err = NewServer(Config{
+		StoreDriver: "memory",
+		TLSCertFile: filepath.Join(tempDir, "cert.pem"),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), tlsKeyFileEnvName)
+
+	_, err = NewServer(Config{
+		StoreDriver:    "memory",
+		TLSClientCerts: true,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), tlsCertFileEnvName)
+
+	_, err = NewServer(Config{
+		StoreDriver: "memory",
+		TLSCertFile: filepath.Join(tempDir, "missing-cert.pem"),
+		TLSKeyFile:  filepath.Join(tempDir, "missing-key.pem"),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "load TLS certificate")
+}
+
+func TestNewServerWithMemoryStoreNeedsNoPathOrKey(t *testing.T) {
+	server, err := NewServer(Config{StoreDriver: " Memory "})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, server.Close())
+	})
+
+	recorder := httptest.NewRecorder()
+	server.mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/webhooks", strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.NoFileExists(t, defaultStorePath)
+}
+
+func TestRotateKeysReencryptsStoredSecrets(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "rotate.db")
+	server, err := NewServer(Config{StorePath: storePath, EncryptionKey: testEncryptionKey})
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	server.mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/webhooks", strings.NewReader(`{"hmacHeader":"X-Signature","hmacSecret":"secret"}`)))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NoError(t, server.Close())
+
+	rotated, err := RotateKeys(Config{
+		StorePath:              storePath,
+		EncryptionKey:          otherEncryptionKey,
+		PreviousEncryptionKeys: []string{testEncryptionKey},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, rotated)
+
+	_, err = RotateKeys(Config{StoreDriver: "memory"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not encrypt")
 }
 
 func TestServerRunShutsDownOnContextCancel(t *testing.T) {
@@ -123,6 +240,103 @@ func TestServerRunShutsDownOnContextCancel(t *testing.T) {
 	assert.Nil(t, server.cleanupDone)
 }
 
+func TestServerRunServesTLSAndCapturesClientCertificates(t *testing.T) {
+	certificates := writeTestCertificates(t)
+	listenAddr := freeLocalAddress(t)
+	server, err := NewServer(Config{
+		ListenAddr:     listenAddr,
+		StoreDriver:    "memory",
+		TLSCertFile:    certificates.serverCertFile,
+		TLSKeyFile:     certificates.serverKeyFile,
+		TLSClientCerts: true,
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Run(ctx)
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(certificates.ca)
+	anonymousClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	clientWithCertificate := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{certificates.client},
+	}}}
+
+	input, err := json.Marshal(model.WebhookInput{MutualTLS: &model.MutualTLS{CACertificates: string(certificates.caPEM)}})
+	require.NoError(t, err)
+	var createResponse struct {
+		ID string `json:"id"`
+	}
+	require.Eventually(t, func() bool {
+		resp, err := anonymousClient.Post("https://"+listenAddr+"/api/webhooks", "application/json", strings.NewReader(string(input)))
+		if err != nil {
+			return false
+		}
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+		return resp.StatusCode == http.StatusOK && json.NewDecoder(resp.Body).Decode(&createResponse) == nil
+	}, 5*time.Second, 50*time.Millisecond)
+
+	hookURL := fmt.Sprintf("https://%s/hooks/%s", listenAddr, createResponse.ID)
+	resp, err := anonymousClient.Post(hookURL, "text/plain", strings.NewReader("anonymous"))
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp, err = clientWithCertificate.Post(hookURL, "text/plain", strings.NewReader("signed"))
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	page, err := server.store.GetMessagePageForWebhook(createResponse.ID, 1, 10, model.MessageFilter{Outcome: model.MessageOutcomeAll})
+	require.NoError(t, err)
+	require.Len(t, page.Messages, 2)
+	accepted, rejected := page.Messages[0], page.Messages[1]
+	assert.Equal(t, "Missing TLS client certificate", rejected.ErrorMessage)
+	assert.Nil(t, rejected.ClientCertificate)
+	require.NotNil(t, accepted.ClientCertificate)
+	assert.Equal(t, "CN=webhook-sender", accepted.ClientCertificate.Subject)
+	assert.Equal(t, "CN=Test CA", accepted.ClientCertificate.Issuer)
+	assert.Equal(t, model.CertificateFingerprint(certificates.client.Leaf), accepted.ClientCertificate.Fingerprint)
+
+	cancel()
+	require.NoError(t, <-errCh)
+}
+
+func TestNewServerVerifiesClientCertificatesAgainstClientCA(t *testing.T) {
+	certificates := writeTestCertificates(t)
+	caFile := filepath.Join(t.TempDir(), "client-ca.pem")
+	require.NoError(t, os.WriteFile(caFile, certificates.caPEM, 0600))
+
+	server, err := NewServer(Config{
+		StoreDriver:     "memory",
+		TLSCertFile:     certificates.serverCertFile,
+		TLSKeyFile:      certificates.serverKeyFile,
+		TLSClientCAFile: caFile,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, server.Close())
+	})
+	assert.Equal(t, tls.VerifyClientCertIfGiven, server.httpServer.TLSConfig.ClientAuth)
+	assert.NotNil(t, server.httpServer.TLSConfig.ClientCAs)
+
+	require.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0600))
+	_, err = NewServer(Config{
+		StoreDriver:     "memory",
+		TLSCertFile:     certificates.serverCertFile,
+		TLSKeyFile:      certificates.serverKeyFile,
+		TLSClientCAFile: caFile,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), tlsClientCAFileEnvName)
+}
+
 func TestCloseIsIdempotent(t *testing.T) {
 	server, err := NewServer(Config{
 		ListenAddr:    "127.0.0.1:0",
@@ -145,3 +359,72 @@ func freeLocalAddress(t *testing.T) string {
 	require.NoError(t, listener.Close())
 	return addr
 }
+
+type testCertificates struct {
+	ca             *x509.Certificate
+	caPEM          []byte
+	serverCertFile string
+	serverKeyFile  string
+	client         tls.Certificate
+}
+
+// writeTestCertificates issues a server certificate for 127.0.0.1 and a client
+// certificate from a throwaway CA and writes the server key pair to disk.
+func writeTestCertificates(t *testing.T) testCertificates {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	issue := func(serial int64, commonName string, usage x509.ExtKeyUsage, ips []net.IP) ([]byte, *ecdsa.PrivateKey) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: commonName},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  ips,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		return der, key
+	}
+	encodeKey := func(key *ecdsa.PrivateKey) []byte {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		require.NoError(t, err)
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	}
+
+	dir := t.TempDir()
+	serverDER, serverKey := issue(2, "127.0.0.1", x509.ExtKeyUsageServerAuth, []net.IP{net.ParseIP("127.0.0.1")})
+	certificates := testCertificates{
+		ca:             ca,
+		caPEM:          pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		serverCertFile: filepath.Join(dir, "server.pem"),
+		serverKeyFile:  filepath.Join(dir, "server-key.pem"),
+	}
+	require.NoError(t, os.WriteFile(certificates.serverCertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: serverDER}), 0600))
+	require.NoError(t, os.WriteFile(certificates.serverKeyFile, encodeKey(serverKey), 0600))
+
+	clientDER, clientKey := issue(3, "webhook-sender", x509.ExtKeyUsageClientAuth, nil)
+	certificates.client, err = tls.X509KeyPair(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: clientDER}), encodeKey(clientKey))
+	require.NoError(t, err)
+
+	return certificates
+}
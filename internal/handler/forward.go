@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+)
+
+// forwardRequest relays an accepted delivery to the webhook's upstream and
+// records the upstream's answer. Failures are reported on the result instead of
+// being returned so the delivery is still captured.
+func (h *Handler) forwardRequest(r *http.Request, webhook *model.Webhook, requestBody []byte) *model.ForwardResult {
+	result := &model.ForwardResult{TargetURL: webhook.ForwardURL}
+
+	target, err := model.ParseTargetURL(webhook.ForwardURL)
+	if err != nil {
+		result.ErrorMessage = err.Error()
+		return result
+	}
+
+	forwardURL := outboundURL(target, hookPathSuffix(webhook.ID, r.URL.Path), r.URL.RawQuery)
+	request, err := http.NewRequestWithContext(r.Context(), r.Method, forwardURL.String(), bytes.NewReader(requestBody))
+	if err != nil {
+		result.ErrorMessage = err.Error()
+		return result
+	}
+	copyOutboundHeaders(request.Header, r.Header)
+
+	startedAt := time.Now()
+	response, err := h.outboundClient.Do(request)
+	result.LatencyMillis = time.Since(startedAt).Milliseconds()
+	if err != nil {
+		result.ErrorMessage = outboundErrorMessage(err)
+		return result
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	responseBody, err := io.ReadAll(io.LimitReader(response.Body, maxRequestBodyBytes))
+	if err != nil {
+		result.ErrorMessage = fmt.Sprintf("could not read response body: %s", err)
+		return result
+	}
+
+	result.StatusCode = response.StatusCode
+	result.Headers = map[string][]string{}
+	for name, values := range response.Header {
+		if hopByHopHeaders[name] {
+			continue
+		}
+		result.Headers[name] = values
+	}
+	result.Body = string(responseBody)
+
+	return result
+}
+
+// writeForwardedResponse relays the upstream answer to the sender, or answers
+// with 502 Bad Gateway when the upstream could not be reached.
+func (h *Handler) writeForwardedResponse(w http.ResponseWriter, result *model.ForwardResult) {
+	if result.Failed() {
+		h.writeJSON(w, http.StatusBadGateway, map[string]string{"message": "Could not forward request to upstream"})
+		return
+	}
+
+	for name, values := range result.Headers {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	w.WriteHeader(result.StatusCode)
+	if _, err := io.WriteString(w, result.Body); err != nil {
+		log.Printf("Could not write forwarded webhook response: %s", err)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/achawki/webhook-receiver/internal/model"
 	"github.com/achawki/webhook-receiver/internal/storage"
 )
 
@@ -27,12 +28,17 @@ var assetFS embed.FS
 
 // Handler handles incoming HTTP requests.
 type Handler struct {
-	storage        storage.WebhookStorage
-	templates      *template.Template
-	assets         http.Handler
-	limiter        *ipRateLimiter
-	publicBaseURL  string
-	clientIPHeader string
+	storage         storage.WebhookStorage
+	templates       *template.Template
+	assets          http.Handler
+	limiter         *ipRateLimiter
+	broker          *messageBroker
+	outboundClient  *http.Client
+	publicBaseURL   string
+	clientIPHeader  string
+	headerRedaction *model.Redaction
+	streamPing      time.Duration
+	retentionLimits model.RetentionLimits
 }
 
 // Option configures a handler.
@@ -59,6 +65,32 @@ func WithClientIPHeader(headerName string) Option {
 	}
 }
 
+// WithHeaderRedaction replaces the server-wide header redaction. Patterns are
+// header names or prefixes ending in "*". Dropped headers are not captured and
+// masked headers keep only their last four characters.
+func WithHeaderRedaction(dropHeaders []string, maskHeaders []string) Option {
+	return func(h *Handler) {
+		h.headerRedaction = &model.Redaction{
+			DropHeaders: model.NewHeaderPatterns(dropHeaders),
+			MaskHeaders: model.NewHeaderPatterns(maskHeaders),
+		}
+	}
+}
+
+// WithPrivateOutboundTargets allows replayed requests to reach loopback and private network addresses.
+func WithPrivateOutboundTargets(allowed bool) Option {
+	return func(h *Handler) {
+		h.outboundClient = newOutboundClient(allowed)
+	}
+}
+
+// WithRetentionLimits sets the maximum TTL and message cap a webhook may request.
+func WithRetentionLimits(limits model.RetentionLimits) Option {
+	return func(h *Handler) {
+		h.retentionLimits = limits
+	}
+}
+
 // NewHandler creates and initializes handler.
 func NewHandler(storage storage.WebhookStorage, options ...Option) *Handler {
 	templates := template.Must(template.ParseFS(templateFS, "templates/*.gohtml"))
@@ -67,10 +99,15 @@ func NewHandler(storage storage.WebhookStorage, options ...Option) *Handler {
 		panic(err)
 	}
 	handler := &Handler{
-		storage:   storage,
-		templates: templates,
-		assets:    http.FileServer(http.FS(assetsSubFS)),
-		limiter:   newIPRateLimiter(defaultRateLimitRequests, defaultRateLimitWindow),
+		storage:         storage,
+		templates:       templates,
+		assets:          http.FileServer(http.FS(assetsSubFS)),
+		limiter:         newIPRateLimiter(defaultRateLimitRequests, defaultRateLimitWindow),
+		broker:          newMessageBroker(defaultSubscriberBuffer),
+		outboundClient:  newOutboundClient(false),
+		streamPing:      defaultStreamPingInterval,
+		retentionLimits: model.DefaultRetentionLimits(),
+		headerRedaction: &model.Redaction{DropHeaders: model.DefaultDroppedHeaders()},
 	}
 	for _, option := range options {
 		option(handler)
@@ -92,6 +129,11 @@ func (h *Handler) Register(mux *http.ServeMux) {
 	mux.HandleFunc("/api/webhooks/", h.MessageHandler)
 }
 
+// Close disconnects all live message streams.
+func (h *Handler) Close() {
+	h.broker.Close()
+}
+
 // UnknownHandler handles requests for unknown endpoints and returns 404
 func (h *Handler) UnknownHandler(w http.ResponseWriter, r *http.Request) {
 	http.NotFound(w, r)
@@ -119,6 +161,10 @@ func (h *Handler) unauthorizedHandler(w http.ResponseWriter) {
 	h.writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Request did not satisfy the configured webhook authorization"})
 }
 
+func (h *Handler) forbiddenHandler(w http.ResponseWriter) {
+	h.writeJSON(w, http.StatusForbidden, map[string]string{"message": "Request did not come from an IP address allowed for this webhook"})
+}
+
 func (h *Handler) tooManyRequestsHandler(w http.ResponseWriter, r *http.Request) {
 	if strings.HasPrefix(r.URL.Path, "/api/") || strings.HasPrefix(r.URL.Path, "/hooks/") {
 		h.writeJSON(w, http.StatusTooManyRequests, map[string]string{"message": "Too many requests from this IP. Please retry later."})
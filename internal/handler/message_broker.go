@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"sync"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+)
+
+const defaultSubscriberBuffer = 32
+
+// messageBroker fans captured messages out to live subscribers of a webhook.
+// Publishing never blocks: a subscriber whose buffer is full is disconnected
+// so that slow clients cannot hold up ingestion.
+type messageBroker struct {
+	mu          sync.Mutex
+	bufferSize  int
+	subscribers map[string]map[*messageSubscription]struct{}
+	closed      bool
+}
+
+type messageSubscription struct {
+	webhookID string
+	messages  chan *model.Message
+	closeOnce sync.Once
+}
+
+func newMessageBroker(bufferSize int) *messageBroker {
+	if bufferSize <= 0 {
+		bufferSize = defaultSubscriberBuffer
+	}
+
+	return &messageBroker{
+		bufferSize:  bufferSize,
+		subscribers: map[string]map[*messageSubscription]struct{}{},
+	}
+}
+
+// Subscribe registers a new subscriber for the webhook. The returned
+// subscription's channel is closed when the subscriber falls behind, is
+// unsubscribed, or the broker is closed.
+func (b *messageBroker) Subscribe(webhookID string) *messageSubscription {
+	subscription := &messageSubscription{
+		webhookID: webhookID,
+		messages:  make(chan *model.Message, b.bufferSize),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		subscription.close()
+		return subscription
+	}
+
+	if b.subscribers[webhookID] == nil {
+		b.subscribers[webhookID] = map[*messageSubscription]struct{}{}
+	}
+	b.subscribers[webhookID][subscription] = struct{}{}
+
+	return subscription
+}
+
+// Unsubscribe removes the subscription and closes its channel.
+func (b *messageBroker) Unsubscribe(subscription *messageSubscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.removeLocked(subscription)
+}
+
+// Publish delivers the message to every subscriber of the webhook without blocking.
+func (b *messageBroker) Publish(webhookID string, message *model.Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for subscription := range b.subscribers[webhookID] {
+		select {
+		case subscription.messages <- message:
+		default:
+			b.removeLocked(subscription)
+		}
+	}
+}
+
+// Close disconnects all subscribers and rejects new subscriptions.
+func (b *messageBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for _, subscriptions := range b.subscribers {
+		for subscription := range subscriptions {
+			b.removeLocked(subscription)
+		}
+	}
+}
+
+func (b *messageBroker) subscriberCount(webhookID string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.subscribers[webhookID])
+}
+
+func (b *messageBroker) removeLocked(subscription *messageSubscription) {
+	subscriptions := b.subscribers[subscription.webhookID]
+	delete(subscriptions, subscription)
+	if len(subscriptions) == 0 {
+		delete(b.subscribers, subscription.webhookID)
+	}
+	subscription.close()
+}
+
+func (s *messageSubscription) close() {
+	s.closeOnce.Do(func() {
+		close(s.messages)
+	})
+}
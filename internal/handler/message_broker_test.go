@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageBrokerFansOutToWebhookSubscribers(t *testing.T) {
+	broker := newMessageBroker(4)
+	first := broker.Subscribe("webhook-1")
+	second := broker.Subscribe("webhook-1")
+	other := broker.Subscribe("webhook-2")
+
+	message := model.NewMessage(http.MethodPost, "/hooks/webhook-1", "", "payload", nil)
+	broker.Publish("webhook-1", message)
+
+	assert.Same(t, message, <-first.messages)
+	assert.Same(t, message, <-second.messages)
+	assert.Empty(t, other.messages)
+}
+
+func TestMessageBrokerDisconnectsSlowSubscribers(t *testing.T) {
+	broker := newMessageBroker(1)
+	slow := broker.Subscribe("webhook-1")
+
+	broker.Publish("webhook-1", model.NewMessage(http.MethodPost, "/hooks/webhook-1", "", "first", nil))
+	broker.Publish("webhook-1", model.NewMessage(http.MethodPost, "/hooks/webhook-1", "", "second", nil))
+
+	first, ok := <-slow.messages
+	require.True(t, ok)
+	assert.Equal(t, "first", first.Payload)
+	_, ok = <-slow.messages
+	assert.False(t, ok)
+	assert.Zero(t, broker.subscriberCount("webhook-1"))
+}
+
+func TestMessageBrokerUnsubscribeAndClose(t *testing.T) {
+	broker := newMessageBroker(1)
+	subscription := broker.Subscribe("webhook-1")
+	broker.Unsubscribe(subscription)
+	broker.Unsubscribe(subscription)
+
+	_, ok := <-subscription.messages
+	assert.False(t, ok)
+	assert.Zero(t, broker.subscriberCount("webhook-1"))
+
+	open := broker.Subscribe("webhook-1")
+	broker.Close()
+	_, ok = <-open.messages
+	assert.False(t, ok)
+
+	late := broker.Subscribe("webhook-1")
+	_, ok = <-late.messages
+	assert.False(t, ok)
+}
@@ -1,9 +1,11 @@
 package handler
 
 import (
+	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -18,15 +20,16 @@ const (
 	maxMessagesPageSize = 100
 )
 
-// MessageHandler handles requests for the captured-messages endpoint.
+// MessageHandler handles requests below /api/webhooks/{id}: webhook management, captured messages,
+// single messages and their raw payloads, the message stream, and replays.
 func (h *Handler) MessageHandler(w http.ResponseWriter, r *http.Request) {
-	webhookID := h.retrieveWebhookIDFromMessagePath(r.URL.Path)
-	if webhookID == "" {
+	route, ok := h.retrieveWebhookRouteFromAPIPath(r.URL.Path)
+	if !ok {
 		h.UnknownHandler(w, r)
 		return
 	}
 
-	if r.Method != http.MethodGet {
+	if !route.allowsMethod(r.Method) {
 		h.UnknownHandler(w, r)
 		return
 	}
@@ -35,23 +38,37 @@ func (h *Handler) MessageHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	webhook, err := h.storage.GetWebhook(webhookID)
+	webhook, err := h.storage.GetWebhook(route.webhookID)
 	if err != nil {
 		log.Printf("Could not retrieve webhook: %s", err)
 		switch err.(type) {
 		case *storage.WebhookNotFoundError:
-			h.unknownWebhookHandler(w, webhookID)
+			h.unknownWebhookHandler(w, route.webhookID)
 		default:
 			h.internalServerErrorHandler(w, "Could not retrieve webhook")
 		}
 		return
 	}
 
-	h.messagesGETHandler(w, r, webhook)
+	switch {
+	case route.resource == "":
+		h.webhookManagementHandler(w, r, webhook)
+	case route.action == "replay":
+		h.replayHandler(w, r, webhook, route.messageID)
+	case route.action == "payload":
+		h.messagePayloadHandler(w, webhook, route.messageID)
+	case route.messageID != 0:
+		h.singleMessageHandler(w, r, webhook, route.messageID)
+	case route.resource == "stream":
+		h.messageStreamHandler(w, r, webhook)
+	default:
+		h.messagesGETHandler(w, r, webhook)
+	}
 }
 
 // HookHandler accepts incoming webhook deliveries on /hooks/{id}[/*].
 func (h *Handler) HookHandler(w http.ResponseWriter, r *http.Request) {
+	received := time.Now()
 	webhookID := h.retrieveWebhookIDFromHookPath(r.URL.Path)
 	if webhookID == "" {
 		h.UnknownHandler(w, r)
@@ -74,10 +91,10 @@ func (h *Handler) HookHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.ingestRequest(w, r, webhook)
+	h.ingestRequest(w, r, webhook, received)
 }
 
-func (h *Handler) ingestRequest(w http.ResponseWriter, r *http.Request, webhook *model.Webhook) {
+func (h *Handler) ingestRequest(w http.ResponseWriter, r *http.Request, webhook *model.Webhook, received time.Time) {
 	requestBody, err := readRequestBody(w, r)
 	if err != nil {
 		log.Printf("Could not read request body: %s", err)
@@ -85,77 +102,173 @@ func (h *Handler) ingestRequest(w http.ResponseWriter, r *http.Request, webhook
 		return
 	}
 
-	headers := sanitizedHeaders(r.Header, webhook)
+	message := model.NewMessage(r.Method, r.URL.Path, r.URL.RawQuery, string(webhook.Redaction.MaskPayload(requestBody)), h.sanitizedHeaders(r.Header, webhook))
+	message.CaptureRequestMetadata(r, h.clientIP(r), len(requestBody))
+	message.DecodeBody()
+
+	if ipFailure := webhook.IPAllowlistFailure(message.RemoteIP); ipFailure != "" {
+		log.Printf("Rejected delivery from outside the IP allowlist of webhook %s", webhook.ID)
+		h.rejectDelivery(w, webhook, message, received, http.StatusForbidden, ipFailure)
+		return
+	}
+
 	authFailure := webhook.AuthorizationFailure(r, requestBody)
 	if authFailure != "" {
 		log.Printf("Not authorized to access webhook with ID: %s", webhook.ID)
-		rejectedMessage := model.NewMessage(r.Method, r.URL.Path, r.URL.RawQuery, string(requestBody), headers)
-		rejectedMessage.MarkRejected(http.StatusUnauthorized, authFailure)
-		if err := h.storage.InsertMessage(webhook.ID, rejectedMessage); err != nil {
-			log.Printf("Could not insert rejected webhook request %s", err)
-		}
-		h.unauthorizedHandler(w)
+		h.rejectDelivery(w, webhook, message, received, http.StatusUnauthorized, authFailure)
 		return
 	}
 
-	message := model.NewMessage(r.Method, r.URL.Path, r.URL.RawQuery, string(requestBody), headers)
+	// Delivery IDs are only remembered once the delivery is authorized, so
+	// unsigned requests cannot burn IDs of genuine deliveries. An ID remembered
+	// here is forgotten again if the delivery is not processed, so the sender's
+	// retry is not rejected as a duplicate.
+	var rememberedDeliveryID string
+	if deliveryID := webhook.ReplayProtection.DeliveryID(r); deliveryID != "" {
+		firstSeen, err := h.storage.RememberDeliveryID(webhook.ID, deliveryID)
+		if err != nil {
+			log.Printf("Could not remember delivery ID: %s", err)
+			h.internalServerErrorHandler(w, "Something went wrong")
+			return
+		}
+		if !firstSeen {
+			if !webhook.ReplayProtection.FlagDuplicates {
+				log.Printf("Rejected duplicate delivery for webhook %s", webhook.ID)
+				h.rejectDelivery(w, webhook, message, received, http.StatusUnauthorized, webhook.ReplayProtection.DuplicateFailure(deliveryID))
+				return
+			}
+			message.Duplicate = true
+		} else {
+			rememberedDeliveryID = deliveryID
+		}
+	}
+
+	if webhook.HasForwarding() {
+		h.forwardAndCapture(w, r, webhook, message, requestBody, received, rememberedDeliveryID)
+		return
+	}
 
+	message.SetProcessingDuration(time.Since(received))
 	err = h.storage.InsertMessage(webhook.ID, message)
 	if err != nil {
 		log.Printf("Could not insert webhook message: %s", err)
+		h.forgetDeliveryID(webhook.ID, rememberedDeliveryID)
 		h.internalServerErrorHandler(w, "Something went wrong")
 		return
 	}
 	log.Printf("Inserted message for webhook %s", webhook.ID)
+	h.broker.Publish(webhook.ID, message)
+
+	writeConfiguredResponse(w, webhook.Response)
+}
+
+// rejectDelivery captures a delivery that failed authorization and answers it
+// with the given status: 403 for senders outside the IP allowlist, 401 otherwise.
+func (h *Handler) rejectDelivery(w http.ResponseWriter, webhook *model.Webhook, message *model.Message, received time.Time, statusCode int, failure string) {
+	message.MarkRejected(statusCode, failure)
+	message.SetProcessingDuration(time.Since(received))
+	if err := h.storage.InsertMessage(webhook.ID, message); err != nil {
+		log.Printf("Could not insert rejected webhook request %s", err)
+	} else {
+		h.broker.Publish(webhook.ID, message)
+	}
+	if statusCode == http.StatusForbidden {
+		h.forbiddenHandler(w)
+		return
+	}
+	h.unauthorizedHandler(w)
+}
+
+// forwardAndCapture relays the delivery upstream before capturing it. The
+// upstream answer is returned to the sender even if the capture fails, because
+// the upstream has already processed the delivery. If the upstream could not
+// process it, the delivery ID is forgotten so the sender's retry is forwarded.
+func (h *Handler) forwardAndCapture(w http.ResponseWriter, r *http.Request, webhook *model.Webhook, message *model.Message, requestBody []byte, received time.Time, deliveryID string) {
+	result := h.forwardRequest(r, webhook, requestBody)
+	message.MarkForwarded(result)
+	message.SetProcessingDuration(time.Since(received))
+	if result.Failed() {
+		log.Printf("Could not forward message for webhook %s: %s", webhook.ID, result.ErrorMessage)
+	}
+	if result.Failed() || result.StatusCode >= http.StatusInternalServerError {
+		h.forgetDeliveryID(webhook.ID, deliveryID)
+	}
+
+	if err := h.storage.InsertMessage(webhook.ID, message); err != nil {
+		log.Printf("Could not insert forwarded webhook message: %s", err)
+	} else {
+		log.Printf("Inserted forwarded message for webhook %s", webhook.ID)
+		h.broker.Publish(webhook.ID, message)
+	}
+
+	h.writeForwardedResponse(w, result)
 }
 
-func sanitizedHeaders(headers http.Header, webhook *model.Webhook) map[string][]string {
+// forgetDeliveryID releases a delivery ID remembered for a delivery that was
+// not processed. Errors are only logged; the sender already gets a failure.
+func (h *Handler) forgetDeliveryID(webhookID string, deliveryID string) {
+	if deliveryID == "" {
+		return
+	}
+	if err := h.storage.ForgetDeliveryID(webhookID, deliveryID); err != nil {
+		log.Printf("Could not forget delivery ID: %s", err)
+	}
+}
+
+func writeConfiguredResponse(w http.ResponseWriter, response *model.WebhookResponse) {
+	if response == nil {
+		return
+	}
+
+	for _, name := range response.HeaderNames() {
+		w.Header().Set(name, response.Headers[name])
+	}
+	if response.ContentType != "" {
+		w.Header().Set("Content-Type", response.ContentType)
+	}
+
+	w.WriteHeader(response.EffectiveStatusCode())
+	if _, err := io.WriteString(w, response.Body); err != nil {
+		log.Printf("Could not write configured webhook response: %s", err)
+	}
+}
+
+// sanitizedHeaders removes credentials and applies header redaction. The
+// webhook's redaction is consulted before the server-wide one, so a webhook can
+// mask a header that the server would drop.
+func (h *Handler) sanitizedHeaders(headers http.Header, webhook *model.Webhook) map[string][]string {
 	sanitized := headers.Clone()
 	sanitized.Del("Authorization")
+	var webhookRedaction *model.Redaction
 	if webhook != nil {
 		sanitized.Del(webhook.TokenName)
 		sanitized.Del(webhook.HMACHeader)
+		webhookRedaction = webhook.Redaction
 	}
-	for name := range sanitized {
-		if shouldHideCapturedHeader(name) {
-			sanitized.Del(name)
-		}
-	}
+	model.RedactHeaders(sanitized, webhookRedaction, h.headerRedaction)
 
 	return sanitized
 }
 
-func shouldHideCapturedHeader(name string) bool {
-	canonicalName := http.CanonicalHeaderKey(strings.TrimSpace(name))
-	switch {
-	case strings.HasPrefix(canonicalName, "Fly-"):
-		return true
-	case strings.HasPrefix(canonicalName, "X-Forwarded-"):
-		return true
-	case canonicalName == "Via":
-		return true
-	case canonicalName == "X-Request-Start":
-		return true
-	default:
-		return false
-	}
-}
-
 func (h *Handler) messagesGETHandler(w http.ResponseWriter, r *http.Request, webhook *model.Webhook) {
-	page, pageSize, outcome, err := messagePageFromQuery(r)
+	page, pageSize, filter, err := messagePageFromQuery(r)
 	if err != nil {
 		h.badRequestHandler(w, err.Error())
 		return
 	}
 
 	log.Printf("Retrieving messages for webhook %s", webhook.ID)
-	messagePage, err := h.storage.GetMessagePageForWebhook(webhook.ID, page, pageSize, outcome)
+	messagePage, err := h.storage.GetMessagePageForWebhook(webhook.ID, page, pageSize, filter)
 	if err != nil {
 		log.Printf("Could not retrieve messages for webhook %s: %s", webhook.ID, err)
 		h.internalServerErrorHandler(w, "Something went wrong")
 		return
 	}
 
+	for _, message := range messagePage.Messages {
+		message.DecodeBody()
+	}
+
 	h.writeJSON(w, http.StatusOK, struct {
 		WebhookID       string           `json:"webhookId"`
 		ExpiresAt       string           `json:"expiresAt"`
@@ -167,10 +280,11 @@ func (h *Handler) messagesGETHandler(w http.ResponseWriter, r *http.Request, web
 		TotalPages      int              `json:"totalPages"`
 		HasNextPage     bool             `json:"hasNextPage"`
 		HasPreviousPage bool             `json:"hasPreviousPage"`
+		SearchTruncated bool             `json:"searchTruncated,omitempty"`
 	}{
 		WebhookID:       webhook.ID,
 		ExpiresAt:       webhook.ExpiresAt.Format(time.RFC3339Nano),
-		Outcome:         string(outcome),
+		Outcome:         string(filter.Outcome),
 		Messages:        messagePage.Messages,
 		Page:            messagePage.Page,
 		PageSize:        messagePage.PageSize,
@@ -178,9 +292,60 @@ func (h *Handler) messagesGETHandler(w http.ResponseWriter, r *http.Request, web
 		TotalPages:      messagePage.TotalPages,
 		HasNextPage:     messagePage.HasNextPage,
 		HasPreviousPage: messagePage.HasPreviousPage,
+		SearchTruncated: messagePage.SearchTruncated,
+	})
+}
+
+func (h *Handler) singleMessageHandler(w http.ResponseWriter, r *http.Request, webhook *model.Webhook, messageID int64) {
+	if r.Method == http.MethodDelete {
+		if !h.requireManagementSecret(w, r, webhook) {
+			return
+		}
+		if err := h.storage.DeleteMessage(webhook.ID, messageID); err != nil {
+			h.messageLookupErrorHandler(w, webhook.ID, err)
+			return
+		}
+		log.Printf("Deleted message %d of webhook %s", messageID, webhook.ID)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	message, err := h.storage.GetMessage(webhook.ID, messageID)
+	if err != nil {
+		h.messageLookupErrorHandler(w, webhook.ID, err)
+		return
+	}
+	message.DecodeBody()
+
+	h.writeJSON(w, http.StatusOK, struct {
+		WebhookID string         `json:"webhookId"`
+		Message   *model.Message `json:"message"`
+	}{
+		WebhookID: webhook.ID,
+		Message:   message,
 	})
 }
 
+// messagePayloadHandler downloads the raw captured body. It is always served as
+// an attachment so captured content is never rendered on this origin.
+func (h *Handler) messagePayloadHandler(w http.ResponseWriter, webhook *model.Webhook, messageID int64) {
+	message, err := h.storage.GetMessage(webhook.ID, messageID)
+	if err != nil {
+		h.messageLookupErrorHandler(w, webhook.ID, err)
+		return
+	}
+
+	body := message.Body()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="message-%d.bin"`, messageID))
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(body); err != nil {
+		log.Printf("Could not write message payload: %s", err)
+	}
+}
+
 func readRequestBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
 	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
 	defer func() {
@@ -190,17 +355,67 @@ func readRequestBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
 	return io.ReadAll(r.Body)
 }
 
-func (h *Handler) retrieveWebhookIDFromMessagePath(path string) string {
+// webhookAPIResources are the collections below /api/webhooks/{id}. They are
+// never valid webhook IDs, so /api/webhooks/messages is treated as a missing ID.
+var webhookAPIResources = map[string]bool{
+	"messages": true,
+	"stream":   true,
+}
+
+type webhookAPIRoute struct {
+	webhookID string
+	resource  string
+	messageID int64
+	action    string
+}
+
+func (h *Handler) retrieveWebhookRouteFromAPIPath(path string) (webhookAPIRoute, bool) {
 	segments := cleanPathSegments(path)
-	if len(segments) != 4 {
-		return ""
+	if len(segments) < 3 || segments[0] != "api" || segments[1] != "webhooks" {
+		return webhookAPIRoute{}, false
 	}
 
-	if segments[0] != "api" || segments[1] != "webhooks" || segments[3] != "messages" {
-		return ""
+	if len(segments) == 3 {
+		return webhookAPIRoute{webhookID: segments[2]}, !webhookAPIResources[segments[2]]
 	}
 
-	return segments[2]
+	route := webhookAPIRoute{webhookID: segments[2], resource: segments[3]}
+	if len(segments) == 4 {
+		return route, webhookAPIResources[route.resource]
+	}
+	if route.resource != "messages" || len(segments) > 6 {
+		return webhookAPIRoute{}, false
+	}
+
+	messageID, err := strconv.ParseInt(segments[4], 10, 64)
+	if err != nil || messageID < 1 {
+		return webhookAPIRoute{}, false
+	}
+	route.messageID = messageID
+
+	if len(segments) == 6 {
+		if segments[5] != "replay" && segments[5] != "payload" {
+			return webhookAPIRoute{}, false
+		}
+		route.action = segments[5]
+	}
+
+	return route, true
+}
+
+func (route webhookAPIRoute) allowsMethod(method string) bool {
+	switch {
+	case route.resource == "":
+		return method == http.MethodGet || method == http.MethodPatch || method == http.MethodDelete
+	case route.action == "replay":
+		return method == http.MethodGet || method == http.MethodPost
+	case route.action == "payload":
+		return method == http.MethodGet
+	case route.messageID != 0:
+		return method == http.MethodGet || method == http.MethodDelete
+	default:
+		return method == http.MethodGet
+	}
 }
 
 func (h *Handler) retrieveWebhookIDFromHookPath(path string) string {
@@ -216,36 +431,107 @@ func (h *Handler) retrieveWebhookIDFromHookPath(path string) string {
 	return segments[1]
 }
 
-func messagePageFromQuery(r *http.Request) (int, int, model.MessageOutcome, error) {
+// searchTimeLayouts are accepted for the since and until parameters. The
+// layouts without a zone match datetime-local form inputs, read as UTC.
+var searchTimeLayouts = []string{time.RFC3339Nano, searchFormTimeLayout, "2006-01-02T15:04"}
+
+const searchFormTimeLayout = "2006-01-02T15:04:05"
+
+func messagePageFromQuery(r *http.Request) (int, int, model.MessageFilter, error) {
 	page := defaultMessagesPage
 	pageSize := defaultMessagesSize
-	outcome := model.MessageOutcomeAll
+	query := r.URL.Query()
 
-	if pageValue := r.URL.Query().Get("page"); pageValue != "" {
+	if pageValue := query.Get("page"); pageValue != "" {
 		parsedPage, err := strconv.Atoi(pageValue)
 		if err != nil || parsedPage < 1 {
-			return 0, 0, "", errInvalidPagination("page")
+			return 0, 0, model.MessageFilter{}, errInvalidPagination("page")
 		}
 		page = parsedPage
 	}
 
-	if pageSizeValue := r.URL.Query().Get("pageSize"); pageSizeValue != "" {
+	if pageSizeValue := query.Get("pageSize"); pageSizeValue != "" {
 		parsedPageSize, err := strconv.Atoi(pageSizeValue)
 		if err != nil || parsedPageSize < 1 || parsedPageSize > maxMessagesPageSize {
-			return 0, 0, "", errInvalidPagination("pageSize")
+			return 0, 0, model.MessageFilter{}, errInvalidPagination("pageSize")
 		}
 		pageSize = parsedPageSize
 	}
 
-	if outcomeValue := r.URL.Query().Get("outcome"); outcomeValue != "" {
-		parsedOutcome, ok := model.ParseMessageOutcome(outcomeValue)
-		if !ok {
-			return 0, 0, "", &paginationError{message: "outcome must be one of all, accepted, rejected"}
+	filter, err := messageFilterFromQuery(query)
+	if err != nil {
+		return 0, 0, model.MessageFilter{}, err
+	}
+
+	return page, pageSize, filter, nil
+}
+
+// messageFilterFromQuery reads the outcome and search parameters of a
+// message listing.
+func messageFilterFromQuery(query url.Values) (model.MessageFilter, error) {
+	outcome, ok := model.ParseMessageOutcome(query.Get("outcome"))
+	if !ok {
+		return model.MessageFilter{}, &paginationError{message: "outcome must be one of all, accepted, rejected, failed"}
+	}
+
+	filter := model.MessageFilter{
+		Outcome:     outcome,
+		Method:      query.Get("method"),
+		PathPrefix:  query.Get("path"),
+		HeaderName:  query.Get("header"),
+		HeaderValue: query.Get("headerValue"),
+		Text:        query.Get("q"),
+	}
+
+	if statusValue := strings.TrimSpace(query.Get("status")); statusValue != "" {
+		statusCode, err := strconv.Atoi(statusValue)
+		if err != nil {
+			return model.MessageFilter{}, &paginationError{message: "status must be a status code between 100 and 599"}
+		}
+		filter.StatusCode = statusCode
+	}
+
+	for _, bound := range []struct {
+		name  string
+		value *time.Time
+	}{{name: "since", value: &filter.Since}, {name: "until", value: &filter.Until}} {
+		value := strings.TrimSpace(query.Get(bound.name))
+		if value == "" {
+			continue
+		}
+		parsed, err := parseSearchTime(value)
+		if err != nil {
+			return model.MessageFilter{}, &paginationError{message: bound.name + " must be an RFC 3339 timestamp"}
+		}
+		*bound.value = parsed
+	}
+
+	if expression := strings.TrimSpace(query.Get("jsonFilter")); expression != "" {
+		jsonFilter, err := model.ParseJSONFilter(expression)
+		if err != nil {
+			return model.MessageFilter{}, &paginationError{message: err.Error()}
+		}
+		filter.JSON = jsonFilter
+	}
+
+	filter = model.NewMessageFilter(filter)
+	if err := filter.Validate(); err != nil {
+		return model.MessageFilter{}, &paginationError{message: err.Error()}
+	}
+
+	return filter, nil
+}
+
+func parseSearchTime(value string) (time.Time, error) {
+	var err error
+	for _, layout := range searchTimeLayouts {
+		var parsed time.Time
+		if parsed, err = time.Parse(layout, value); err == nil {
+			return parsed, nil
 		}
-		outcome = parsedOutcome
 	}
 
-	return page, pageSize, outcome, nil
+	return time.Time{}, err
 }
 
 func errInvalidPagination(field string) error {
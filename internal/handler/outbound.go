@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const outboundRequestTimeout = 10 * time.Second
+const maxOutboundResponseBytes = 64 << 10
+
+var errPrivateOutboundTarget = errors.New("target resolves to a private or loopback address")
+
+// hopByHopHeaders are connection-specific headers that must not be copied to outbound requests.
+var hopByHopHeaders = map[string]bool{
+	"Accept-Encoding":     true,
+	"Connection":          true,
+	"Content-Length":      true,
+	"Host":                true,
+	"Keep-Alive":          true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Proxy-Connection":    true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+}
+
+// newOutboundClient creates the client used to send captured requests to other hosts.
+// Unless private targets are allowed, it refuses to connect to loopback, private, and
+// link-local addresses so the public receiver cannot be used to reach internal services.
+func newOutboundClient(allowPrivateTargets bool) *http.Client {
+	dialer := &net.Dialer{Timeout: outboundRequestTimeout}
+	if !allowPrivateTargets {
+		dialer.Control = func(network string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || privateOutboundIP(ip) {
+				return errPrivateOutboundTarget
+			}
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Timeout: outboundRequestTimeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   outboundRequestTimeout,
+			ResponseHeaderTimeout: outboundRequestTimeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func privateOutboundIP(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast()
+}
+
+// outboundURL appends the path suffix and query of a captured request to the target URL.
+func outboundURL(target *url.URL, pathSuffix string, rawQuery string) *url.URL {
+	outbound := *target
+	if pathSuffix != "" {
+		outbound.Path = strings.TrimRight(outbound.Path, "/") + pathSuffix
+		outbound.RawPath = ""
+	}
+	if rawQuery != "" {
+		if outbound.RawQuery != "" {
+			outbound.RawQuery += "&" + rawQuery
+		} else {
+			outbound.RawQuery = rawQuery
+		}
+	}
+
+	return &outbound
+}
+
+func hookPathSuffix(webhookID string, path string) string {
+	return strings.TrimPrefix(path, "/hooks/"+webhookID)
+}
+
+func copyOutboundHeaders(destination http.Header, source map[string][]string) {
+	for name, values := range source {
+		if hopByHopHeaders[http.CanonicalHeaderKey(name)] {
+			continue
+		}
+		for _, value := range values {
+			destination.Add(name, value)
+		}
+	}
+}
+
+func readOutboundResponseBody(body io.Reader) (string, error) {
+	content, err := io.ReadAll(io.LimitReader(body, maxOutboundResponseBytes))
+	if err != nil {
+		return "", fmt.Errorf("could not read response body: %w", err)
+	}
+
+	return string(content), nil
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/achawki/webhook-receiver/internal/storage"
+)
+
+func (h *Handler) replayHandler(w http.ResponseWriter, r *http.Request, webhook *model.Webhook, messageID int64) {
+	switch r.Method {
+	case http.MethodGet:
+		h.replayAttemptsGETHandler(w, webhook, messageID)
+	case http.MethodPost:
+		h.replayPOSTHandler(w, r, webhook, messageID)
+	default:
+		h.UnknownHandler(w, r)
+	}
+}
+
+func (h *Handler) replayAttemptsGETHandler(w http.ResponseWriter, webhook *model.Webhook, messageID int64) {
+	attempts, err := h.storage.ListReplayAttempts(webhook.ID, messageID)
+	if err != nil {
+		h.messageLookupErrorHandler(w, webhook.ID, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, struct {
+		WebhookID string                 `json:"webhookId"`
+		MessageID int64                  `json:"messageId"`
+		Replays   []*model.ReplayAttempt `json:"replays"`
+	}{
+		WebhookID: webhook.ID,
+		MessageID: messageID,
+		Replays:   attempts,
+	})
+}
+
+func (h *Handler) replayPOSTHandler(w http.ResponseWriter, r *http.Request, webhook *model.Webhook, messageID int64) {
+	replayInput, err := decodeReplayJSONInput(w, r)
+	if err != nil {
+		log.Printf("Error occurred during json processing: %s", err)
+		h.badRequestHandler(w, processDecodingError(err))
+		return
+	}
+
+	if err := replayInput.Validate(); err != nil {
+		h.validationErrorHandler(w, err.Error())
+		return
+	}
+
+	message, err := h.storage.GetMessage(webhook.ID, messageID)
+	if err != nil {
+		h.messageLookupErrorHandler(w, webhook.ID, err)
+		return
+	}
+
+	attempt := h.replayMessage(r.Context(), webhook.ID, message, replayInput)
+	if err := h.storage.InsertReplayAttempt(webhook.ID, attempt); err != nil {
+		log.Printf("Could not insert replay attempt for webhook %s: %s", webhook.ID, err)
+		h.internalServerErrorHandler(w, "Could not record replay attempt")
+		return
+	}
+	log.Printf("Replayed message %d of webhook %s", messageID, webhook.ID)
+
+	h.writeJSON(w, http.StatusOK, attempt)
+}
+
+func (h *Handler) replayMessage(ctx context.Context, webhookID string, message *model.Message, replayInput *model.ReplayInput) *model.ReplayAttempt {
+	attempt := model.NewReplayAttempt(message.ID, replayInput.TargetURL)
+
+	target, err := model.ParseTargetURL(replayInput.TargetURL)
+	if err != nil {
+		attempt.ErrorMessage = err.Error()
+		return attempt
+	}
+
+	replayURL := outboundURL(target, hookPathSuffix(webhookID, message.Path), message.Query)
+	request, err := http.NewRequestWithContext(ctx, message.Method, replayURL.String(), bytes.NewReader(message.Body()))
+	if err != nil {
+		attempt.ErrorMessage = err.Error()
+		return attempt
+	}
+	copyOutboundHeaders(request.Header, message.Headers)
+	for name, value := range replayInput.Headers {
+		if value == "" {
+			request.Header.Del(name)
+			continue
+		}
+		request.Header.Set(name, value)
+	}
+
+	startedAt := time.Now()
+	response, err := h.outboundClient.Do(request)
+	attempt.LatencyMillis = time.Since(startedAt).Milliseconds()
+	if err != nil {
+		attempt.ErrorMessage = outboundErrorMessage(err)
+		return attempt
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	attempt.StatusCode = response.StatusCode
+	responseBody, err := readOutboundResponseBody(response.Body)
+	if err != nil {
+		attempt.ErrorMessage = err.Error()
+	}
+	attempt.ResponseBody = responseBody
+
+	return attempt
+}
+
+func (h *Handler) messageLookupErrorHandler(w http.ResponseWriter, webhookID string, err error) {
+	var webhookNotFound *storage.WebhookNotFoundError
+	var messageNotFound *storage.MessageNotFoundError
+	switch {
+	case errors.As(err, &webhookNotFound):
+		h.unknownWebhookHandler(w, webhookID)
+	case errors.As(err, &messageNotFound):
+		h.writeJSON(w, http.StatusNotFound, map[string]string{"message": "Message does not exist"})
+	default:
+		log.Printf("Could not retrieve message for webhook %s: %s", webhookID, err)
+		h.internalServerErrorHandler(w, "Could not retrieve message")
+	}
+}
+
+func decodeReplayJSONInput(w http.ResponseWriter, r *http.Request) (*model.ReplayInput, error) {
+	var replayInput model.ReplayInput
+	if err := decodeJSONObject(w, r, &replayInput); err != nil {
+		return nil, err
+	}
+
+	return &replayInput, nil
+}
+
+func outboundErrorMessage(err error) string {
+	if errors.Is(err, errPrivateOutboundTarget) {
+		return "Target resolves to a private or loopback address"
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "Target did not respond in time"
+	}
+
+	return err.Error()
+}
@@ -0,0 +1,187 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/achawki/webhook-receiver/internal/handler"
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/achawki/webhook-receiver/internal/storage"
+	"github.com/achawki/webhook-receiver/internal/storage/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayHandlerReplaysMessageToTarget(t *testing.T) {
+	var receivedRequest *http.Request
+	var receivedBody string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receivedRequest = r
+		receivedBody = string(body)
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("queued"))
+	}))
+	t.Cleanup(target.Close)
+
+	webhookID := "webhookID"
+	message := model.NewMessage(http.MethodPost, "/hooks/webhookID/github/push", "ref=main", `{"message":"hello"}`, map[string][]string{
+		"Content-Type":    {"application/json"},
+		"X-Github-Event":  {"push"},
+		"X-Remove-Me":     {"yes"},
+		"Accept-Encoding": {"gzip"},
+	})
+	message.ID = 7
+	mockStorage := new(mocks.WebhookStorage)
+	webhook := model.NewWebhook("", "", "", "", "", "")
+	webhook.ID = webhookID
+	mockStorage.On("GetWebhook", webhookID).Return(webhook, nil)
+	mockStorage.On("GetMessage", webhookID, int64(7)).Return(message, nil)
+	var recordedAttempt *model.ReplayAttempt
+	mockStorage.On("InsertReplayAttempt", webhookID, mock.AnythingOfType("*model.ReplayAttempt")).
+		Run(func(args mock.Arguments) {
+			recordedAttempt = args.Get(1).(*model.ReplayAttempt)
+		}).
+		Return(nil)
+
+	handler := handler.NewHandler(mockStorage, handler.WithPrivateOutboundTargets(true))
+	body := fmt.Sprintf(`{"targetUrl":%q,"headers":{"X-Replayed":"true","X-Remove-Me":""}}`, target.URL+"/receiver")
+	request, _ := http.NewRequest(http.MethodPost, "http://localhost/api/webhooks/webhookID/messages/7/replay", bytes.NewBufferString(body))
+
+	w := httptest.NewRecorder()
+	handler.MessageHandler(w, request)
+
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	require.NotNil(t, receivedRequest)
+	assert.Equal(t, http.MethodPost, receivedRequest.Method)
+	assert.Equal(t, "/receiver/github/push", receivedRequest.URL.Path)
+	assert.Equal(t, "ref=main", receivedRequest.URL.RawQuery)
+	assert.Equal(t, `{"message":"hello"}`, receivedBody)
+	assert.Equal(t, "application/json", receivedRequest.Header.Get("Content-Type"))
+	assert.Equal(t, "push", receivedRequest.Header.Get("X-Github-Event"))
+	assert.Equal(t, "true", receivedRequest.Header.Get("X-Replayed"))
+	assert.Empty(t, receivedRequest.Header.Get("X-Remove-Me"))
+
+	require.NotNil(t, recordedAttempt)
+	assert.Equal(t, int64(7), recordedAttempt.MessageID)
+	assert.Equal(t, target.URL+"/receiver", recordedAttempt.TargetURL)
+	assert.Equal(t, http.StatusAccepted, recordedAttempt.StatusCode)
+	assert.Equal(t, "queued", recordedAttempt.ResponseBody)
+	assert.Empty(t, recordedAttempt.ErrorMessage)
+
+	var response model.ReplayAttempt
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, http.StatusAccepted, response.StatusCode)
+	assert.Equal(t, "queued", response.ResponseBody)
+}
+
+func TestReplayHandlerBlocksPrivateTargetsByDefault(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("private target must not be contacted")
+	}))
+	t.Cleanup(target.Close)
+
+	webhookID := "webhookID"
+	message := model.NewMessage(http.MethodPost, "/hooks/webhookID", "", "{}", nil)
+	message.ID = 1
+	mockStorage := new(mocks.WebhookStorage)
+	webhook := model.NewWebhook("", "", "", "", "", "")
+	webhook.ID = webhookID
+	mockStorage.On("GetWebhook", webhookID).Return(webhook, nil)
+	mockStorage.On("GetMessage", webhookID, int64(1)).Return(message, nil)
+	mockStorage.On("InsertReplayAttempt", webhookID, mock.AnythingOfType("*model.ReplayAttempt")).Return(nil)
+
+	handler := handler.NewHandler(mockStorage)
+	body := fmt.Sprintf(`{"targetUrl":%q}`, target.URL)
+	request, _ := http.NewRequest(http.MethodPost, "http://localhost/api/webhooks/webhookID/messages/1/replay", bytes.NewBufferString(body))
+
+	w := httptest.NewRecorder()
+	handler.MessageHandler(w, request)
+
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response model.ReplayAttempt
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Zero(t, response.StatusCode)
+	assert.Equal(t, "Target resolves to a private or loopback address", response.ErrorMessage)
+}
+
+func TestReplayHandlerWithInvalidTargetURL(t *testing.T) {
+	webhookID := "webhookID"
+	mockStorage := new(mocks.WebhookStorage)
+	webhook := model.NewWebhook("", "", "", "", "", "")
+	webhook.ID = webhookID
+	mockStorage.On("GetWebhook", webhookID).Return(webhook, nil)
+
+	handler := handler.NewHandler(mockStorage)
+	request, _ := http.NewRequest(http.MethodPost, "http://localhost/api/webhooks/webhookID/messages/1/replay", bytes.NewBufferString(`{"targetUrl":"ftp://example.com"}`))
+
+	w := httptest.NewRecorder()
+	handler.MessageHandler(w, request)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Result().StatusCode)
+	mockStorage.AssertNotCalled(t, "GetMessage", mock.Anything, mock.Anything)
+}
+
+func TestReplayHandlerWithUnknownMessage(t *testing.T) {
+	webhookID := "webhookID"
+	mockStorage := new(mocks.WebhookStorage)
+	webhook := model.NewWebhook("", "", "", "", "", "")
+	webhook.ID = webhookID
+	mockStorage.On("GetWebhook", webhookID).Return(webhook, nil)
+	mockStorage.On("GetMessage", webhookID, int64(42)).Return(nil, &storage.MessageNotFoundError{WebhookId: webhookID, MessageId: 42})
+
+	handler := handler.NewHandler(mockStorage)
+	request, _ := http.NewRequest(http.MethodPost, "http://localhost/api/webhooks/webhookID/messages/42/replay", bytes.NewBufferString(`{"targetUrl":"https://example.com"}`))
+
+	w := httptest.NewRecorder()
+	handler.MessageHandler(w, request)
+
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	assert.JSONEq(t, `{"message":"Message does not exist"}`, w.Body.String())
+}
+
+func TestReplayHandlerListsAttempts(t *testing.T) {
+	webhookID := "webhookID"
+	attempt := model.NewReplayAttempt(3, "https://example.com")
+	attempt.ID = 1
+	attempt.StatusCode = http.StatusOK
+	mockStorage := new(mocks.WebhookStorage)
+	webhook := model.NewWebhook("", "", "", "", "", "")
+	webhook.ID = webhookID
+	mockStorage.On("GetWebhook", webhookID).Return(webhook, nil)
+	mockStorage.On("ListReplayAttempts", webhookID, int64(3)).Return([]*model.ReplayAttempt{attempt}, nil)
+
+	handler := handler.NewHandler(mockStorage)
+	request, _ := http.NewRequest(http.MethodGet, "http://localhost/api/webhooks/webhookID/messages/3/replay", nil)
+
+	w := httptest.NewRecorder()
+	handler.MessageHandler(w, request)
+
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response struct {
+		WebhookID string                 `json:"webhookId"`
+		MessageID int64                  `json:"messageId"`
+		Replays   []*model.ReplayAttempt `json:"replays"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, webhookID, response.WebhookID)
+	assert.Equal(t, int64(3), response.MessageID)
+	require.Len(t, response.Replays, 1)
+	assert.Equal(t, "https://example.com", response.Replays[0].TargetURL)
+}
+
+func TestReplayHandlerWithInvalidMessageID(t *testing.T) {
+	handler := handler.NewHandler(nil)
+	request, _ := http.NewRequest(http.MethodPost, "http://localhost/api/webhooks/webhookID/messages/abc/replay", bytes.NewBufferString(`{}`))
+
+	w := httptest.NewRecorder()
+	handler.MessageHandler(w, request)
+
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+)
+
+const defaultStreamPingInterval = 15 * time.Second
+
+func (h *Handler) messageStreamHandler(w http.ResponseWriter, r *http.Request, webhook *model.Webhook) {
+	outcome := model.MessageOutcomeAll
+	if outcomeValue := r.URL.Query().Get("outcome"); outcomeValue != "" {
+		parsedOutcome, ok := model.ParseMessageOutcome(outcomeValue)
+		if !ok {
+			h.badRequestHandler(w, "outcome must be one of all, accepted, rejected, failed")
+			return
+		}
+		outcome = parsedOutcome
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.internalServerErrorHandler(w, "Streaming is not supported")
+		return
+	}
+
+	subscription := h.broker.Subscribe(webhook.ID)
+	defer h.broker.Unsubscribe(subscription)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if _, err := fmt.Fprint(w, "retry: 3000\n\n"); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	ping := time.NewTicker(h.streamPing)
+	defer ping.Stop()
+	expiry := time.NewTimer(time.Until(webhook.ExpiresAt))
+	defer expiry.Stop()
+
+	log.Printf("Streaming messages for webhook %s", webhook.ID)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-expiry.C:
+			return
+		case <-ping.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case message, ok := <-subscription.messages:
+			if !ok {
+				return
+			}
+			if !outcome.Matches(message) {
+				continue
+			}
+			if err := writeMessageEvent(w, message); err != nil {
+				log.Printf("Could not write message event for webhook %s: %s", webhook.ID, err)
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeMessageEvent(w http.ResponseWriter, message *model.Message) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: message\ndata: %s\n\n", payload)
+	return err
+}
@@ -0,0 +1,102 @@
+package handler_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/achawki/webhook-receiver/internal/handler"
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/achawki/webhook-receiver/internal/storage"
+	"github.com/achawki/webhook-receiver/internal/storage/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageStreamPushesIngestedMessages(t *testing.T) {
+	webhookID := "webhookID"
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{})
+	webhook.ID = webhookID
+	webhook.ExpiresAt = time.Now().UTC().Add(time.Hour)
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("GetWebhook", webhookID).Return(webhook, nil)
+	mockStorage.On("InsertMessage", webhookID, mock.Anything).Return(nil)
+
+	h := handler.NewHandler(mockStorage)
+	mux := http.NewServeMux()
+	h.Register(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	t.Cleanup(h.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	streamRequest, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/webhooks/"+webhookID+"/stream", nil)
+	require.NoError(t, err)
+	streamResponse, err := http.DefaultClient.Do(streamRequest)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = streamResponse.Body.Close()
+	})
+	require.Equal(t, http.StatusOK, streamResponse.StatusCode)
+	assert.Equal(t, "text/event-stream", streamResponse.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(streamResponse.Body)
+	retryLine, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "retry: 3000\n", retryLine)
+
+	hookResponse, err := http.Post(server.URL+"/hooks/"+webhookID, "application/json", bytes.NewBufferString(`{"hello":"world"}`))
+	require.NoError(t, err)
+	require.NoError(t, hookResponse.Body.Close())
+
+	var data string
+	for data == "" {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if strings.HasPrefix(line, "data: ") {
+			data = strings.TrimSpace(strings.TrimPrefix(line, "data: "))
+		}
+	}
+
+	var message model.Message
+	require.NoError(t, json.Unmarshal([]byte(data), &message))
+	assert.Equal(t, http.MethodPost, message.Method)
+	assert.Equal(t, `{"hello":"world"}`, message.Payload)
+	assert.Equal(t, http.StatusOK, message.StatusCode)
+}
+
+func TestMessageStreamWithUnknownWebhook(t *testing.T) {
+	webhookID := "webhookID"
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("GetWebhook", webhookID).Return(nil, &storage.WebhookNotFoundError{WebhookId: webhookID})
+	h := handler.NewHandler(mockStorage)
+	request, _ := http.NewRequest(http.MethodGet, "http://localhost/api/webhooks/"+webhookID+"/stream", nil)
+
+	w := httptest.NewRecorder()
+	h.MessageHandler(w, request)
+
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestMessageStreamRejectsInvalidOutcome(t *testing.T) {
+	webhookID := "webhookID"
+	webhook := &model.Webhook{ID: webhookID, ExpiresAt: time.Now().UTC().Add(time.Hour)}
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("GetWebhook", webhookID).Return(webhook, nil)
+	h := handler.NewHandler(mockStorage)
+	request, _ := http.NewRequest(http.MethodGet, "http://localhost/api/webhooks/"+webhookID+"/stream?outcome=broken", nil)
+
+	w := httptest.NewRecorder()
+	h.MessageHandler(w, request)
+
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	assert.JSONEq(t, `{"message":"outcome must be one of all, accepted, rejected, failed"}`, w.Body.String())
+}
@@ -1,20 +1,27 @@
 package handler
 
 import (
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/achawki/webhook-receiver/internal/model"
 	"github.com/achawki/webhook-receiver/internal/storage"
 )
 
 type homePageData struct {
-	PageTitle string
-	Error     string
+	PageTitle   string
+	Error       string
+	MaxTTLHours int
+	MaxMessages int
 }
 
 type webhookPageData struct {
@@ -22,7 +29,9 @@ type webhookPageData struct {
 	Webhook    webhookCardView
 	Requests   []requestView
 	Outcome    outcomeFilterView
+	Search     searchView
 	Pagination paginationView
+	Stream     streamView
 }
 
 type webhookCardView struct {
@@ -32,19 +41,81 @@ type webhookCardView struct {
 	PublicIngestURL string
 	MessagesURL     string
 	ExpiresAt       string
+	Response        *responseView
+	Redaction       *model.Redaction
+	ForwardURL      string
+	MaxMessages     int
+}
+
+type responseView struct {
+	StatusCode  int
+	StatusText  string
+	ContentType string
+	Headers     []headerView
+	Body        string
 }
 
 type requestView struct {
-	Method       string
-	Path         string
-	Query        string
-	Payload      string
-	Time         string
-	Headers      []headerView
-	StatusCode   int
-	StatusText   string
-	Rejected     bool
-	ErrorMessage string
+	ID                int64
+	Anchor            string
+	MessageURL        string
+	ReplayURL         string
+	Method            string
+	Path              string
+	Query             string
+	Payload           string
+	Binary            *binaryPayloadView
+	Parsed            *parsedBodyView
+	Time              string
+	Headers           []headerView
+	StatusCode        int
+	StatusText        string
+	Rejected          bool
+	Duplicate         bool
+	ErrorMessage      string
+	Metadata          *requestMetadataView
+	ClientCertificate *model.ClientCertificate
+	Forward           *forwardView
+}
+
+type requestMetadataView struct {
+	RemoteIP      string
+	Protocol      string
+	Host          string
+	ContentLength int64
+	TLS           *model.TLSDetails
+	Processing    string
+}
+
+type binaryPayloadView struct {
+	Size         int
+	DownloadURL  string
+	HexDump      string
+	HexTruncated bool
+}
+
+type parsedBodyView struct {
+	Error  string
+	JSON   string
+	Fields []model.FormField
+	Parts  []multipartPartView
+	XML    string
+}
+
+type multipartPartView struct {
+	Name     string
+	Filename string
+	Headers  []headerView
+	Size     int64
+	Value    string
+}
+
+type forwardView struct {
+	StatusCode    int
+	StatusText    string
+	LatencyMillis int64
+	Headers       []headerView
+	Body          string
 }
 
 type paginationView struct {
@@ -55,6 +126,7 @@ type paginationView struct {
 	Outcome         string
 	HasNextPage     bool
 	HasPreviousPage bool
+	SearchTruncated bool
 	NextPageURL     string
 	PreviousPageURL string
 }
@@ -64,6 +136,29 @@ type outcomeFilterView struct {
 	AllURL      string
 	AcceptedURL string
 	RejectedURL string
+	FailedURL   string
+}
+
+type searchView struct {
+	Active      bool
+	Outcome     string
+	PageSize    int
+	Method      string
+	Path        string
+	Status      string
+	Header      string
+	HeaderValue string
+	Since       string
+	Until       string
+	Text        string
+	JSONFilter  string
+	ClearURL    string
+}
+
+type streamView struct {
+	Enabled      bool
+	URL          string
+	MessagesPath string
 }
 
 type headerView struct {
@@ -135,13 +230,13 @@ func (h *Handler) WebhookPageHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	page, pageSize, outcome, err := messagePageFromQuery(r)
+	page, pageSize, filter, err := messagePageFromQuery(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	messagePage, err := h.storage.GetMessagePageForWebhook(webhookID, page, pageSize, outcome)
+	messagePage, err := h.storage.GetMessagePageForWebhook(webhookID, page, pageSize, filter)
 	if err != nil {
 		log.Printf("Could not retrieve messages for detail page: %s", err)
 		http.Error(w, "Could not retrieve requests", http.StatusInternalServerError)
@@ -151,9 +246,11 @@ func (h *Handler) WebhookPageHandler(w http.ResponseWriter, r *http.Request) {
 	data := webhookPageData{
 		PageTitle:  fmt.Sprintf("Webhook %s", webhookID),
 		Webhook:    h.buildWebhookCardView(r, webhook),
-		Requests:   buildRequestViews(messagePage.Messages),
-		Outcome:    buildOutcomeFilterView(webhookID, pageSize, outcome),
-		Pagination: buildPaginationView(webhookID, messagePage, outcome),
+		Requests:   buildRequestViews(webhookID, messagePage.Messages),
+		Outcome:    buildOutcomeFilterView(webhookID, pageSize, filter),
+		Search:     buildSearchView(webhookID, pageSize, filter),
+		Pagination: buildPaginationView(webhookID, messagePage, filter),
+		Stream:     buildStreamView(webhookID, messagePage, filter),
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -175,6 +272,29 @@ func (h *Handler) webhookFormPOSTHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	response, err := webhookResponseFromForm(r)
+	if err != nil {
+		h.renderHomePage(w, r, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	ttlSeconds, err := optionalFormInt(r, "ttlHours", "ttl hours")
+	if err != nil {
+		h.renderHomePage(w, r, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	maxMessages, err := optionalFormInt(r, "maxMessages", "max messages")
+	if err != nil {
+		h.renderHomePage(w, r, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	maxSkewSeconds, err := optionalFormInt(r, "replayMaxSkewSeconds", "max timestamp skew")
+	if err != nil {
+		h.renderHomePage(w, r, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
 	webhookInput := &model.WebhookInput{
 		Username:   r.FormValue("username"),
 		Password:   r.FormValue("password"),
@@ -182,6 +302,48 @@ func (h *Handler) webhookFormPOSTHandler(w http.ResponseWriter, r *http.Request)
 		TokenValue: r.FormValue("tokenValue"),
 		HMACHeader: r.FormValue("hmacHeader"),
 		HMACSecret: r.FormValue("hmacSecret"),
+		HMACOptions: &model.HMACOptions{
+			Algorithm: model.HMACAlgorithm(r.FormValue("hmacAlgorithm")),
+			Encoding:  model.SignatureEncoding(r.FormValue("hmacEncoding")),
+			Prefix:    r.FormValue("hmacPrefix"),
+			Template:  r.FormValue("hmacTemplate"),
+		},
+		Verifier:  model.Verifier(r.FormValue("verifier")),
+		JWTSecret: r.FormValue("jwtSecret"),
+		JWT: &model.JWTValidation{
+			Issuer:   r.FormValue("jwtIssuer"),
+			Audience: r.FormValue("jwtAudience"),
+			JWKS:     json.RawMessage(r.FormValue("jwtJwks")),
+		},
+		PublicKeySignature: &model.PublicKeySignature{
+			Algorithm: model.SignatureAlgorithm(r.FormValue("signatureAlgorithm")),
+			PublicKey: r.FormValue("signaturePublicKey"),
+			Header:    r.FormValue("signatureHeader"),
+			Encoding:  model.SignatureEncoding(r.FormValue("signatureEncoding")),
+			Prefix:    r.FormValue("signaturePrefix"),
+			Template:  r.FormValue("signatureTemplate"),
+		},
+		ReplayProtection: &model.ReplayProtection{
+			TimestampHeader:  r.FormValue("replayTimestampHeader"),
+			MaxSkewSeconds:   maxSkewSeconds,
+			DeliveryIDHeader: r.FormValue("replayDeliveryIdHeader"),
+			FlagDuplicates:   r.FormValue("replayFlagDuplicates") != "",
+		},
+		MutualTLS: &model.MutualTLS{
+			Required:       r.FormValue("mtlsRequired") != "",
+			CACertificates: r.FormValue("mtlsCaCertificates"),
+			Fingerprints:   strings.Split(r.FormValue("mtlsFingerprints"), "\n"),
+		},
+		AllowedCIDRs: strings.Split(r.FormValue("allowedCidrs"), "\n"),
+		Redaction: &model.Redaction{
+			DropHeaders:  strings.Split(r.FormValue("redactionDropHeaders"), "\n"),
+			MaskHeaders:  strings.Split(r.FormValue("redactionMaskHeaders"), "\n"),
+			PayloadPaths: strings.Split(r.FormValue("redactionPayloadPaths"), "\n"),
+		},
+		Response:    response,
+		ForwardURL:  r.FormValue("forwardUrl"),
+		TTLSeconds:  ttlSeconds * int(time.Hour/time.Second),
+		MaxMessages: maxMessages,
 	}
 
 	webhook := model.NewWebhookFromInput(webhookInput)
@@ -189,6 +351,17 @@ func (h *Handler) webhookFormPOSTHandler(w http.ResponseWriter, r *http.Request)
 		h.renderHomePage(w, r, err.Error(), http.StatusUnprocessableEntity)
 		return
 	}
+	if err := webhook.ApplyRetention(webhookInput.TTLSeconds, webhookInput.MaxMessages, h.retentionLimits, time.Now()); err != nil {
+		h.renderHomePage(w, r, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	managementSecret, err := webhook.IssueManagementSecret()
+	if err != nil {
+		log.Printf("Could not issue management secret from form: %s", err)
+		h.renderHomePage(w, r, "Could not create webhook", http.StatusInternalServerError)
+		return
+	}
 
 	id, err := h.storage.InsertWebhook(webhook)
 	if err != nil {
@@ -197,13 +370,64 @@ func (h *Handler) webhookFormPOSTHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	http.Redirect(w, r, fmt.Sprintf("/webhooks/%s", id), http.StatusSeeOther)
+	// The fragment is never sent back to the server, so the secret is shown once
+	// on the detail page without ending up in access logs.
+	http.Redirect(w, r, fmt.Sprintf("/webhooks/%s#managementSecret=%s", id, managementSecret), http.StatusSeeOther)
+}
+
+func optionalFormInt(r *http.Request, field string, label string) (int, error) {
+	value := strings.TrimSpace(r.FormValue(field))
+	if value == "" {
+		return 0, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number", label)
+	}
+
+	return number, nil
+}
+
+func webhookResponseFromForm(r *http.Request) (*model.WebhookResponse, error) {
+	response := &model.WebhookResponse{
+		ContentType: r.FormValue("responseContentType"),
+		Body:        r.FormValue("responseBody"),
+	}
+
+	if statusValue := strings.TrimSpace(r.FormValue("responseStatusCode")); statusValue != "" {
+		statusCode, err := strconv.Atoi(statusValue)
+		if err != nil {
+			return nil, errors.New("response status code must be a number")
+		}
+		response.StatusCode = statusCode
+	}
+
+	for _, line := range strings.Split(r.FormValue("responseHeaders"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("response header line %q must use the form Name: value", line)
+		}
+		if response.Headers == nil {
+			response.Headers = map[string]string{}
+		}
+		response.Headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+
+	return response, nil
 }
 
 func (h *Handler) renderHomePage(w http.ResponseWriter, r *http.Request, errorMessage string, statusCode int) {
 	data := homePageData{
-		PageTitle: "Webhook Receiver",
-		Error:     errorMessage,
+		PageTitle:   "Webhook Receiver",
+		Error:       errorMessage,
+		MaxTTLHours: int(h.retentionLimits.TTLLimit() / time.Hour),
+		MaxMessages: h.retentionLimits.MessageLimit(),
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -223,71 +447,296 @@ func (h *Handler) buildWebhookCardView(r *http.Request, webhook *model.Webhook)
 		PublicIngestURL: capabilityURL(baseURL, fmt.Sprintf("/hooks/%s", webhook.ID)),
 		MessagesURL:     capabilityURL(baseURL, fmt.Sprintf("/api/webhooks/%s/messages", webhook.ID)),
 		ExpiresAt:       webhook.ExpiresAt.Format(timeLayout),
+		Response:        buildResponseView(webhook.Response),
+		Redaction:       webhook.Redaction,
+		ForwardURL:      webhook.ForwardURL,
+		MaxMessages:     webhook.MaxMessages,
+	}
+}
+
+func buildResponseView(response *model.WebhookResponse) *responseView {
+	if response == nil {
+		return nil
+	}
+
+	headers := make([]headerView, 0, len(response.Headers))
+	for _, name := range response.HeaderNames() {
+		headers = append(headers, headerView{Name: name, Values: response.Headers[name]})
+	}
+
+	return &responseView{
+		StatusCode:  response.EffectiveStatusCode(),
+		StatusText:  http.StatusText(response.EffectiveStatusCode()),
+		ContentType: response.ContentType,
+		Headers:     headers,
+		Body:        response.Body,
 	}
 }
 
-func buildRequestViews(messages []*model.Message) []requestView {
+func buildRequestViews(webhookID string, messages []*model.Message) []requestView {
 	requests := make([]requestView, 0, len(messages))
 	for _, message := range messages {
+		if message.ParsedBody == nil {
+			message.DecodeBody()
+		}
 		requests = append(requests, requestView{
-			Method:       message.Method,
-			Path:         message.Path,
-			Query:        message.Query,
-			Payload:      message.Payload,
-			Time:         message.Time.Format(timeLayout),
-			Headers:      buildHeaderViews(message.Headers),
-			StatusCode:   message.StatusCode,
-			StatusText:   http.StatusText(message.StatusCode),
-			Rejected:     message.Rejected(),
-			ErrorMessage: message.ErrorMessage,
+			ID:                message.ID,
+			Anchor:            messageAnchor(message.ID),
+			MessageURL:        fmt.Sprintf("/api/webhooks/%s/messages/%d", webhookID, message.ID),
+			ReplayURL:         fmt.Sprintf("/api/webhooks/%s/messages/%d/replay", webhookID, message.ID),
+			Method:            message.Method,
+			Path:              message.Path,
+			Query:             message.Query,
+			Payload:           message.Payload,
+			Binary:            buildBinaryPayloadView(webhookID, message),
+			Parsed:            buildParsedBodyView(message.ParsedBody),
+			Time:              message.Time.Format(timeLayout),
+			Headers:           buildHeaderViews(message.Headers),
+			StatusCode:        message.StatusCode,
+			StatusText:        http.StatusText(message.StatusCode),
+			Rejected:          message.Rejected(),
+			Duplicate:         message.Duplicate,
+			ErrorMessage:      message.ErrorMessage,
+			Metadata:          buildRequestMetadataView(message),
+			ClientCertificate: message.ClientCertificate,
+			Forward:           buildForwardView(message.Forward),
 		})
 	}
 
 	return requests
 }
 
-func buildPaginationView(webhookID string, page *model.MessagePage, outcome model.MessageOutcome) paginationView {
+func messageAnchor(messageID int64) string {
+	return fmt.Sprintf("message-%d", messageID)
+}
+
+func buildBinaryPayloadView(webhookID string, message *model.Message) *binaryPayloadView {
+	if !message.Binary() {
+		return nil
+	}
+
+	body := message.Body()
+	view := &binaryPayloadView{
+		Size:        len(body),
+		DownloadURL: fmt.Sprintf("/api/webhooks/%s/messages/%d/payload", webhookID, message.ID),
+	}
+	if len(body) > maxHexViewBytes {
+		body = body[:maxHexViewBytes]
+		view.HexTruncated = true
+	}
+	view.HexDump = hex.Dump(body)
+
+	return view
+}
+
+func buildParsedBodyView(parsed *model.ParsedBody) *parsedBodyView {
+	if parsed == nil {
+		return nil
+	}
+
+	view := &parsedBodyView{
+		Error:  parsed.Error,
+		Fields: parsed.Fields,
+		XML:    parsed.XML,
+	}
+	if parsed.JSON != nil {
+		indented, err := json.MarshalIndent(parsed.JSON, "", "  ")
+		if err != nil {
+			view.Error = err.Error()
+		} else {
+			view.JSON = string(indented)
+		}
+	}
+	for _, part := range parsed.Parts {
+		view.Parts = append(view.Parts, multipartPartView{
+			Name:     part.Name,
+			Filename: part.Filename,
+			Headers:  buildHeaderViews(part.Headers),
+			Size:     part.Size,
+			Value:    part.Value,
+		})
+	}
+
+	return view
+}
+
+// buildRequestMetadataView returns nil for messages captured before request
+// metadata was recorded.
+func buildRequestMetadataView(message *model.Message) *requestMetadataView {
+	if message.Protocol == "" {
+		return nil
+	}
+
+	return &requestMetadataView{
+		RemoteIP:      message.RemoteIP,
+		Protocol:      message.Protocol,
+		Host:          message.Host,
+		ContentLength: message.ContentLength,
+		TLS:           message.TLS,
+		Processing:    formatProcessingDuration(message.ProcessingMicros),
+	}
+}
+
+// formatProcessingDuration matches the formatting of the live stream script.
+func formatProcessingDuration(micros int64) string {
+	if micros < 1000 {
+		return fmt.Sprintf("%d µs", micros)
+	}
+
+	return fmt.Sprintf("%.1f ms", float64(micros)/1000)
+}
+
+func buildForwardView(result *model.ForwardResult) *forwardView {
+	if result == nil || result.Failed() {
+		return nil
+	}
+
+	return &forwardView{
+		StatusCode:    result.StatusCode,
+		StatusText:    http.StatusText(result.StatusCode),
+		LatencyMillis: result.LatencyMillis,
+		Headers:       buildHeaderViews(result.Headers),
+		Body:          result.Body,
+	}
+}
+
+func buildPaginationView(webhookID string, page *model.MessagePage, filter model.MessageFilter) paginationView {
 	view := paginationView{
 		CurrentPage:     page.Page,
 		PageSize:        page.PageSize,
 		TotalMessages:   page.TotalMessages,
 		TotalPages:      page.TotalPages,
-		Outcome:         string(outcome),
+		Outcome:         string(filter.Outcome),
 		HasNextPage:     page.HasNextPage,
 		HasPreviousPage: page.HasPreviousPage,
+		SearchTruncated: page.SearchTruncated,
 	}
 
 	if page.HasPreviousPage {
-		view.PreviousPageURL = detailPageURL(webhookID, page.Page-1, page.PageSize, outcome)
+		view.PreviousPageURL = detailPageURL(webhookID, page.Page-1, page.PageSize, filter)
 	}
 	if page.HasNextPage {
-		view.NextPageURL = detailPageURL(webhookID, page.Page+1, page.PageSize, outcome)
+		view.NextPageURL = detailPageURL(webhookID, page.Page+1, page.PageSize, filter)
 	}
 
 	return view
 }
 
-func buildOutcomeFilterView(webhookID string, pageSize int, outcome model.MessageOutcome) outcomeFilterView {
+// buildStreamView enables live updates on the first page. Searches disable
+// them, since the stream only filters by outcome.
+func buildStreamView(webhookID string, page *model.MessagePage, filter model.MessageFilter) streamView {
+	streamURL := fmt.Sprintf("/api/webhooks/%s/stream", webhookID)
+	if filter.Outcome != "" && filter.Outcome != model.MessageOutcomeAll {
+		streamURL += fmt.Sprintf("?outcome=%s", filter.Outcome)
+	}
+
+	return streamView{
+		Enabled:      page.Page == 1 && !filter.Searching(),
+		URL:          streamURL,
+		MessagesPath: fmt.Sprintf("/api/webhooks/%s/messages", webhookID),
+	}
+}
+
+func buildOutcomeFilterView(webhookID string, pageSize int, filter model.MessageFilter) outcomeFilterView {
+	withOutcome := func(outcome model.MessageOutcome) string {
+		outcomeFilter := filter
+		outcomeFilter.Outcome = outcome
+		return detailPageURL(webhookID, 1, pageSize, outcomeFilter)
+	}
+
 	return outcomeFilterView{
-		Current:     string(outcome),
-		AllURL:      detailPageURL(webhookID, 1, pageSize, model.MessageOutcomeAll),
-		AcceptedURL: detailPageURL(webhookID, 1, pageSize, model.MessageOutcomeAccepted),
-		RejectedURL: detailPageURL(webhookID, 1, pageSize, model.MessageOutcomeRejected),
+		Current:     string(filter.Outcome),
+		AllURL:      withOutcome(model.MessageOutcomeAll),
+		AcceptedURL: withOutcome(model.MessageOutcomeAccepted),
+		RejectedURL: withOutcome(model.MessageOutcomeRejected),
+		FailedURL:   withOutcome(model.MessageOutcomeFailed),
+	}
+}
+
+func buildSearchView(webhookID string, pageSize int, filter model.MessageFilter) searchView {
+	view := searchView{
+		Active:      filter.Searching(),
+		PageSize:    pageSize,
+		Method:      filter.Method,
+		Path:        filter.PathPrefix,
+		Header:      filter.HeaderName,
+		HeaderValue: filter.HeaderValue,
+		Text:        filter.Text,
+		ClearURL:    detailPageURL(webhookID, 1, pageSize, model.MessageFilter{Outcome: filter.Outcome}),
+	}
+	if filter.Outcome != model.MessageOutcomeAll {
+		view.Outcome = string(filter.Outcome)
+	}
+	if filter.StatusCode != 0 {
+		view.Status = strconv.Itoa(filter.StatusCode)
 	}
+	if !filter.Since.IsZero() {
+		view.Since = filter.Since.UTC().Format(searchFormTimeLayout)
+	}
+	if !filter.Until.IsZero() {
+		view.Until = filter.Until.UTC().Format(searchFormTimeLayout)
+	}
+	if filter.JSON != nil {
+		view.JSONFilter = filter.JSON.String()
+	}
+
+	return view
 }
 
-func detailPageURL(webhookID string, page int, pageSize int, outcome model.MessageOutcome) string {
+func detailPageURL(webhookID string, page int, pageSize int, filter model.MessageFilter) string {
 	queryParts := []string{
 		fmt.Sprintf("page=%d", page),
 		fmt.Sprintf("pageSize=%d", pageSize),
 	}
-	if outcome != "" && outcome != model.MessageOutcomeAll {
-		queryParts = append(queryParts, fmt.Sprintf("outcome=%s", outcome))
+	if filter.Outcome != "" && filter.Outcome != model.MessageOutcomeAll {
+		queryParts = append(queryParts, fmt.Sprintf("outcome=%s", filter.Outcome))
+	}
+	for _, param := range []struct {
+		name  string
+		value string
+	}{
+		{name: "method", value: filter.Method},
+		{name: "path", value: filter.PathPrefix},
+		{name: "status", value: statusQueryValue(filter.StatusCode)},
+		{name: "header", value: filter.HeaderName},
+		{name: "headerValue", value: filter.HeaderValue},
+		{name: "since", value: timeQueryValue(filter.Since)},
+		{name: "until", value: timeQueryValue(filter.Until)},
+		{name: "q", value: filter.Text},
+		{name: "jsonFilter", value: jsonFilterQueryValue(filter.JSON)},
+	} {
+		if param.value != "" {
+			queryParts = append(queryParts, param.name+"="+url.QueryEscape(param.value))
+		}
 	}
 
 	return fmt.Sprintf("/webhooks/%s?%s", webhookID, strings.Join(queryParts, "&"))
 }
 
+func statusQueryValue(statusCode int) string {
+	if statusCode == 0 {
+		return ""
+	}
+
+	return strconv.Itoa(statusCode)
+}
+
+func jsonFilterQueryValue(filter *model.JSONFilter) string {
+	if filter == nil {
+		return ""
+	}
+
+	return filter.String()
+}
+
+func timeQueryValue(value time.Time) string {
+	if value.IsZero() {
+		return ""
+	}
+
+	return value.UTC().Format(time.RFC3339Nano)
+}
+
 func buildHeaderViews(headers map[string][]string) []headerView {
 	names := make([]string, 0, len(headers))
 	for name := range headers {
@@ -314,12 +763,29 @@ func authModesForWebhook(webhook *model.Webhook) []string {
 	if webhook.HasHeaderToken() {
 		authModes = append(authModes, fmt.Sprintf("Header token (%s)", webhook.TokenName))
 	}
-	if webhook.HasHMAC() {
-		authModes = append(authModes, fmt.Sprintf("HMAC SHA-256 (%s)", webhook.HMACHeader))
+	if webhook.HasJWT() {
+		authModes = append(authModes, webhook.JWT.Description(webhook.JWTSecret() != ""))
+	}
+	if webhook.HasHMAC() && webhook.Verifier != "" {
+		authModes = append(authModes, fmt.Sprintf("%s (%s)", webhook.Verifier.Description(), webhook.HMACHeader))
+	} else if webhook.HasHMAC() {
+		authModes = append(authModes, fmt.Sprintf("%s (%s)", webhook.HMACOptions.Description(), webhook.HMACHeader))
+	}
+	if webhook.PublicKeySignature != nil {
+		authModes = append(authModes, webhook.PublicKeySignature.Description())
+	}
+	if webhook.MutualTLS != nil {
+		authModes = append(authModes, webhook.MutualTLS.Description())
 	}
 	if len(authModes) == 0 {
 		authModes = append(authModes, "No request authentication")
 	}
+	if webhook.ReplayProtection != nil {
+		authModes = append(authModes, webhook.ReplayProtection.Description())
+	}
+	if webhook.HasIPAllowlist() {
+		authModes = append(authModes, fmt.Sprintf("IP allowlist (%s)", strings.Join(webhook.AllowedCIDRs, ", ")))
+	}
 
 	return authModes
 }
@@ -338,3 +804,6 @@ func (h *Handler) retrieveWebhookIDFromDetailPath(path string) string {
 }
 
 const timeLayout = "2006-01-02 15:04:05 MST"
+
+// maxHexViewBytes limits how much of a binary payload is rendered as hex dump.
+const maxHexViewBytes = 4096
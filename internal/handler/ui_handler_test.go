@@ -39,6 +39,7 @@ func TestRegisterServesEmbeddedAssets(t *testing.T) {
 }
 
 func TestWebhooksPageHandlerPOSTRedirectsToDetailPage(t *testing.T) {
+	var insertedWebhook *model.Webhook
 	mockStorage := new(mocks.WebhookStorage)
 	mockStorage.On("InsertWebhook", mock.MatchedBy(func(webhook *model.Webhook) bool {
 		return webhook.Username == "username" &&
@@ -47,7 +48,9 @@ func TestWebhooksPageHandlerPOSTRedirectsToDetailPage(t *testing.T) {
 			webhook.HasHeaderToken() &&
 			webhook.HMACHeader == "X-Hub-Signature-256" &&
 			webhook.HasHMAC()
-	})).Return("webhook-123", nil)
+	})).Run(func(args mock.Arguments) {
+		insertedWebhook = args.Get(0).(*model.Webhook)
+	}).Return("webhook-123", nil)
 
 	h := handler.NewHandler(mockStorage)
 	form := url.Values{
@@ -65,7 +68,139 @@ func TestWebhooksPageHandlerPOSTRedirectsToDetailPage(t *testing.T) {
 	h.WebhooksPageHandler(w, req)
 
 	assert.Equal(t, http.StatusSeeOther, w.Result().StatusCode)
-	assert.Equal(t, "/webhooks/webhook-123", w.Result().Header.Get("Location"))
+	location := w.Result().Header.Get("Location")
+	path, managementSecret, ok := strings.Cut(location, "#managementSecret=")
+	require.True(t, ok, location)
+	assert.Equal(t, "/webhooks/webhook-123", path)
+	require.NotNil(t, insertedWebhook)
+	assert.True(t, insertedWebhook.ValidateManagementSecret(managementSecret))
+	mockStorage.AssertExpectations(t)
+}
+
+func TestWebhooksPageHandlerPOSTWithHMACOptions(t *testing.T) {
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("InsertWebhook", mock.MatchedBy(func(webhook *model.Webhook) bool {
+		return webhook.HMACOptions != nil &&
+			webhook.HMACOptions.Algorithm == "" &&
+			webhook.HMACOptions.Encoding == "" &&
+			webhook.HMACOptions.Prefix == "v0=" &&
+			webhook.HMACOptions.Template == "v0:{header:X-Slack-Request-Timestamp}:{body}"
+	})).Return("webhook-123", nil)
+
+	h := handler.NewHandler(mockStorage)
+	form := url.Values{
+		"hmacHeader":    {"X-Slack-Signature"},
+		"hmacSecret":    {"secret"},
+		"hmacAlgorithm": {"sha256"},
+		"hmacEncoding":  {"hex"},
+		"hmacPrefix":    {"v0="},
+		"hmacTemplate":  {"v0:{header:X-Slack-Request-Timestamp}:{body}"},
+	}
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost/webhooks", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	w := httptest.NewRecorder()
+	h.WebhooksPageHandler(w, req)
+
+	assert.Equal(t, http.StatusSeeOther, w.Result().StatusCode)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestWebhooksPageHandlerPOSTWithPublicKeySignature(t *testing.T) {
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("InsertWebhook", mock.MatchedBy(func(webhook *model.Webhook) bool {
+		return webhook.PublicKeySignature != nil &&
+			webhook.PublicKeySignature.Algorithm == model.SignatureEd25519 &&
+			webhook.PublicKeySignature.Header == "X-Signature-Ed25519" &&
+			webhook.PublicKeySignature.Encoding == "" &&
+			webhook.PublicKeySignature.Template == "{header:X-Signature-Timestamp}{body}"
+	})).Return("webhook-123", nil)
+
+	h := handler.NewHandler(mockStorage)
+	form := url.Values{
+		"signatureAlgorithm": {"ed25519"},
+		"signaturePublicKey": {"d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a"},
+		"signatureHeader":    {"X-Signature-Ed25519"},
+		"signatureEncoding":  {"hex"},
+		"signatureTemplate":  {"{header:X-Signature-Timestamp}{body}"},
+	}
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost/webhooks", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	w := httptest.NewRecorder()
+	h.WebhooksPageHandler(w, req)
+
+	assert.Equal(t, http.StatusSeeOther, w.Result().StatusCode)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestWebhooksPageHandlerPOSTWithMutualTLS(t *testing.T) {
+	first, second := strings.Repeat("ab", 32), strings.Repeat("cd", 32)
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("InsertWebhook", mock.MatchedBy(func(webhook *model.Webhook) bool {
+		return webhook.MutualTLS != nil &&
+			webhook.MutualTLS.Required &&
+			webhook.MutualTLS.CACertificates == "" &&
+			assert.ObjectsAreEqual([]string{first, second}, webhook.MutualTLS.Fingerprints)
+	})).Return("webhook-123", nil)
+
+	h := handler.NewHandler(mockStorage)
+	form := url.Values{
+		"mtlsFingerprints": {first + "\r\n\r\nSHA256:" + strings.ToUpper(second)},
+	}
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost/webhooks", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	w := httptest.NewRecorder()
+	h.WebhooksPageHandler(w, req)
+
+	assert.Equal(t, http.StatusSeeOther, w.Result().StatusCode)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestWebhooksPageHandlerPOSTWithAllowedCIDRs(t *testing.T) {
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("InsertWebhook", mock.MatchedBy(func(webhook *model.Webhook) bool {
+		return assert.ObjectsAreEqual([]string{"192.0.2.0/24", "198.51.100.7/32"}, webhook.AllowedCIDRs)
+	})).Return("webhook-123", nil)
+
+	h := handler.NewHandler(mockStorage)
+	form := url.Values{
+		"allowedCidrs": {"192.0.2.0/24\r\n\r\n198.51.100.7"},
+	}
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost/webhooks", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	w := httptest.NewRecorder()
+	h.WebhooksPageHandler(w, req)
+
+	assert.Equal(t, http.StatusSeeOther, w.Result().StatusCode)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestWebhooksPageHandlerPOSTWithRedaction(t *testing.T) {
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("InsertWebhook", mock.MatchedBy(func(webhook *model.Webhook) bool {
+		return assert.ObjectsAreEqual(&model.Redaction{
+			DropHeaders:  []string{"X-Internal-*"},
+			MaskHeaders:  []string{"X-Api-Key", "X-Token"},
+			PayloadPaths: []string{"$.card.number"},
+		}, webhook.Redaction)
+	})).Return("webhook-123", nil)
+
+	h := handler.NewHandler(mockStorage)
+	form := url.Values{
+		"redactionDropHeaders":  {"x-internal-*"},
+		"redactionMaskHeaders":  {"X-Api-Key\r\nx-token\r\n"},
+		"redactionPayloadPaths": {"$.card.number"},
+	}
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost/webhooks", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	w := httptest.NewRecorder()
+	h.WebhooksPageHandler(w, req)
+
+	assert.Equal(t, http.StatusSeeOther, w.Result().StatusCode)
 	mockStorage.AssertExpectations(t)
 }
 
@@ -113,10 +248,11 @@ func TestWebhookPageHandlerRendersDetailPageWithPaginationAndFilters(t *testing.
 	})
 	message.Time = time.Date(2026, 3, 21, 12, 0, 0, 0, time.UTC)
 	message.MarkRejected(http.StatusUnauthorized, "Missing basic auth credentials")
+	message.ID = 9
 
 	mockStorage := new(mocks.WebhookStorage)
 	mockStorage.On("GetWebhook", webhookID).Return(webhook, nil)
-	mockStorage.On("GetMessagePageForWebhook", webhookID, 2, 10, model.MessageOutcomeRejected).Return(&model.MessagePage{
+	mockStorage.On("GetMessagePageForWebhook", webhookID, 2, 10, model.MessageFilter{Outcome: model.MessageOutcomeRejected}).Return(&model.MessagePage{
 		Messages:        []*model.Message{message},
 		Page:            2,
 		PageSize:        10,
@@ -149,6 +285,56 @@ func TestWebhookPageHandlerRendersDetailPageWithPaginationAndFilters(t *testing.
 	assert.Contains(t, body, "Content-Type")
 	assert.Contains(t, body, "X-Trace-Id")
 	assert.Contains(t, body, "2026-03-21 12:00:00 UTC")
+	assert.Contains(t, body, `id="message-9"`)
+	assert.Contains(t, body, `href="#message-9"`)
+	assert.Contains(t, body, "/api/webhooks/"+webhookID+"/messages/9")
+	assert.NotContains(t, body, "EventSource")
+	mockStorage.AssertExpectations(t)
+}
+
+func TestWebhookPageHandlerKeepsSearchAcrossFiltersAndPages(t *testing.T) {
+	webhookID := "webhook-123"
+	webhook := model.NewWebhook("", "", "", "", "", "")
+	webhook.ID = webhookID
+	webhook.ExpiresAt = time.Date(2026, 3, 23, 12, 0, 0, 0, time.UTC)
+	jsonFilter, err := model.ParseJSONFilter("amount >= 100")
+	require.NoError(t, err)
+
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("GetWebhook", webhookID).Return(webhook, nil)
+	mockStorage.On("GetMessagePageForWebhook", webhookID, 1, 10, model.MessageFilter{
+		Outcome:    model.MessageOutcomeAll,
+		Method:     http.MethodPost,
+		HeaderName: "X-Github-Event",
+		Since:      time.Date(2026, 3, 21, 9, 30, 0, 0, time.UTC),
+		Text:       "invoice paid",
+		JSON:       jsonFilter,
+	}).Return(&model.MessagePage{
+		Messages:      []*model.Message{},
+		Page:          1,
+		PageSize:      10,
+		TotalMessages: 12,
+		TotalPages:    2,
+		HasNextPage:   true,
+	}, nil)
+
+	h := handler.NewHandler(mockStorage)
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/webhooks/"+webhookID+"?pageSize=10&method=POST&header=x-github-event&since=2026-03-21T09:30&q=invoice+paid&status=&jsonFilter=amount+%3E%3D+100", nil)
+
+	w := httptest.NewRecorder()
+	h.WebhookPageHandler(w, req)
+
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	body := w.Body.String()
+	assert.Contains(t, body, `name="q" type="search" value="invoice paid"`)
+	assert.Contains(t, body, `name="header" value="X-Github-Event"`)
+	assert.Contains(t, body, `name="jsonFilter" value="amount &gt;= 100"`)
+	assert.Contains(t, body, `name="since" type="datetime-local" step="1" value="2026-03-21T09:30:00"`)
+	assert.Contains(t, body, "/webhooks/"+webhookID+"?page=2&amp;pageSize=10&amp;method=POST&amp;header=X-Github-Event&amp;since=2026-03-21T09%3A30%3A00Z&amp;q=invoice&#43;paid&amp;jsonFilter=amount&#43;%3E%3D&#43;100")
+	assert.Contains(t, body, "/webhooks/"+webhookID+"?page=1&amp;pageSize=10&amp;outcome=rejected&amp;method=POST")
+	assert.Contains(t, body, `href="/webhooks/`+webhookID+`?page=1&amp;pageSize=10">Clear</a>`)
+	assert.Contains(t, body, "No captured requests match this search.")
+	assert.NotContains(t, body, "EventSource")
 	mockStorage.AssertExpectations(t)
 }
 
@@ -171,6 +357,100 @@ func TestWebhookPageHandlerRejectsInvalidPagination(t *testing.T) {
 	mockStorage.AssertExpectations(t)
 }
 
+func TestWebhookPageHandlerShowsPublicKeyFingerprintOnly(t *testing.T) {
+	webhookID := "webhook-123"
+	publicKey := "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a"
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{
+		PublicKeySignature: &model.PublicKeySignature{Algorithm: model.SignatureEd25519, PublicKey: publicKey, Header: "X-Signature-Ed25519"},
+	})
+	webhook.ID = webhookID
+	webhook.ExpiresAt = time.Date(2026, 3, 23, 12, 0, 0, 0, time.UTC)
+
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("GetWebhook", webhookID).Return(webhook, nil)
+	mockStorage.On("GetMessagePageForWebhook", webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAll}).Return(&model.MessagePage{
+		Messages: []*model.Message{},
+		Page:     1,
+		PageSize: 25,
+	}, nil)
+
+	h := handler.NewHandler(mockStorage)
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/webhooks/"+webhookID, nil)
+
+	w := httptest.NewRecorder()
+	h.WebhookPageHandler(w, req)
+
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	body := w.Body.String()
+	assert.Contains(t, body, "Ed25519 signature (X-Signature-Ed25519, key "+webhook.PublicKeySignature.Fingerprint()+")")
+	assert.NotContains(t, body, publicKey)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestWebhookPageHandlerListsIPAllowlist(t *testing.T) {
+	webhookID := "webhook-123"
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{AllowedCIDRs: []string{"192.0.2.0/24", "2001:db8::/32"}})
+	webhook.ID = webhookID
+	webhook.ExpiresAt = time.Date(2026, 3, 23, 12, 0, 0, 0, time.UTC)
+
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("GetWebhook", webhookID).Return(webhook, nil)
+	mockStorage.On("GetMessagePageForWebhook", webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAll}).Return(&model.MessagePage{
+		Messages: []*model.Message{},
+		Page:     1,
+		PageSize: 25,
+	}, nil)
+
+	h := handler.NewHandler(mockStorage)
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/webhooks/"+webhookID, nil)
+
+	w := httptest.NewRecorder()
+	h.WebhookPageHandler(w, req)
+
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Contains(t, w.Body.String(), "IP allowlist (192.0.2.0/24, 2001:db8::/32)")
+	mockStorage.AssertExpectations(t)
+}
+
+func TestWebhookPageHandlerShowsRequestMetadata(t *testing.T) {
+	webhookID := "webhook-123"
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{})
+	webhook.ID = webhookID
+	webhook.ExpiresAt = time.Date(2026, 3, 23, 12, 0, 0, 0, time.UTC)
+	message := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", "{}", nil)
+	message.ID = 1
+	message.RemoteIP = "203.0.113.10"
+	message.Protocol = "HTTP/2.0"
+	message.Host = "receiver.example"
+	message.ContentLength = 2
+	message.TLS = &model.TLSDetails{Version: "TLS 1.3", CipherSuite: "TLS_AES_128_GCM_SHA256", ServerName: "receiver.example"}
+	message.ProcessingMicros = 2500
+
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("GetWebhook", webhookID).Return(webhook, nil)
+	mockStorage.On("GetMessagePageForWebhook", webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAll}).Return(&model.MessagePage{
+		Messages:      []*model.Message{message},
+		Page:          1,
+		PageSize:      25,
+		TotalMessages: 1,
+		TotalPages:    1,
+	}, nil)
+
+	h := handler.NewHandler(mockStorage)
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/webhooks/"+webhookID, nil)
+
+	w := httptest.NewRecorder()
+	h.WebhookPageHandler(w, req)
+
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	body := w.Body.String()
+	assert.Contains(t, body, "Sender and connection")
+	assert.Contains(t, body, "203.0.113.10")
+	assert.Contains(t, body, "TLS 1.3, TLS_AES_128_GCM_SHA256")
+	assert.Contains(t, body, "2.5 ms")
+	mockStorage.AssertExpectations(t)
+}
+
 func TestWebhookPageHandlerUsesRelativeURLsWithoutPublicBaseURL(t *testing.T) {
 	webhookID := "webhook-123"
 	webhook := model.NewWebhook("", "", "", "", "", "")
@@ -179,7 +459,7 @@ func TestWebhookPageHandlerUsesRelativeURLsWithoutPublicBaseURL(t *testing.T) {
 
 	mockStorage := new(mocks.WebhookStorage)
 	mockStorage.On("GetWebhook", webhookID).Return(webhook, nil)
-	mockStorage.On("GetMessagePageForWebhook", webhookID, 1, 25, model.MessageOutcomeAll).Return(&model.MessagePage{
+	mockStorage.On("GetMessagePageForWebhook", webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAll}).Return(&model.MessagePage{
 		Messages:        []*model.Message{},
 		Page:            1,
 		PageSize:        25,
@@ -201,6 +481,7 @@ func TestWebhookPageHandlerUsesRelativeURLsWithoutPublicBaseURL(t *testing.T) {
 	body := w.Body.String()
 	assert.Contains(t, body, "/hooks/"+webhookID)
 	assert.Contains(t, body, "/api/webhooks/"+webhookID+"/messages")
+	assert.Contains(t, body, "/api/webhooks/"+webhookID+"/stream")
 	assert.NotContains(t, body, "evil.example")
 	mockStorage.AssertExpectations(t)
 }
@@ -221,3 +502,209 @@ func TestWebhookPageHandlerShowsDescriptiveMessageForMissingWebhook(t *testing.T
 	assert.Contains(t, w.Body.String(), "Create Webhook")
 	mockStorage.AssertExpectations(t)
 }
+
+func TestWebhooksPageHandlerPOSTAcceptsCustomResponse(t *testing.T) {
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("InsertWebhook", mock.MatchedBy(func(webhook *model.Webhook) bool {
+		return webhook.Response != nil &&
+			webhook.Response.StatusCode == http.StatusAccepted &&
+			webhook.Response.ContentType == "text/plain" &&
+			webhook.Response.Body == "challenge" &&
+			webhook.Response.Headers["X-Ack"] == "received" &&
+			webhook.Response.Headers["X-Trace"] == "a:b"
+	})).Return("webhook-123", nil)
+
+	h := handler.NewHandler(mockStorage)
+	form := url.Values{
+		"responseStatusCode":  {"202"},
+		"responseContentType": {"text/plain"},
+		"responseHeaders":     {"X-Ack: received\r\n\r\nX-Trace: a:b"},
+		"responseBody":        {"challenge"},
+	}
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost/webhooks", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	w := httptest.NewRecorder()
+	h.WebhooksPageHandler(w, req)
+
+	assert.Equal(t, http.StatusSeeOther, w.Result().StatusCode)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestWebhooksPageHandlerPOSTRejectsMalformedResponseHeaders(t *testing.T) {
+	h := handler.NewHandler(nil)
+	form := url.Values{
+		"responseHeaders": {"missing-separator"},
+	}
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost/webhooks", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	w := httptest.NewRecorder()
+	h.WebhooksPageHandler(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Result().StatusCode)
+	assert.Contains(t, w.Body.String(), "must use the form Name: value")
+}
+
+func TestWebhookPageHandlerShowsConfiguredResponse(t *testing.T) {
+	webhookID := "webhook-123"
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{
+		Response: &model.WebhookResponse{
+			StatusCode:  http.StatusAccepted,
+			ContentType: "application/json",
+			Headers:     map[string]string{"X-Ack": "received"},
+			Body:        `{"ok":true}`,
+		},
+	})
+	webhook.ID = webhookID
+	webhook.ExpiresAt = time.Date(2026, 3, 23, 12, 0, 0, 0, time.UTC)
+
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("GetWebhook", webhookID).Return(webhook, nil)
+	mockStorage.On("GetMessagePageForWebhook", webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAll}).Return(&model.MessagePage{
+		Messages: []*model.Message{},
+		Page:     1,
+		PageSize: 25,
+	}, nil)
+
+	h := handler.NewHandler(mockStorage)
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/webhooks/"+webhookID, nil)
+
+	w := httptest.NewRecorder()
+	h.WebhookPageHandler(w, req)
+
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	body := w.Body.String()
+	assert.Contains(t, body, "Configured response")
+	assert.Contains(t, body, "202 Accepted")
+	assert.Contains(t, body, "X-Ack")
+	assert.Contains(t, body, "{&#34;ok&#34;:true}")
+}
+
+func TestWebhookPageHandlerShowsForwardingResults(t *testing.T) {
+	webhookID := "webhook-123"
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{ForwardURL: "https://upstream.example.com/webhooks"})
+	webhook.ID = webhookID
+	webhook.ExpiresAt = time.Date(2026, 3, 23, 12, 0, 0, 0, time.UTC)
+
+	forwarded := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", `{"hello":"world"}`, nil)
+	forwarded.MarkForwarded(&model.ForwardResult{
+		TargetURL:     webhook.ForwardURL,
+		StatusCode:    http.StatusCreated,
+		Headers:       map[string][]string{"X-Upstream": {"yes"}},
+		Body:          "upstream-body",
+		LatencyMillis: 42,
+	})
+	failed := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", `{}`, nil)
+	failed.MarkForwarded(&model.ForwardResult{TargetURL: webhook.ForwardURL, ErrorMessage: "connection refused"})
+
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("GetWebhook", webhookID).Return(webhook, nil)
+	mockStorage.On("GetMessagePageForWebhook", webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAll}).Return(&model.MessagePage{
+		Messages:      []*model.Message{forwarded, failed},
+		Page:          1,
+		PageSize:      25,
+		TotalMessages: 2,
+		TotalPages:    1,
+	}, nil)
+
+	h := handler.NewHandler(mockStorage)
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/webhooks/"+webhookID, nil)
+
+	w := httptest.NewRecorder()
+	h.WebhookPageHandler(w, req)
+
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	body := w.Body.String()
+	assert.Contains(t, body, "Forwarding to")
+	assert.Contains(t, body, "https://upstream.example.com/webhooks")
+	assert.Contains(t, body, "/webhooks/"+webhookID+"?page=1&amp;pageSize=25&amp;outcome=failed")
+	assert.Contains(t, body, "201 Created in 42 ms")
+	assert.Contains(t, body, "X-Upstream")
+	assert.Contains(t, body, "upstream-body")
+	assert.Contains(t, body, "502 Bad Gateway")
+	assert.Contains(t, body, "Forwarding failed: connection refused")
+}
+
+func TestWebhooksPageHandlerPOSTAcceptsForwardURL(t *testing.T) {
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("InsertWebhook", mock.MatchedBy(func(webhook *model.Webhook) bool {
+		return webhook.ForwardURL == "https://upstream.example.com/webhooks"
+	})).Return("webhook-123", nil)
+
+	h := handler.NewHandler(mockStorage)
+	form := url.Values{"forwardUrl": {" https://upstream.example.com/webhooks "}}
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost/webhooks", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	w := httptest.NewRecorder()
+	h.WebhooksPageHandler(w, req)
+
+	assert.Equal(t, http.StatusSeeOther, w.Result().StatusCode)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestWebhookPageHandlerShowsHexViewForBinaryPayloads(t *testing.T) {
+	webhookID := "webhook-123"
+	webhook := model.NewWebhook("", "", "", "", "", "")
+	webhook.ID = webhookID
+	webhook.ExpiresAt = time.Date(2026, 3, 23, 12, 0, 0, 0, time.UTC)
+
+	binary := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", string([]byte{0xde, 0xad, 0xbe, 0xef}), nil)
+	binary.ID = 3
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("GetWebhook", webhookID).Return(webhook, nil)
+	mockStorage.On("GetMessagePageForWebhook", webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAll}).Return(&model.MessagePage{
+		Messages:      []*model.Message{binary},
+		Page:          1,
+		PageSize:      25,
+		TotalMessages: 1,
+		TotalPages:    1,
+	}, nil)
+
+	h := handler.NewHandler(mockStorage)
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/webhooks/"+webhookID, nil)
+
+	w := httptest.NewRecorder()
+	h.WebhookPageHandler(w, req)
+
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	body := w.Body.String()
+	assert.Contains(t, body, "Binary payload, 4 bytes.")
+	assert.Contains(t, body, `href="/api/webhooks/webhook-123/messages/3/payload"`)
+	assert.Contains(t, body, "00000000  de ad be ef")
+	assert.NotContains(t, body, "<pre>3q2+7w==</pre>")
+}
+
+func TestWebhookPageHandlerRendersDecodedBodies(t *testing.T) {
+	webhookID := "webhook-123"
+	webhook := model.NewWebhook("", "", "", "", "", "")
+	webhook.ID = webhookID
+	webhook.ExpiresAt = time.Date(2026, 3, 23, 12, 0, 0, 0, time.UTC)
+
+	jsonMessage := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", `{"event":"push"}`, map[string][]string{"Content-Type": {"application/json"}})
+	formMessage := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", "token=abc&text=hello+there", map[string][]string{"Content-Type": {"application/x-www-form-urlencoded"}})
+	brokenXML := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", "<a><b></a>", map[string][]string{"Content-Type": {"application/xml"}})
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("GetWebhook", webhookID).Return(webhook, nil)
+	mockStorage.On("GetMessagePageForWebhook", webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAll}).Return(&model.MessagePage{
+		Messages:      []*model.Message{jsonMessage, formMessage, brokenXML},
+		Page:          1,
+		PageSize:      25,
+		TotalMessages: 3,
+		TotalPages:    1,
+	}, nil)
+
+	h := handler.NewHandler(mockStorage)
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/webhooks/"+webhookID, nil)
+
+	w := httptest.NewRecorder()
+	h.WebhookPageHandler(w, req)
+
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	body := w.Body.String()
+	assert.Contains(t, body, "{\n  &#34;event&#34;: &#34;push&#34;\n}")
+	assert.Contains(t, body, `<td class="mono">text</td><td class="mono">hello there</td>`)
+	assert.Contains(t, body, "Could not decode body: invalid XML")
+	assert.Contains(t, body, "Raw body")
+}
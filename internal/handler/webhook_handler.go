@@ -10,14 +10,35 @@ import (
 	"time"
 
 	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/achawki/webhook-receiver/internal/storage"
 )
 
 type createdWebhookResponse struct {
-	ID          string    `json:"id"`
-	DetailURL   string    `json:"detailUrl"`
-	HookURL     string    `json:"hookUrl"`
-	MessagesURL string    `json:"messagesUrl"`
-	ExpiresAt   time.Time `json:"expiresAt"`
+	ID               string    `json:"id"`
+	DetailURL        string    `json:"detailUrl"`
+	HookURL          string    `json:"hookUrl"`
+	MessagesURL      string    `json:"messagesUrl"`
+	ExpiresAt        time.Time `json:"expiresAt"`
+	MaxMessages      int       `json:"maxMessages"`
+	ManagementSecret string    `json:"managementSecret,omitempty"`
+}
+
+type webhookConfigurationResponse struct {
+	createdWebhookResponse
+	Username           string                    `json:"username,omitempty"`
+	TokenName          string                    `json:"tokenName,omitempty"`
+	HMACHeader         string                    `json:"hmacHeader,omitempty"`
+	HMACOptions        *model.HMACOptions        `json:"hmacOptions,omitempty"`
+	Verifier           model.Verifier            `json:"verifier,omitempty"`
+	PublicKeySignature *model.PublicKeySignature `json:"publicKeySignature,omitempty"`
+	JWT                *model.JWTValidation      `json:"jwt,omitempty"`
+	ReplayProtection   *model.ReplayProtection   `json:"replayProtection,omitempty"`
+	MutualTLS          *model.MutualTLS          `json:"mutualTls,omitempty"`
+	AllowedCIDRs       []string                  `json:"allowedCidrs,omitempty"`
+	Redaction          *model.Redaction          `json:"redaction,omitempty"`
+	Response           *model.WebhookResponse    `json:"response,omitempty"`
+	ForwardURL         string                    `json:"forwardUrl,omitempty"`
+	AuthModes          []string                  `json:"authModes"`
 }
 
 // WebhookHandler handles request for webhook endpoint.
@@ -58,6 +79,18 @@ func (h *Handler) createWebhook(w http.ResponseWriter, r *http.Request, webhookI
 		return
 	}
 
+	if err := webhook.ApplyRetention(webhookInput.TTLSeconds, webhookInput.MaxMessages, h.retentionLimits, time.Now()); err != nil {
+		h.validationErrorHandler(w, err.Error())
+		return
+	}
+
+	managementSecret, err := webhook.IssueManagementSecret()
+	if err != nil {
+		log.Printf("Error occurred while issuing management secret %s", err)
+		h.internalServerErrorHandler(w, "Error occurred while inserting webhook.")
+		return
+	}
+
 	id, err := h.storage.InsertWebhook(webhook)
 	if err != nil {
 		log.Printf("Error occurred while inserting webhook %s", err)
@@ -67,19 +100,150 @@ func (h *Handler) createWebhook(w http.ResponseWriter, r *http.Request, webhookI
 	webhook.ID = id
 	log.Printf("Inserted webhook with ID %s successfully", id)
 
+	response := h.buildCreatedWebhookResponse(r, webhook)
+	response.ManagementSecret = managementSecret
+	h.writeJSON(w, http.StatusOK, response)
+}
+
+// webhookManagementHandler serves GET, PATCH, and DELETE on /api/webhooks/{id}.
+// All of them require the management secret issued when the webhook was created.
+func (h *Handler) webhookManagementHandler(w http.ResponseWriter, r *http.Request, webhook *model.Webhook) {
+	if !h.requireManagementSecret(w, r, webhook) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.writeJSON(w, http.StatusOK, h.buildWebhookConfigurationResponse(r, webhook))
+	case http.MethodPatch:
+		h.webhookPATCHHandler(w, r, webhook)
+	case http.MethodDelete:
+		h.webhookDELETEHandler(w, webhook)
+	default:
+		h.UnknownHandler(w, r)
+	}
+}
+
+func (h *Handler) webhookPATCHHandler(w http.ResponseWriter, r *http.Request, webhook *model.Webhook) {
+	webhookPatch, err := decodeWebhookPatchJSONInput(w, r)
+	if err != nil {
+		log.Printf("Error occurred during json processing: %s", err)
+		h.badRequestHandler(w, processDecodingError(err))
+		return
+	}
+
+	if err := webhookPatch.Apply(webhook, h.retentionLimits, time.Now()); err != nil {
+		h.validationErrorHandler(w, err.Error())
+		return
+	}
+	if err := webhook.Validate(); err != nil {
+		log.Printf("Error occurred during webhook validation %s", err)
+		h.validationErrorHandler(w, err.Error())
+		return
+	}
+
+	if err := h.storage.UpdateWebhook(webhook); err != nil {
+		h.webhookStorageErrorHandler(w, webhook.ID, err, "Could not update webhook")
+		return
+	}
+	log.Printf("Updated webhook with ID %s", webhook.ID)
+
+	h.writeJSON(w, http.StatusOK, h.buildWebhookConfigurationResponse(r, webhook))
+}
+
+func (h *Handler) webhookDELETEHandler(w http.ResponseWriter, webhook *model.Webhook) {
+	if err := h.storage.DeleteWebhook(webhook.ID); err != nil {
+		h.webhookStorageErrorHandler(w, webhook.ID, err, "Could not delete webhook")
+		return
+	}
+	log.Printf("Deleted webhook with ID %s", webhook.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) webhookStorageErrorHandler(w http.ResponseWriter, webhookID string, err error, message string) {
+	log.Printf("%s %s: %s", message, webhookID, err)
+	switch err.(type) {
+	case *storage.WebhookNotFoundError:
+		h.unknownWebhookHandler(w, webhookID)
+	default:
+		h.internalServerErrorHandler(w, message)
+	}
+}
+
+func (h *Handler) buildCreatedWebhookResponse(r *http.Request, webhook *model.Webhook) createdWebhookResponse {
 	baseURL := h.requestBaseURL(r)
-	h.writeJSON(w, http.StatusOK, createdWebhookResponse{
+	return createdWebhookResponse{
 		ID:          webhook.ID,
 		DetailURL:   capabilityURL(baseURL, "/webhooks/"+webhook.ID),
 		HookURL:     capabilityURL(baseURL, "/hooks/"+webhook.ID),
 		MessagesURL: capabilityURL(baseURL, "/api/webhooks/"+webhook.ID+"/messages"),
 		ExpiresAt:   webhook.ExpiresAt,
-	})
+		MaxMessages: webhook.MaxMessages,
+	}
+}
+
+func (h *Handler) buildWebhookConfigurationResponse(r *http.Request, webhook *model.Webhook) webhookConfigurationResponse {
+	return webhookConfigurationResponse{
+		createdWebhookResponse: h.buildCreatedWebhookResponse(r, webhook),
+		Username:               webhook.Username,
+		TokenName:              webhook.TokenName,
+		HMACHeader:             webhook.HMACHeader,
+		HMACOptions:            webhook.HMACOptions,
+		Verifier:               webhook.Verifier,
+		PublicKeySignature:     webhook.PublicKeySignature,
+		JWT:                    webhook.JWT,
+		ReplayProtection:       webhook.ReplayProtection,
+		MutualTLS:              webhook.MutualTLS,
+		AllowedCIDRs:           webhook.AllowedCIDRs,
+		Redaction:              webhook.Redaction,
+		Response:               webhook.Response,
+		ForwardURL:             webhook.ForwardURL,
+		AuthModes:              authModesForWebhook(webhook),
+	}
+}
+
+// requireManagementSecret answers with 401 and returns false unless the request
+// carries the webhook's management secret as a bearer token.
+func (h *Handler) requireManagementSecret(w http.ResponseWriter, r *http.Request, webhook *model.Webhook) bool {
+	if webhook.ValidateManagementSecret(managementSecretFromRequest(r)) {
+		return true
+	}
+
+	w.Header().Set("WWW-Authenticate", `Bearer realm="webhook-management"`)
+	h.writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Missing or invalid management secret"})
+	return false
+}
+
+func managementSecretFromRequest(r *http.Request) string {
+	scheme, secret, ok := strings.Cut(strings.TrimSpace(r.Header.Get("Authorization")), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+
+	return strings.TrimSpace(secret)
 }
 
 func decodeWebhookJSONInput(w http.ResponseWriter, r *http.Request) (*model.WebhookInput, error) {
 	var webhookInput model.WebhookInput
+	if err := decodeJSONObject(w, r, &webhookInput); err != nil {
+		return nil, err
+	}
+
+	return &webhookInput, nil
+}
 
+func decodeWebhookPatchJSONInput(w http.ResponseWriter, r *http.Request) (*model.WebhookPatch, error) {
+	var webhookPatch model.WebhookPatch
+	if err := decodeJSONObject(w, r, &webhookPatch); err != nil {
+		return nil, err
+	}
+
+	return &webhookPatch, nil
+}
+
+// decodeJSONObject decodes a size-limited body that must contain exactly one JSON object with known fields.
+func decodeJSONObject(w http.ResponseWriter, r *http.Request, target interface{}) error {
 	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
 	defer func() {
 		_ = r.Body.Close()
@@ -88,18 +252,18 @@ func decodeWebhookJSONInput(w http.ResponseWriter, r *http.Request) (*model.Webh
 	decoder := json.NewDecoder(r.Body)
 	decoder.DisallowUnknownFields()
 
-	if err := decoder.Decode(&webhookInput); err != nil {
-		return nil, err
+	if err := decoder.Decode(target); err != nil {
+		return err
 	}
 
 	if err := decoder.Decode(&struct{}{}); err != io.EOF {
 		if err == nil {
-			return nil, errors.New("body must only contain a single json object")
+			return errors.New("body must only contain a single json object")
 		}
-		return nil, err
+		return err
 	}
 
-	return &webhookInput, nil
+	return nil
 }
 
 func processDecodingError(err error) string {
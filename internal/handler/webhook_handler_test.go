@@ -14,6 +14,7 @@ import (
 	"github.com/achawki/webhook-receiver/internal/storage/mocks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestWebhookHandlerWithUnsupportedHTTPMethod(t *testing.T) {
@@ -122,6 +123,82 @@ func TestWebhookHandlerWithValidInput(t *testing.T) {
 	assert.True(t, response.ExpiresAt.Equal(expectedExpiry))
 }
 
+func TestWebhookHandlerAcceptsHMACOptions(t *testing.T) {
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("InsertWebhook", mock.MatchedBy(func(webhook *model.Webhook) bool {
+		return webhook.HMACOptions != nil &&
+			webhook.HMACOptions.Algorithm == model.HMACSHA1 &&
+			webhook.HMACOptions.Encoding == model.SignatureEncodingBase64 &&
+			webhook.HMACOptions.Template == "{method}:{body}"
+	})).Return("id", nil)
+	handler := handler.NewHandler(mockStorage)
+	body := `{"hmacHeader":"X-Signature","hmacSecret":"secret","hmacOptions":{"algorithm":"SHA1","encoding":"base64","template":"{method}:{body}"}}`
+	request, _ := http.NewRequest(http.MethodPost, "http://localhost/api/webhooks", bytes.NewBufferString(body))
+
+	w := httptest.NewRecorder()
+	handler.WebhookHandler(w, request)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestWebhookHandlerRejectsInvalidHMACOptions(t *testing.T) {
+	mockStorage := new(mocks.WebhookStorage)
+	handler := handler.NewHandler(mockStorage)
+	body := `{"hmacHeader":"X-Signature","hmacSecret":"secret","hmacOptions":{"algorithm":"md5"}}`
+	request, _ := http.NewRequest(http.MethodPost, "http://localhost/api/webhooks", bytes.NewBufferString(body))
+
+	w := httptest.NewRecorder()
+	handler.WebhookHandler(w, request)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Result().StatusCode)
+	mockStorage.AssertNotCalled(t, "InsertWebhook", mock.Anything)
+}
+
+func TestWebhookHandlerAppliesRequestedRetention(t *testing.T) {
+	var insertedWebhook *model.Webhook
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("InsertWebhook", mock.Anything).Return("id", nil).Run(func(args mock.Arguments) {
+		insertedWebhook = args.Get(0).(*model.Webhook)
+	})
+	handler := handler.NewHandler(mockStorage, handler.WithRetentionLimits(model.RetentionLimits{MaxTTL: 7 * 24 * time.Hour, MaxMessages: 5000}))
+	request, _ := http.NewRequest(http.MethodPost, "http://localhost/api/webhooks", bytes.NewBufferString(`{"ttlSeconds":604800,"maxMessages":5000}`))
+
+	startedAt := time.Now()
+	w := httptest.NewRecorder()
+	handler.WebhookHandler(w, request)
+
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	require.NotNil(t, insertedWebhook)
+	assert.Equal(t, 5000, insertedWebhook.MaxMessages)
+	assert.WithinDuration(t, startedAt.Add(7*24*time.Hour), insertedWebhook.ExpiresAt, time.Minute)
+
+	var response struct {
+		MaxMessages int `json:"maxMessages"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 5000, response.MaxMessages)
+}
+
+func TestWebhookHandlerRejectsRetentionBeyondServerLimits(t *testing.T) {
+	mockStorage := new(mocks.WebhookStorage)
+	handler := handler.NewHandler(mockStorage)
+
+	for body, message := range map[string]string{
+		`{"ttlSeconds":604800}`: "ttlSeconds must be between 1 and 172800",
+		`{"maxMessages":1000}`:  "maxMessages must be between 1 and 100",
+	} {
+		request, _ := http.NewRequest(http.MethodPost, "http://localhost/api/webhooks", bytes.NewBufferString(body))
+
+		w := httptest.NewRecorder()
+		handler.WebhookHandler(w, request)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Result().StatusCode)
+		assert.Contains(t, w.Body.String(), message)
+	}
+	mockStorage.AssertNotCalled(t, "InsertWebhook", mock.Anything)
+}
+
 func TestWebhookHandlerIgnoresForwardedHostByDefault(t *testing.T) {
 	expectedExpiry := time.Date(2026, 3, 23, 12, 0, 0, 0, time.UTC)
 	mockStorage := new(mocks.WebhookStorage)
@@ -209,3 +286,217 @@ func TestWebhookHandlerWithDatabaseError(t *testing.T) {
 
 	assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
 }
+
+func TestWebhookHandlerIssuesManagementSecret(t *testing.T) {
+	var insertedWebhook *model.Webhook
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("InsertWebhook", mock.Anything).Return("id", nil).Run(func(args mock.Arguments) {
+		insertedWebhook = args.Get(0).(*model.Webhook)
+	})
+	handler := handler.NewHandler(mockStorage)
+	request, _ := http.NewRequest(http.MethodPost, "http://localhost/api/webhooks", bytes.NewBuffer([]byte(`{}`)))
+
+	w := httptest.NewRecorder()
+	handler.WebhookHandler(w, request)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response struct {
+		ManagementSecret string `json:"managementSecret"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.NotEmpty(t, response.ManagementSecret)
+	if assert.NotNil(t, insertedWebhook) {
+		assert.NotEqual(t, response.ManagementSecret, insertedWebhook.ManagementSecretHash())
+		assert.True(t, insertedWebhook.ValidateManagementSecret(response.ManagementSecret))
+	}
+}
+
+func managedWebhook(t *testing.T, webhookID string) (*model.Webhook, string) {
+	t.Helper()
+
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{TokenName: "X-Webhook-Token", TokenValue: "token"})
+	webhook.ID = webhookID
+	webhook.ExpiresAt = time.Now().UTC().Add(time.Hour)
+	managementSecret, err := webhook.IssueManagementSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return webhook, managementSecret
+}
+
+func TestWebhookManagementRequiresManagementSecret(t *testing.T) {
+	webhookID := "webhookID"
+	webhook, _ := managedWebhook(t, webhookID)
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("GetWebhook", webhookID).Return(webhook, nil)
+	handler := handler.NewHandler(mockStorage)
+
+	for _, authorization := range []string{"", "Bearer wrong", "Basic d3Jvbmc="} {
+		request, _ := http.NewRequest(http.MethodDelete, "http://localhost/api/webhooks/webhookID", nil)
+		if authorization != "" {
+			request.Header.Set("Authorization", authorization)
+		}
+
+		w := httptest.NewRecorder()
+		handler.MessageHandler(w, request)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+		assert.JSONEq(t, `{"message":"Missing or invalid management secret"}`, w.Body.String())
+	}
+	mockStorage.AssertNotCalled(t, "DeleteWebhook", mock.Anything)
+}
+
+func TestWebhookManagementGETReturnsConfiguration(t *testing.T) {
+	webhookID := "webhookID"
+	webhook, managementSecret := managedWebhook(t, webhookID)
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("GetWebhook", webhookID).Return(webhook, nil)
+	handler := handler.NewHandler(mockStorage, handler.WithPublicBaseURL("https://hooks.example.com"))
+	request, _ := http.NewRequest(http.MethodGet, "http://localhost/api/webhooks/webhookID", nil)
+	request.Header.Set("Authorization", "Bearer "+managementSecret)
+
+	w := httptest.NewRecorder()
+	handler.MessageHandler(w, request)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, webhookID, response["id"])
+	assert.Equal(t, "https://hooks.example.com/hooks/webhookID", response["hookUrl"])
+	assert.Equal(t, "X-Webhook-Token", response["tokenName"])
+	assert.Equal(t, []interface{}{"Header token (X-Webhook-Token)"}, response["authModes"])
+	assert.NotContains(t, response, "managementSecret")
+	assert.NotContains(t, response, "tokenValue")
+}
+
+func TestWebhookManagementGETReturnsVerifier(t *testing.T) {
+	webhookID := "webhookID"
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{Verifier: model.VerifierStripe, HMACSecret: "whsec_secret"})
+	webhook.ID = webhookID
+	webhook.ExpiresAt = time.Now().UTC().Add(time.Hour)
+	managementSecret, err := webhook.IssueManagementSecret()
+	require.NoError(t, err)
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("GetWebhook", webhookID).Return(webhook, nil)
+	handler := handler.NewHandler(mockStorage)
+	request, _ := http.NewRequest(http.MethodGet, "http://localhost/api/webhooks/webhookID", nil)
+	request.Header.Set("Authorization", "Bearer "+managementSecret)
+
+	w := httptest.NewRecorder()
+	handler.MessageHandler(w, request)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "stripe", response["verifier"])
+	assert.Equal(t, "Stripe-Signature", response["hmacHeader"])
+	assert.Contains(t, response["authModes"], "Stripe signature (Stripe-Signature)")
+}
+
+func TestWebhookManagementGETOmitsJWTSecret(t *testing.T) {
+	webhookID := "webhookID"
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{
+		JWTSecret: "jwt-secret",
+		JWT:       &model.JWTValidation{Issuer: "https://issuer.example"},
+	})
+	webhook.ID = webhookID
+	webhook.ExpiresAt = time.Now().UTC().Add(time.Hour)
+	managementSecret, err := webhook.IssueManagementSecret()
+	require.NoError(t, err)
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("GetWebhook", webhookID).Return(webhook, nil)
+	handler := handler.NewHandler(mockStorage)
+	request, _ := http.NewRequest(http.MethodGet, "http://localhost/api/webhooks/webhookID", nil)
+	request.Header.Set("Authorization", "Bearer "+managementSecret)
+
+	w := httptest.NewRecorder()
+	handler.MessageHandler(w, request)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, map[string]interface{}{"issuer": "https://issuer.example"}, response["jwt"])
+	assert.Contains(t, response["authModes"], "JWT bearer token (HS256, issuer https://issuer.example)")
+	assert.NotContains(t, w.Body.String(), "jwt-secret")
+}
+
+func TestWebhookManagementPATCHUpdatesWebhook(t *testing.T) {
+	webhookID := "webhookID"
+	webhook, managementSecret := managedWebhook(t, webhookID)
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("GetWebhook", webhookID).Return(webhook, nil)
+	mockStorage.On("UpdateWebhook", mock.MatchedBy(func(updated *model.Webhook) bool {
+		request, _ := http.NewRequest(http.MethodPost, "http://localhost/hooks/webhookID", nil)
+		request.Header.Set("X-Webhook-Token", "rotated")
+		return updated.ID == webhookID &&
+			updated.HMACHeader == "X-Signature" &&
+			updated.HMACSecret() == "hmac-secret" &&
+			updated.ValidateReadAuthorization(request) &&
+			updated.ExpiresAt.After(time.Now().UTC().Add(47*time.Hour))
+	})).Return(nil)
+	handler := handler.NewHandler(mockStorage)
+	body := `{"tokenValue":"rotated","hmacHeader":"X-Signature","hmacSecret":"hmac-secret","ttlSeconds":172800}`
+	request, _ := http.NewRequest(http.MethodPatch, "http://localhost/api/webhooks/webhookID", bytes.NewBufferString(body))
+	request.Header.Set("Authorization", "Bearer "+managementSecret)
+
+	w := httptest.NewRecorder()
+	handler.MessageHandler(w, request)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Contains(t, w.Body.String(), `"hmacHeader":"X-Signature"`)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestWebhookManagementPATCHRejectsInvalidUpdates(t *testing.T) {
+	webhookID := "webhookID"
+	invalidBodies := map[string]int{
+		`{"ttlSeconds":172801}`:    http.StatusUnprocessableEntity,
+		`{"ttlSeconds":0}`:         http.StatusUnprocessableEntity,
+		`{"tokenName":""}`:         http.StatusUnprocessableEntity,
+		`{"forwardUrl":"ftp://x"}`: http.StatusUnprocessableEntity,
+		`{"unknown":true}`:         http.StatusBadRequest,
+	}
+
+	for body, expectedStatus := range invalidBodies {
+		webhook, managementSecret := managedWebhook(t, webhookID)
+		mockStorage := new(mocks.WebhookStorage)
+		mockStorage.On("GetWebhook", webhookID).Return(webhook, nil)
+		handler := handler.NewHandler(mockStorage)
+		request, _ := http.NewRequest(http.MethodPatch, "http://localhost/api/webhooks/webhookID", bytes.NewBufferString(body))
+		request.Header.Set("Authorization", "Bearer "+managementSecret)
+
+		w := httptest.NewRecorder()
+		handler.MessageHandler(w, request)
+
+		assert.Equal(t, expectedStatus, w.Result().StatusCode, body)
+		mockStorage.AssertNotCalled(t, "UpdateWebhook", mock.Anything)
+	}
+}
+
+func TestWebhookManagementDELETERemovesWebhook(t *testing.T) {
+	webhookID := "webhookID"
+	webhook, managementSecret := managedWebhook(t, webhookID)
+	mockStorage := new(mocks.WebhookStorage)
+	mockStorage.On("GetWebhook", webhookID).Return(webhook, nil)
+	mockStorage.On("DeleteWebhook", webhookID).Return(nil)
+	handler := handler.NewHandler(mockStorage)
+	request, _ := http.NewRequest(http.MethodDelete, "http://localhost/api/webhooks/webhookID", nil)
+	request.Header.Set("Authorization", "Bearer "+managementSecret)
+
+	w := httptest.NewRecorder()
+	handler.MessageHandler(w, request)
+
+	assert.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestWebhookManagementRejectsUnsupportedMethod(t *testing.T) {
+	handler := handler.NewHandler(nil)
+	request, _ := http.NewRequest(http.MethodPost, "http://localhost/api/webhooks/webhookID", nil)
+
+	w := httptest.NewRecorder()
+	handler.MessageHandler(w, request)
+
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
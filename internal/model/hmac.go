@@ -0,0 +1,294 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+)
+
+// HMACAlgorithm is the hash function used to compute HMAC signatures.
+type HMACAlgorithm string
+
+const (
+	// HMACSHA1 signs with HMAC-SHA1.
+	HMACSHA1 HMACAlgorithm = "sha1"
+	// HMACSHA256 signs with HMAC-SHA256. It is the default.
+	HMACSHA256 HMACAlgorithm = "sha256"
+	// HMACSHA512 signs with HMAC-SHA512.
+	HMACSHA512 HMACAlgorithm = "sha512"
+)
+
+// SignatureEncoding is how a digest is written into the signature header.
+type SignatureEncoding string
+
+const (
+	// SignatureEncodingHex writes the digest as hexadecimal. It is the default.
+	SignatureEncodingHex SignatureEncoding = "hex"
+	// SignatureEncodingBase64 writes the digest as standard base64.
+	SignatureEncodingBase64 SignatureEncoding = "base64"
+)
+
+const maxSignatureTemplateLength = 256
+
+// HMACOptions selects how HMAC signatures are computed and encoded. A nil value
+// signs the raw body with SHA-256 and expects a hex digest.
+type HMACOptions struct {
+	Algorithm HMACAlgorithm     `json:"algorithm,omitempty"`
+	Encoding  SignatureEncoding `json:"encoding,omitempty"`
+	// Prefix is stripped from the signature header before decoding, e.g. "v0=".
+	Prefix string `json:"prefix,omitempty"`
+	// Template builds the signed content from {body}, {method}, {path} and
+	// {header:Name} placeholders, e.g. "v0:{header:X-Slack-Request-Timestamp}:{body}".
+	Template string `json:"template,omitempty"`
+}
+
+// NewHMACOptions normalizes configured HMAC options and returns nil when they
+// match the defaults.
+func NewHMACOptions(options *HMACOptions) *HMACOptions {
+	if options == nil {
+		return nil
+	}
+
+	normalized := &HMACOptions{
+		Algorithm: HMACAlgorithm(strings.ToLower(strings.TrimSpace(string(options.Algorithm)))),
+		Encoding:  SignatureEncoding(strings.ToLower(strings.TrimSpace(string(options.Encoding)))),
+		Prefix:    strings.TrimSpace(options.Prefix),
+		Template:  options.Template,
+	}
+	if normalized.Algorithm == HMACSHA256 {
+		normalized.Algorithm = ""
+	}
+	if normalized.Encoding == SignatureEncodingHex {
+		normalized.Encoding = ""
+	}
+
+	if normalized.Algorithm == "" && normalized.Encoding == "" && normalized.Prefix == "" && normalized.Template == "" {
+		return nil
+	}
+
+	return normalized
+}
+
+// Validate validates the algorithm, encoding and template.
+func (o *HMACOptions) Validate() error {
+	switch o.Algorithm {
+	case "", HMACSHA1, HMACSHA256, HMACSHA512:
+	default:
+		return fmt.Errorf("hmac algorithm must be one of %s, %s or %s", HMACSHA1, HMACSHA256, HMACSHA512)
+	}
+
+	switch o.Encoding {
+	case "", SignatureEncodingHex, SignatureEncodingBase64:
+	default:
+		return fmt.Errorf("hmac encoding must be %s or %s", SignatureEncodingHex, SignatureEncodingBase64)
+	}
+
+	if strings.ContainsAny(o.Prefix, "\r\n") {
+		return errors.New("hmac prefix must not contain line breaks")
+	}
+
+	if o.Template != "" {
+		if len(o.Template) > maxSignatureTemplateLength {
+			return fmt.Errorf("hmac template must not exceed %d characters", maxSignatureTemplateLength)
+		}
+		if _, err := parseSignatureTemplate(o.Template, "hmac"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EffectiveAlgorithm returns the configured algorithm or SHA-256 when unset.
+func (o *HMACOptions) EffectiveAlgorithm() HMACAlgorithm {
+	if o == nil || o.Algorithm == "" {
+		return HMACSHA256
+	}
+
+	return o.Algorithm
+}
+
+// EffectiveEncoding returns the configured encoding or hex when unset.
+func (o *HMACOptions) EffectiveEncoding() SignatureEncoding {
+	if o == nil || o.Encoding == "" {
+		return SignatureEncodingHex
+	}
+
+	return o.Encoding
+}
+
+// Description summarizes the signature scheme, e.g. "HMAC SHA-1, base64".
+func (o *HMACOptions) Description() string {
+	description := "HMAC " + strings.ToUpper(strings.Replace(string(o.EffectiveAlgorithm()), "sha", "sha-", 1))
+	if o.EffectiveEncoding() != SignatureEncodingHex {
+		description += ", " + string(o.EffectiveEncoding())
+	}
+	if o != nil && o.Template != "" {
+		description += ", template " + o.Template
+	}
+
+	return description
+}
+
+// Sign computes the encoded signature of the request, without any prefix.
+func (o *HMACOptions) Sign(r *http.Request, body []byte, secret string) (string, error) {
+	content, err := o.signedContent(r, body)
+	if err != nil {
+		return "", err
+	}
+
+	digest := o.digest(content, secret)
+	if o.EffectiveEncoding() == SignatureEncodingBase64 {
+		return base64.StdEncoding.EncodeToString(digest), nil
+	}
+
+	return hex.EncodeToString(digest), nil
+}
+
+// signatureFailure verifies the signature header value and returns a
+// human-readable failure or an empty string on success.
+func (o *HMACOptions) signatureFailure(r *http.Request, body []byte, secret string, headerName string, signature string) string {
+	content, err := o.signedContent(r, body)
+	if err != nil {
+		return err.Error()
+	}
+
+	provided, ok := o.decodeSignature(signature)
+	if !ok || !hmac.Equal(provided, o.digest(content, secret)) {
+		return fmt.Sprintf("HMAC signature in %q did not match", headerName)
+	}
+
+	return ""
+}
+
+func (o *HMACOptions) digest(content []byte, secret string) []byte {
+	var newHash func() hash.Hash
+	switch o.EffectiveAlgorithm() {
+	case HMACSHA1:
+		newHash = sha1.New
+	case HMACSHA512:
+		newHash = sha512.New
+	default:
+		newHash = sha256.New
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(content)
+	return mac.Sum(nil)
+}
+
+// decodeSignature strips the configured prefix, or the conventional
+// "<algorithm>=" prefix, and decodes the digest.
+func (o *HMACOptions) decodeSignature(signature string) ([]byte, bool) {
+	signature = strings.TrimSpace(signature)
+	if o != nil && o.Prefix != "" {
+		signature = strings.TrimPrefix(signature, o.Prefix)
+	}
+	algorithmPrefix := string(o.EffectiveAlgorithm()) + "="
+	if len(signature) >= len(algorithmPrefix) && strings.EqualFold(signature[:len(algorithmPrefix)], algorithmPrefix) {
+		signature = signature[len(algorithmPrefix):]
+	}
+
+	return decodeSignatureValue(signature, o.EffectiveEncoding())
+}
+
+// decodeSignatureValue decodes a hex or base64 signature. Base64 is accepted
+// with or without padding and in the URL-safe alphabet.
+func decodeSignatureValue(signature string, encoding SignatureEncoding) ([]byte, bool) {
+	if encoding == SignatureEncodingBase64 {
+		for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+			if decoded, err := encoding.DecodeString(signature); err == nil {
+				return decoded, true
+			}
+		}
+		return nil, false
+	}
+
+	decoded, err := hex.DecodeString(strings.ToLower(signature))
+	return decoded, err == nil
+}
+
+func (o *HMACOptions) signedContent(r *http.Request, body []byte) ([]byte, error) {
+	if o == nil || o.Template == "" {
+		return body, nil
+	}
+
+	return renderSignatureTemplate(o.Template, "HMAC", r, body)
+}
+
+// renderSignatureTemplate builds the signed content described by a template.
+// The label names the template in errors, e.g. "HMAC".
+func renderSignatureTemplate(template string, label string, r *http.Request, body []byte) ([]byte, error) {
+	parts, err := parseSignatureTemplate(template, label)
+	if err != nil {
+		return nil, err
+	}
+
+	var content []byte
+	for _, part := range parts {
+		switch {
+		case part.literal != "":
+			content = append(content, part.literal...)
+		case part.placeholder == "body":
+			content = append(content, body...)
+		case part.placeholder == "method":
+			content = append(content, r.Method...)
+		case part.placeholder == "path":
+			content = append(content, r.URL.Path...)
+		default:
+			headerName := strings.TrimPrefix(part.placeholder, "header:")
+			value := r.Header.Get(headerName)
+			if value == "" {
+				return nil, fmt.Errorf("Missing header %q required by the %s template", headerName, label)
+			}
+			content = append(content, value...)
+		}
+	}
+
+	return content, nil
+}
+
+type signatureTemplatePart struct {
+	literal     string
+	placeholder string
+}
+
+func parseSignatureTemplate(template string, label string) ([]signatureTemplatePart, error) {
+	label = strings.ToLower(label)
+	var parts []signatureTemplatePart
+	remaining := template
+	for remaining != "" {
+		start := strings.IndexByte(remaining, '{')
+		if start < 0 {
+			parts = append(parts, signatureTemplatePart{literal: remaining})
+			break
+		}
+		if start > 0 {
+			parts = append(parts, signatureTemplatePart{literal: remaining[:start]})
+		}
+
+		end := strings.IndexByte(remaining[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("%s template has an unclosed placeholder", label)
+		}
+		placeholder := remaining[start+1 : start+end]
+		switch {
+		case placeholder == "body", placeholder == "method", placeholder == "path":
+		case strings.HasPrefix(placeholder, "header:") && validHeaderName(strings.TrimPrefix(placeholder, "header:")):
+		default:
+			return nil, fmt.Errorf("%s template placeholder {%s} is not supported; use {body}, {method}, {path} or {header:Name}", label, placeholder)
+		}
+		parts = append(parts, signatureTemplatePart{placeholder: placeholder})
+		remaining = remaining[start+end+1:]
+	}
+
+	return parts, nil
+}
@@ -0,0 +1,127 @@
+package model_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Example request from Slack's "Verifying requests from Slack" documentation.
+const (
+	slackSigningSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+	slackTimestamp     = "1531420618"
+	slackBody          = "token=xyzz0WbapA4vBCDEFasx0q6G&team_id=T1DC2JH3J&team_domain=testteamnow&channel_id=G8PSS9T3V&channel_name=foobar&user_id=U2CERLKJA&user_name=roadrunner&command=%2Fwebhook-collect&text=&response_url=https%3A%2F%2Fhooks.slack.com%2Fcommands%2FT1DC2JH3J%2F397700885554%2F96rGlfmibIGlgcZRskXaIFfN&trigger_id=398738663015.47445629121.803a0bc887a14d10d2c447fce8b6703c"
+	slackSignature     = "v0=a2114d57b48eac39b9ad189dd8316235a7b4a8d21a10bd27519666489c69b503"
+)
+
+func TestNewHMACOptionsDropsDefaults(t *testing.T) {
+	assert.Nil(t, model.NewHMACOptions(nil))
+	assert.Nil(t, model.NewHMACOptions(&model.HMACOptions{Algorithm: " SHA256 ", Encoding: "hex"}))
+
+	options := model.NewHMACOptions(&model.HMACOptions{Algorithm: "SHA1", Encoding: "Base64", Prefix: " sha1= "})
+	assert.Equal(t, &model.HMACOptions{Algorithm: model.HMACSHA1, Encoding: model.SignatureEncodingBase64, Prefix: "sha1="}, options)
+}
+
+func TestHMACOptionsValidate(t *testing.T) {
+	valid := []*model.HMACOptions{
+		{Algorithm: model.HMACSHA512},
+		{Encoding: model.SignatureEncodingBase64},
+		{Template: "{method} {path}\n{header:X-Timestamp}.{body}"},
+	}
+	for _, options := range valid {
+		assert.NoError(t, options.Validate())
+	}
+
+	invalid := []*model.HMACOptions{
+		{Algorithm: "md5"},
+		{Encoding: "base32"},
+		{Prefix: "v0=\r\n"},
+		{Template: "{body"},
+		{Template: "{query}"},
+		{Template: "{header:Bad Header}"},
+		{Template: strings.Repeat("a", 257)},
+	}
+	for _, options := range invalid {
+		assert.Error(t, options.Validate(), "%+v", options)
+	}
+}
+
+func TestHMACOptionsDescription(t *testing.T) {
+	var defaults *model.HMACOptions
+	assert.Equal(t, "HMAC SHA-256", defaults.Description())
+	assert.Equal(t, "HMAC SHA-1, base64", (&model.HMACOptions{Algorithm: model.HMACSHA1, Encoding: model.SignatureEncodingBase64}).Description())
+	assert.Equal(t, "HMAC SHA-512, template {method}:{body}", (&model.HMACOptions{Algorithm: model.HMACSHA512, Template: "{method}:{body}"}).Description())
+}
+
+func TestValidateAuthorizationWithHMACSHA1Prefix(t *testing.T) {
+	body := []byte(`{"zen":"Keep it logically awesome."}`)
+	mac := hmac.New(sha1.New, []byte("secret"))
+	mac.Write(body)
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{
+		HMACHeader:  "X-Hub-Signature",
+		HMACSecret:  "secret",
+		HMACOptions: &model.HMACOptions{Algorithm: model.HMACSHA1},
+	})
+	require.NoError(t, webhook.Validate())
+	request, _ := http.NewRequest(http.MethodPost, "/hooks/id", nil)
+	request.Header.Set("X-Hub-Signature", "sha1="+hex.EncodeToString(mac.Sum(nil)))
+
+	assert.Empty(t, webhook.AuthorizationFailure(request, body))
+}
+
+func TestValidateAuthorizationWithHMACSHA512Base64(t *testing.T) {
+	body := []byte(`{"event":"delivered"}`)
+	mac := hmac.New(sha512.New, []byte("secret"))
+	mac.Write(body)
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{
+		HMACHeader:  "X-Signature",
+		HMACSecret:  "secret",
+		HMACOptions: &model.HMACOptions{Algorithm: model.HMACSHA512, Encoding: model.SignatureEncodingBase64},
+	})
+	request, _ := http.NewRequest(http.MethodPost, "/hooks/id", nil)
+	request.Header.Set("X-Signature", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+
+	assert.Empty(t, webhook.AuthorizationFailure(request, body))
+
+	request.Header.Set("X-Signature", hex.EncodeToString(mac.Sum(nil)))
+	assert.Equal(t, `HMAC signature in "X-Signature" did not match`, webhook.AuthorizationFailure(request, body))
+}
+
+func TestValidateAuthorizationWithSlackTemplate(t *testing.T) {
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{
+		HMACHeader: "X-Slack-Signature",
+		HMACSecret: slackSigningSecret,
+		HMACOptions: &model.HMACOptions{
+			Prefix:   "v0=",
+			Template: "v0:{header:X-Slack-Request-Timestamp}:{body}",
+		},
+	})
+	require.NoError(t, webhook.Validate())
+	request, _ := http.NewRequest(http.MethodPost, "/hooks/id", nil)
+	request.Header.Set("X-Slack-Signature", slackSignature)
+	request.Header.Set("X-Slack-Request-Timestamp", slackTimestamp)
+
+	assert.Empty(t, webhook.AuthorizationFailure(request, []byte(slackBody)))
+
+	signature, err := webhook.HMACOptions.Sign(request, []byte(slackBody), slackSigningSecret)
+	require.NoError(t, err)
+	assert.Equal(t, strings.TrimPrefix(slackSignature, "v0="), signature)
+
+	request.Header.Del("X-Slack-Request-Timestamp")
+	assert.Equal(t, `Missing header "X-Slack-Request-Timestamp" required by the HMAC template`, webhook.AuthorizationFailure(request, []byte(slackBody)))
+}
+
+func TestValidateRejectsHMACOptionsWithoutHMAC(t *testing.T) {
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{HMACOptions: &model.HMACOptions{Algorithm: model.HMACSHA1}})
+
+	assert.EqualError(t, webhook.Validate(), "hmac options require an hmac header and secret")
+}
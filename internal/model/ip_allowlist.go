@@ -0,0 +1,71 @@
+package model
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+const maxAllowedCIDRs = 100
+
+// NewAllowedCIDRs normalizes configured sender networks. Bare IP addresses
+// become single-address ranges and blank entries are dropped; entries that do
+// not parse are kept as given so Validate can report them.
+func NewAllowedCIDRs(cidrs []string) []string {
+	var normalized []string
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		if ip := net.ParseIP(cidr); ip != nil {
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			cidr = fmt.Sprintf("%s/%d", ip, bits)
+		}
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			cidr = network.String()
+		}
+		normalized = append(normalized, cidr)
+	}
+
+	return normalized
+}
+
+func validateAllowedCIDRs(cidrs []string) error {
+	if len(cidrs) > maxAllowedCIDRs {
+		return fmt.Errorf("allowed cidrs must not contain more than %d ranges", maxAllowedCIDRs)
+	}
+	for _, cidr := range cidrs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("allowed cidr %q is not a valid IP address or CIDR range", cidr)
+		}
+	}
+
+	return nil
+}
+
+// HasIPAllowlist indicates whether deliveries are restricted to sender networks.
+func (w *Webhook) HasIPAllowlist() bool {
+	return len(w.AllowedCIDRs) > 0
+}
+
+// IPAllowlistFailure returns a human-readable failure when the client IP is
+// outside the allowed ranges, or an empty string when it is allowed.
+func (w *Webhook) IPAllowlistFailure(clientIP string) string {
+	if !w.HasIPAllowlist() {
+		return ""
+	}
+
+	if ip := net.ParseIP(clientIP); ip != nil {
+		for _, cidr := range w.AllowedCIDRs {
+			if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(ip) {
+				return ""
+			}
+		}
+	}
+
+	return fmt.Sprintf("Client IP %s is not in the allowed ranges", clientIP)
+}
@@ -0,0 +1,42 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAllowedCIDRs(t *testing.T) {
+	assert.Nil(t, model.NewAllowedCIDRs(nil))
+	assert.Nil(t, model.NewAllowedCIDRs([]string{" ", "\r"}))
+	assert.Equal(t,
+		[]string{"192.0.2.0/24", "198.51.100.7/32", "2001:db8::1/128", "not-a-range"},
+		model.NewAllowedCIDRs([]string{" 192.0.2.17/24\r", "198.51.100.7", "2001:DB8::1", "not-a-range"}),
+	)
+}
+
+func TestIPAllowlistFailure(t *testing.T) {
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{AllowedCIDRs: []string{"192.0.2.0/24", "2001:db8::/32"}})
+	require.NoError(t, webhook.Validate())
+
+	assert.Empty(t, webhook.IPAllowlistFailure("192.0.2.10"))
+	assert.Empty(t, webhook.IPAllowlistFailure("2001:db8::42"))
+	assert.Equal(t, "Client IP 198.51.100.7 is not in the allowed ranges", webhook.IPAllowlistFailure("198.51.100.7"))
+	assert.Equal(t, "Client IP unknown is not in the allowed ranges", webhook.IPAllowlistFailure("unknown"))
+
+	assert.Empty(t, model.NewWebhookFromInput(&model.WebhookInput{}).IPAllowlistFailure("unknown"))
+}
+
+func TestValidateRejectsInvalidAllowedCIDRs(t *testing.T) {
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{AllowedCIDRs: []string{"192.0.2.0/33"}})
+	assert.EqualError(t, webhook.Validate(), `allowed cidr "192.0.2.0/33" is not a valid IP address or CIDR range`)
+
+	tooMany := make([]string, 101)
+	for index := range tooMany {
+		tooMany[index] = "192.0.2.1"
+	}
+	webhook = model.NewWebhookFromInput(&model.WebhookInput{AllowedCIDRs: tooMany})
+	assert.EqualError(t, webhook.Validate(), "allowed cidrs must not contain more than 100 ranges")
+}
@@ -0,0 +1,246 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const maxJSONFilterLength = 512
+
+// JSONFilterOperator compares the values a JSON filter path selects with its
+// literal.
+type JSONFilterOperator string
+
+const (
+	// JSONFilterExists matches when the path selects any value.
+	JSONFilterExists JSONFilterOperator = ""
+	// JSONFilterEqual matches values equal to the literal.
+	JSONFilterEqual JSONFilterOperator = "=="
+	// JSONFilterNotEqual matches values that differ from the literal.
+	JSONFilterNotEqual JSONFilterOperator = "!="
+	// JSONFilterLess matches strings or numbers ordered before the literal.
+	JSONFilterLess JSONFilterOperator = "<"
+	// JSONFilterLessOrEqual matches strings or numbers up to the literal.
+	JSONFilterLessOrEqual JSONFilterOperator = "<="
+	// JSONFilterGreater matches strings or numbers ordered after the literal.
+	JSONFilterGreater JSONFilterOperator = ">"
+	// JSONFilterGreaterOrEqual matches strings or numbers from the literal on.
+	JSONFilterGreaterOrEqual JSONFilterOperator = ">="
+)
+
+// jsonFilterOperators is ordered so that two-character operators are found
+// before their one-character prefixes.
+var jsonFilterOperators = []JSONFilterOperator{
+	JSONFilterEqual, JSONFilterNotEqual, JSONFilterLessOrEqual, JSONFilterGreaterOrEqual, JSONFilterLess, JSONFilterGreater,
+}
+
+// JSONFilter selects JSON payloads by comparing a path with a literal, e.g.
+// `event.type == "invoice.paid"` or `amount >= 100`. A path alone matches
+// payloads in which it exists. The literal is a JSON string, number, true,
+// false or null. Comparisons match when any selected value satisfies them
+// and never match when the path is missing or the payload is not JSON.
+type JSONFilter struct {
+	raw      string
+	Path     JSONPath
+	Operator JSONFilterOperator
+	// Value is the literal: a string, json.Number, bool or nil.
+	Value interface{}
+}
+
+// ParseJSONFilter parses a filter expression.
+func ParseJSONFilter(expression string) (*JSONFilter, error) {
+	raw := strings.TrimSpace(expression)
+	if len(raw) > maxJSONFilterLength {
+		return nil, fmt.Errorf("json filter must not exceed %d characters", maxJSONFilterLength)
+	}
+
+	pathText, operator, literal, err := splitJSONFilter(raw)
+	if err != nil {
+		return nil, fmt.Errorf("json filter %q is invalid: %w", expression, err)
+	}
+	path, err := ParseJSONPath(pathText)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &JSONFilter{raw: raw, Path: path, Operator: operator}
+	if operator == JSONFilterExists {
+		return filter, nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(literal))
+	decoder.UseNumber()
+	if err := decoder.Decode(&filter.Value); err != nil || decoder.More() {
+		return nil, fmt.Errorf("json filter %q must compare with a JSON string, number, true, false or null", expression)
+	}
+	switch filter.Value.(type) {
+	case string, json.Number:
+	case bool, nil:
+		if operator != JSONFilterEqual && operator != JSONFilterNotEqual {
+			return nil, fmt.Errorf("json filter %q can only order strings and numbers", expression)
+		}
+	default:
+		return nil, fmt.Errorf("json filter %q must compare with a JSON string, number, true, false or null", expression)
+	}
+
+	return filter, nil
+}
+
+// splitJSONFilter finds the operator outside quoted path keys and returns the
+// path and literal around it.
+func splitJSONFilter(expression string) (string, JSONFilterOperator, string, error) {
+	for index := 0; index < len(expression); index++ {
+		switch char := expression[index]; {
+		case char == '[' && index+1 < len(expression) && (expression[index+1] == '\'' || expression[index+1] == '"'):
+			end := strings.IndexByte(expression[index+2:], expression[index+1])
+			if end < 0 {
+				return "", "", "", errors.New("unterminated quoted key")
+			}
+			index += end + 2
+		case strings.IndexByte("=!<>", char) >= 0:
+			for _, operator := range jsonFilterOperators {
+				if strings.HasPrefix(expression[index:], string(operator)) {
+					literal := strings.TrimSpace(expression[index+len(operator):])
+					if literal == "" {
+						return "", "", "", fmt.Errorf("missing value after %s", operator)
+					}
+					return strings.TrimSpace(expression[:index]), operator, literal, nil
+				}
+			}
+			return "", "", "", fmt.Errorf("unexpected %q, use ==, !=, <, <=, > or >=", char)
+		case char == ' ' || char == '\t':
+			if rest := strings.TrimSpace(expression[index:]); rest != "" && strings.IndexByte("=!<>", rest[0]) < 0 {
+				return "", "", "", fmt.Errorf("expected an operator after %q", expression[:index])
+			}
+		}
+	}
+
+	return expression, JSONFilterExists, "", nil
+}
+
+// String returns the expression as given.
+func (f *JSONFilter) String() string {
+	return f.raw
+}
+
+// Matches reports whether the body is JSON and one of the values selected by
+// the path satisfies the comparison.
+func (f *JSONFilter) Matches(body []byte) bool {
+	if !json.Valid(body) {
+		return false
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return false
+	}
+
+	for _, value := range selectJSONValues(document, f.Path.segments) {
+		if f.compare(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func selectJSONValues(value interface{}, segments []jsonPathSegment) []interface{} {
+	if len(segments) == 0 {
+		return []interface{}{value}
+	}
+
+	segment, rest := segments[0], segments[1:]
+	var selected []interface{}
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		if segment.wildcard {
+			for _, member := range typed {
+				selected = append(selected, selectJSONValues(member, rest)...)
+			}
+		} else if member, ok := typed[segment.key]; ok && !segment.isIndex {
+			selected = append(selected, selectJSONValues(member, rest)...)
+		}
+	case []interface{}:
+		for index, element := range typed {
+			if segment.matchesIndex(index) {
+				selected = append(selected, selectJSONValues(element, rest)...)
+			}
+		}
+	}
+
+	return selected
+}
+
+func (f *JSONFilter) compare(value interface{}) bool {
+	if f.Operator == JSONFilterExists {
+		return true
+	}
+
+	var (
+		order      int
+		comparable bool
+	)
+	switch literal := f.Value.(type) {
+	case string:
+		if text, ok := value.(string); ok {
+			order, comparable = strings.Compare(text, literal), true
+		}
+	case json.Number:
+		if number, ok := value.(json.Number); ok {
+			order, comparable = compareJSONNumbers(number, literal)
+		}
+	case bool:
+		if flag, ok := value.(bool); ok {
+			comparable = true
+			if flag != literal {
+				order = 1
+			}
+		}
+	case nil:
+		if value == nil {
+			comparable = true
+		}
+	}
+
+	switch f.Operator {
+	case JSONFilterEqual:
+		return comparable && order == 0
+	case JSONFilterNotEqual:
+		return !comparable || order != 0
+	case JSONFilterLess:
+		return comparable && order < 0
+	case JSONFilterLessOrEqual:
+		return comparable && order <= 0
+	case JSONFilterGreater:
+		return comparable && order > 0
+	case JSONFilterGreaterOrEqual:
+		return comparable && order >= 0
+	default:
+		return false
+	}
+}
+
+func compareJSONNumbers(value json.Number, literal json.Number) (int, bool) {
+	left, err := strconv.ParseFloat(string(value), 64)
+	if err != nil {
+		return 0, false
+	}
+	right, err := strconv.ParseFloat(string(literal), 64)
+	if err != nil {
+		return 0, false
+	}
+
+	switch {
+	case left < right:
+		return -1, true
+	case left > right:
+		return 1, true
+	default:
+		return 0, true
+	}
+}
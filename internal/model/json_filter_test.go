@@ -0,0 +1,90 @@
+package model_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJSONFilter(t *testing.T) {
+	filter, err := model.ParseJSONFilter(` event.type == "invoice.paid" `)
+	require.NoError(t, err)
+	assert.Equal(t, "event.type", filter.Path.String())
+	assert.Equal(t, model.JSONFilterEqual, filter.Operator)
+	assert.Equal(t, "invoice.paid", filter.Value)
+	assert.Equal(t, `event.type == "invoice.paid"`, filter.String())
+
+	filter, err = model.ParseJSONFilter(`$['a == b']>=10.5`)
+	require.NoError(t, err)
+	assert.Equal(t, "$['a == b']", filter.Path.String())
+	assert.Equal(t, model.JSONFilterGreaterOrEqual, filter.Operator)
+	assert.Equal(t, json.Number("10.5"), filter.Value)
+
+	filter, err = model.ParseJSONFilter(`items[*].id`)
+	require.NoError(t, err)
+	assert.Equal(t, model.JSONFilterExists, filter.Operator)
+	assert.Nil(t, filter.Value)
+}
+
+func TestParseJSONFilterRejectsInvalidExpressions(t *testing.T) {
+	invalidExpressions := map[string]string{
+		`event.type = "a"`:   `json filter "event.type = \"a\"" is invalid: unexpected '=', use ==, !=, <, <=, > or >=`,
+		`event.type ==`:      `json filter "event.type ==" is invalid: missing value after ==`,
+		`event type`:         `json filter "event type" is invalid: expected an operator after "event"`,
+		`$['type == "a"`:     `json filter "$['type == \"a\"" is invalid: unterminated quoted key`,
+		`== "a"`:             `json path "" must not be empty`,
+		`event.type == paid`: `json filter "event.type == paid" must compare with a JSON string, number, true, false or null`,
+		`event == {"a":1}`:   `json filter "event == {\"a\":1}" must compare with a JSON string, number, true, false or null`,
+		`event == "a" "b"`:   `json filter "event == \"a\" \"b\"" must compare with a JSON string, number, true, false or null`,
+		`live > true`:        `json filter "live > true" can only order strings and numbers`,
+	}
+
+	for expression, expected := range invalidExpressions {
+		_, err := model.ParseJSONFilter(expression)
+		assert.EqualError(t, err, expected, expression)
+	}
+}
+
+func TestJSONFilterMatches(t *testing.T) {
+	body := []byte(`{"event":{"type":"invoice.paid"},"amount":1200,"live":true,"note":null,"items":[{"id":1},{"id":2}]}`)
+
+	matches := map[string]bool{
+		`event.type == "invoice.paid"`:  true,
+		`event.type != "invoice.paid"`:  false,
+		`event.type < "invoice.voided"`: true,
+		`event.type == 1`:               false,
+		`event.type != 1`:               true,
+		`amount == 1.2e3`:               true,
+		`amount > 1200`:                 false,
+		`amount >= 1200`:                true,
+		`amount == "1200"`:              false,
+		`live == true`:                  true,
+		`live != false`:                 true,
+		`note == null`:                  true,
+		`note`:                          true,
+		`missing`:                       false,
+		`missing != "x"`:                false,
+		`items[*].id == 2`:              true,
+		`items[0].id == 2`:              false,
+		`$.*.type == "invoice.paid"`:    true,
+	}
+
+	for expression, expected := range matches {
+		filter, err := model.ParseJSONFilter(expression)
+		require.NoError(t, err, expression)
+		assert.Equal(t, expected, filter.Matches(body), expression)
+	}
+}
+
+func TestJSONFilterDoesNotMatchNonJSONPayloads(t *testing.T) {
+	filter, err := model.ParseJSONFilter(`type`)
+	require.NoError(t, err)
+
+	for _, body := range []string{"", "type=invoice.paid", `{"type":`, string([]byte{'"', 0xff, '"'})} {
+		assert.False(t, filter.Matches([]byte(body)), body)
+	}
+	assert.True(t, filter.Matches([]byte(` {"type":"a"} `)))
+}
@@ -0,0 +1,147 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const maxJSONPathLength = 256
+
+// JSONPath addresses values in a JSON document with a small subset of
+// JSONPath: dotted keys ("$.card.number" or "card.number"), array indexes
+// ("items[0]"), wildcards ("items[*].id", "$.*") and quoted keys
+// ("$['key.with.dots']").
+type JSONPath struct {
+	raw      string
+	segments []jsonPathSegment
+}
+
+type jsonPathSegment struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// ParseJSONPath parses a path in the supported JSONPath subset.
+func ParseJSONPath(path string) (JSONPath, error) {
+	raw := strings.TrimSpace(path)
+	if raw == "" {
+		return JSONPath{}, fmt.Errorf("json path %q must not be empty", path)
+	}
+	if len(raw) > maxJSONPathLength {
+		return JSONPath{}, fmt.Errorf("json path %q must not exceed %d characters", path, maxJSONPathLength)
+	}
+
+	rest := raw
+	if strings.HasPrefix(rest, "$") {
+		rest = rest[1:]
+	} else if !strings.HasPrefix(rest, "[") {
+		rest = "." + rest
+	}
+
+	var segments []jsonPathSegment
+	for rest != "" {
+		var (
+			segment jsonPathSegment
+			err     error
+		)
+		switch rest[0] {
+		case '.':
+			segment, rest, err = parseJSONPathKey(rest[1:])
+		case '[':
+			segment, rest, err = parseJSONPathBracket(rest[1:])
+		default:
+			err = fmt.Errorf("unexpected %q", rest[0])
+		}
+		if err != nil {
+			return JSONPath{}, fmt.Errorf("json path %q is invalid: %w", path, err)
+		}
+		segments = append(segments, segment)
+	}
+	if len(segments) == 0 {
+		return JSONPath{}, fmt.Errorf("json path %q must address a field", path)
+	}
+
+	return JSONPath{raw: raw, segments: segments}, nil
+}
+
+func parseJSONPathKey(rest string) (jsonPathSegment, string, error) {
+	end := strings.IndexAny(rest, ".[")
+	if end < 0 {
+		end = len(rest)
+	}
+	key := rest[:end]
+	if key == "" {
+		return jsonPathSegment{}, "", errors.New("empty key")
+	}
+	if key == "*" {
+		return jsonPathSegment{wildcard: true}, rest[end:], nil
+	}
+
+	return jsonPathSegment{key: key}, rest[end:], nil
+}
+
+func parseJSONPathBracket(rest string) (jsonPathSegment, string, error) {
+	if rest != "" && (rest[0] == '\'' || rest[0] == '"') {
+		quote := rest[0]
+		end := strings.IndexByte(rest[1:], quote)
+		if end < 0 || !strings.HasPrefix(rest[end+2:], "]") {
+			return jsonPathSegment{}, "", errors.New("unterminated quoted key")
+		}
+		return jsonPathSegment{key: rest[1 : end+1]}, rest[end+3:], nil
+	}
+
+	end := strings.IndexByte(rest, ']')
+	if end < 0 {
+		return jsonPathSegment{}, "", errors.New("missing ]")
+	}
+	inner := strings.TrimSpace(rest[:end])
+	if inner == "*" {
+		return jsonPathSegment{wildcard: true}, rest[end+1:], nil
+	}
+	index, err := strconv.Atoi(inner)
+	if err != nil || index < 0 {
+		return jsonPathSegment{}, "", fmt.Errorf("array index %q is not a non-negative number", inner)
+	}
+
+	return jsonPathSegment{index: index, isIndex: true}, rest[end+1:], nil
+}
+
+// JSONPathStep is an object key or array index of a path without wildcards.
+type JSONPathStep struct {
+	Key     string
+	Index   int
+	IsIndex bool
+}
+
+// Steps returns the keys and indexes the path walks through. It reports false
+// for paths with wildcards, which can select more than one value.
+func (p JSONPath) Steps() ([]JSONPathStep, bool) {
+	steps := make([]JSONPathStep, 0, len(p.segments))
+	for _, segment := range p.segments {
+		if segment.wildcard {
+			return nil, false
+		}
+		steps = append(steps, JSONPathStep{Key: segment.key, Index: segment.index, IsIndex: segment.isIndex})
+	}
+
+	return steps, true
+}
+
+// String returns the path as configured.
+func (p JSONPath) String() string {
+	return p.raw
+}
+
+// matchesKey reports whether the segment selects the object member.
+func (s jsonPathSegment) matchesKey(key string) bool {
+	return s.wildcard || (!s.isIndex && s.key == key)
+}
+
+// matchesIndex reports whether the segment selects the array element.
+func (s jsonPathSegment) matchesIndex(index int) bool {
+	return s.wildcard || (s.isIndex && s.index == index)
+}
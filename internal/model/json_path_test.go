@@ -0,0 +1,33 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	for _, path := range []string{"$.card.number", "card.number", "items[0].id", "items[*].id", "$.*", "$['key.with.dots']", `[0]["id"]`} {
+		parsed, err := model.ParseJSONPath(" " + path + " ")
+		require.NoError(t, err, path)
+		assert.Equal(t, path, parsed.String())
+	}
+}
+
+func TestParseJSONPathRejectsInvalidPaths(t *testing.T) {
+	invalidPaths := map[string]string{
+		"":          `json path "" must not be empty`,
+		"$":         `json path "$" must address a field`,
+		"$..card":   `json path "$..card" is invalid: empty key`,
+		"items[-1]": `json path "items[-1]" is invalid: array index "-1" is not a non-negative number`,
+		"items[0":   `json path "items[0" is invalid: missing ]`,
+		"$['card]":  `json path "$['card]" is invalid: unterminated quoted key`,
+	}
+
+	for path, expected := range invalidPaths {
+		_, err := model.ParseJSONPath(path)
+		assert.EqualError(t, err, expected, path)
+	}
+}
@@ -0,0 +1,362 @@
+package model
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// JWTLeeway is how far expiry and not-before claims may be off from the
+// receiver clock.
+const JWTLeeway = time.Minute
+
+const maxJWKSLength = 64 * 1024
+
+// JWTValidation requires a JWT in the Authorization header as a bearer token.
+// Tokens are signed with the webhook's JWT secret (HS256) or with one of the
+// keys of a static JWKS document. Configured issuer and audience must match
+// the token claims, and the token must carry an unexpired exp claim.
+type JWTValidation struct {
+	Issuer   string `json:"issuer,omitempty"`
+	Audience string `json:"audience,omitempty"`
+	// JWKS is a JSON Web Key Set with RSA (RS256, PS256), P-256 (ES256) or
+	// Ed25519 (EdDSA) public keys.
+	JWKS json.RawMessage `json:"jwks,omitempty"`
+}
+
+type jwtKey struct {
+	id        string
+	algorithm string
+	key       crypto.PublicKey
+}
+
+// NewJWTValidation normalizes configured JWT validation and returns nil when
+// nothing is configured.
+func NewJWTValidation(validation *JWTValidation) *JWTValidation {
+	if validation == nil {
+		return nil
+	}
+
+	normalized := &JWTValidation{
+		Issuer:   strings.TrimSpace(validation.Issuer),
+		Audience: strings.TrimSpace(validation.Audience),
+		JWKS:     json.RawMessage(bytes.TrimSpace(validation.JWKS)),
+	}
+	if string(normalized.JWKS) == "null" {
+		normalized.JWKS = nil
+	}
+	if normalized.Issuer == "" && normalized.Audience == "" && len(normalized.JWKS) == 0 {
+		return nil
+	}
+
+	return normalized
+}
+
+// Validate checks that the JWKS document, when set, holds at least one usable key.
+func (v *JWTValidation) Validate() error {
+	if len(v.JWKS) == 0 {
+		return nil
+	}
+	if len(v.JWKS) > maxJWKSLength {
+		return fmt.Errorf("jwks must not exceed %d bytes", maxJWKSLength)
+	}
+	_, err := v.keys()
+	return err
+}
+
+// Description summarizes the validation, e.g. "JWT bearer token (HS256, issuer https://issuer.example)".
+func (v *JWTValidation) Description(hasSecret bool) string {
+	details := []string{"JWKS"}
+	if hasSecret {
+		details = []string{"HS256"}
+	}
+	if v.Issuer != "" {
+		details = append(details, "issuer "+v.Issuer)
+	}
+	if v.Audience != "" {
+		details = append(details, "audience "+v.Audience)
+	}
+
+	return "JWT bearer token (" + strings.Join(details, ", ") + ")"
+}
+
+// failure validates the bearer token at the given time and returns a
+// human-readable failure that never contains the token.
+func (v *JWTValidation) failure(r *http.Request, secret string, now time.Time) string {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(r.Header.Get("Authorization")), " ")
+	token = strings.TrimSpace(token)
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "Missing bearer token in Authorization header"
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "Bearer token is not a valid JWT"
+	}
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return "Bearer token is not a valid JWT"
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "Bearer token is not a valid JWT"
+	}
+
+	signedContent := []byte(parts[0] + "." + parts[1])
+	if failure := v.signatureFailure(header.Algorithm, header.KeyID, signedContent, signature, secret); failure != "" {
+		return failure
+	}
+
+	var claims struct {
+		Issuer    string          `json:"iss"`
+		Audience  json.RawMessage `json:"aud"`
+		ExpiresAt *json.Number    `json:"exp"`
+		NotBefore *json.Number    `json:"nbf"`
+	}
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return "JWT claims could not be decoded"
+	}
+
+	if claims.ExpiresAt == nil {
+		return "JWT has no exp claim"
+	}
+	expiresAt, err := jwtNumericDate(*claims.ExpiresAt)
+	if err != nil {
+		return "JWT exp claim is not a number"
+	}
+	if now.After(expiresAt.Add(JWTLeeway)) {
+		return fmt.Sprintf("JWT expired at %s", expiresAt.UTC().Format(time.RFC3339))
+	}
+	if claims.NotBefore != nil {
+		notBefore, err := jwtNumericDate(*claims.NotBefore)
+		if err != nil {
+			return "JWT nbf claim is not a number"
+		}
+		if now.Add(JWTLeeway).Before(notBefore) {
+			return fmt.Sprintf("JWT is not valid before %s", notBefore.UTC().Format(time.RFC3339))
+		}
+	}
+
+	if v.Issuer != "" && claims.Issuer != v.Issuer {
+		return fmt.Sprintf("JWT issuer %q did not match %q", claims.Issuer, v.Issuer)
+	}
+	if v.Audience != "" && !jwtAudienceContains(claims.Audience, v.Audience) {
+		return fmt.Sprintf("JWT audience did not include %q", v.Audience)
+	}
+
+	return ""
+}
+
+func (v *JWTValidation) signatureFailure(algorithm string, keyID string, content []byte, signature []byte, secret string) string {
+	if secret != "" {
+		if algorithm != "HS256" {
+			return fmt.Sprintf("JWT algorithm %q is not accepted; expected HS256", algorithm)
+		}
+		if !hmac.Equal(signature, macSum(sha256.New, secret, content)) {
+			return "JWT signature did not match"
+		}
+		return ""
+	}
+
+	keys, err := v.keys()
+	if err != nil {
+		return err.Error()
+	}
+
+	candidates := 0
+	for _, key := range keys {
+		if keyID != "" && key.id != "" && key.id != keyID {
+			continue
+		}
+		if !jwtAlgorithmMatches(algorithm, key) {
+			continue
+		}
+		candidates++
+		if verifyJWTSignature(algorithm, key.key, content, signature) {
+			return ""
+		}
+	}
+	if candidates == 0 {
+		if keyID != "" {
+			return fmt.Sprintf("No JWKS key with kid %q accepts algorithm %q", keyID, algorithm)
+		}
+		return fmt.Sprintf("No JWKS key accepts algorithm %q", algorithm)
+	}
+
+	return "JWT signature did not match"
+}
+
+// keys parses the JWKS document. Keys of unsupported types are skipped, so
+// provider documents that also list encryption keys can be uploaded as is.
+func (v *JWTValidation) keys() ([]jwtKey, error) {
+	var document struct {
+		Keys []struct {
+			KeyType   string `json:"kty"`
+			KeyID     string `json:"kid"`
+			Algorithm string `json:"alg"`
+			Use       string `json:"use"`
+			Curve     string `json:"crv"`
+			N         string `json:"n"`
+			E         string `json:"e"`
+			X         string `json:"x"`
+			Y         string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(v.JWKS, &document); err != nil {
+		return nil, errors.New("jwks must be a JSON Web Key Set document")
+	}
+
+	var keys []jwtKey
+	for index, jwk := range document.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		var key crypto.PublicKey
+		var err error
+		switch {
+		case jwk.KeyType == "RSA":
+			key, err = rsaJWK(jwk.N, jwk.E)
+		case jwk.KeyType == "EC" && jwk.Curve == "P-256":
+			key, err = ecdsaJWK(jwk.X, jwk.Y)
+		case jwk.KeyType == "OKP" && jwk.Curve == "Ed25519":
+			var x []byte
+			x, err = base64.RawURLEncoding.DecodeString(jwk.X)
+			if err == nil && len(x) != ed25519.PublicKeySize {
+				err = errors.New("wrong size")
+			}
+			key = ed25519.PublicKey(x)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("jwks key %d is not a valid %s key", index, jwk.KeyType)
+		}
+		keys = append(keys, jwtKey{id: jwk.KeyID, algorithm: jwk.Algorithm, key: key})
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("jwks must contain at least one RSA, P-256 or Ed25519 signing key")
+	}
+
+	return keys, nil
+}
+
+func rsaJWK(modulus string, exponent string) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(modulus)
+	if err != nil || len(n) == 0 {
+		return nil, errors.New("invalid modulus")
+	}
+	e, err := base64.RawURLEncoding.DecodeString(exponent)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, errors.New("invalid exponent")
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+}
+
+func ecdsaJWK(x string, y string) (*ecdsa.PublicKey, error) {
+	xBytes, err := base64.RawURLEncoding.DecodeString(x)
+	if err != nil {
+		return nil, err
+	}
+	yBytes, err := base64.RawURLEncoding.DecodeString(y)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(xBytes), Y: new(big.Int).SetBytes(yBytes)}
+	if _, err := key.ECDH(); err != nil {
+		return nil, errors.New("point is not on the curve")
+	}
+
+	return key, nil
+}
+
+func jwtAlgorithmMatches(algorithm string, key jwtKey) bool {
+	if key.algorithm != "" && key.algorithm != algorithm {
+		return false
+	}
+
+	switch key.key.(type) {
+	case *rsa.PublicKey:
+		return algorithm == "RS256" || algorithm == "PS256"
+	case *ecdsa.PublicKey:
+		return algorithm == "ES256"
+	case ed25519.PublicKey:
+		return algorithm == "EdDSA"
+	}
+
+	return false
+}
+
+func verifyJWTSignature(algorithm string, key crypto.PublicKey, content []byte, signature []byte) bool {
+	switch algorithm {
+	case "RS256":
+		return verifyPublicKeySignature(SignatureRSASHA256, key, content, signature)
+	case "PS256":
+		return verifyPublicKeySignature(SignatureRSAPSSSHA256, key, content, signature)
+	case "ES256":
+		// JWS uses the raw r||s form only.
+		return len(signature) == 64 && verifyPublicKeySignature(SignatureECDSASHA256, key, content, signature)
+	case "EdDSA":
+		return verifyPublicKeySignature(SignatureEd25519, key, content, signature)
+	}
+
+	return false
+}
+
+func decodeJWTSegment(segment string, target interface{}) error {
+	decoded, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(decoded))
+	decoder.UseNumber()
+	return decoder.Decode(target)
+}
+
+func jwtNumericDate(value json.Number) (time.Time, error) {
+	seconds, err := value.Float64()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(int64(seconds), 0), nil
+}
+
+// jwtAudienceContains accepts the aud claim as a single string or an array.
+func jwtAudienceContains(claim json.RawMessage, audience string) bool {
+	var single string
+	if err := json.Unmarshal(claim, &single); err == nil {
+		return single == audience
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(claim, &multiple); err != nil {
+		return false
+	}
+	for _, candidate := range multiple {
+		if candidate == audience {
+			return true
+		}
+	}
+
+	return false
+}
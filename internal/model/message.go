@@ -1,9 +1,11 @@
 package model
 
 import (
+	"encoding/base64"
 	"net/http"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // MessageOutcome filters captured messages by delivery result.
@@ -16,18 +18,55 @@ const (
 	MessageOutcomeAccepted MessageOutcome = "accepted"
 	// MessageOutcomeRejected returns only rejected deliveries.
 	MessageOutcomeRejected MessageOutcome = "rejected"
+	// MessageOutcomeFailed returns only deliveries that could not be forwarded upstream.
+	MessageOutcomeFailed MessageOutcome = "failed"
+)
+
+// PayloadEncoding describes how a captured body is represented in Message.Payload.
+type PayloadEncoding string
+
+const (
+	// PayloadEncodingUTF8 marks payloads that are valid UTF-8 text.
+	PayloadEncodingUTF8 PayloadEncoding = "utf8"
+	// PayloadEncodingBase64 marks binary payloads encoded as standard base64.
+	PayloadEncodingBase64 PayloadEncoding = "base64"
 )
 
 // Message represents a webhook message
 type Message struct {
-	Method       string              `json:"method"`
-	Path         string              `json:"path"`
-	Query        string              `json:"query,omitempty"`
-	Payload      string              `json:"payload"`
-	Headers      map[string][]string `json:"headers"`
-	Time         time.Time           `json:"time"`
-	StatusCode   int                 `json:"statusCode"`
-	ErrorMessage string              `json:"error,omitempty"`
+	ID              int64               `json:"id"`
+	Method          string              `json:"method"`
+	Path            string              `json:"path"`
+	Query           string              `json:"query,omitempty"`
+	Payload         string              `json:"payload"`
+	PayloadEncoding PayloadEncoding     `json:"payloadEncoding"`
+	Headers         map[string][]string `json:"headers"`
+	Time            time.Time           `json:"time"`
+	StatusCode      int                 `json:"statusCode"`
+	ErrorMessage    string              `json:"error,omitempty"`
+	// Duplicate marks accepted deliveries whose delivery ID was seen before.
+	Duplicate bool `json:"duplicate,omitempty"`
+	// RemoteIP is the sender address after client IP resolution.
+	RemoteIP          string             `json:"remoteIp,omitempty"`
+	Protocol          string             `json:"protocol,omitempty"`
+	Host              string             `json:"host,omitempty"`
+	ContentLength     int64              `json:"contentLength"`
+	TLS               *TLSDetails        `json:"tls,omitempty"`
+	ClientCertificate *ClientCertificate `json:"clientCertificate,omitempty"`
+	// ProcessingMicros is the server-side handling time in microseconds.
+	ProcessingMicros int64          `json:"processingUs"`
+	Forward          *ForwardResult `json:"forward,omitempty"`
+	ParsedBody       *ParsedBody    `json:"parsedBody,omitempty"`
+}
+
+// ForwardResult records how a forwarded delivery was answered by the upstream.
+type ForwardResult struct {
+	TargetURL     string              `json:"targetUrl"`
+	StatusCode    int                 `json:"statusCode,omitempty"`
+	Headers       map[string][]string `json:"headers,omitempty"`
+	Body          string              `json:"body,omitempty"`
+	LatencyMillis int64               `json:"latencyMs"`
+	ErrorMessage  string              `json:"error,omitempty"`
 }
 
 // MessagePage represents a single page of captured webhook messages.
@@ -39,6 +78,9 @@ type MessagePage struct {
 	TotalPages      int        `json:"totalPages"`
 	HasNextPage     bool       `json:"hasNextPage"`
 	HasPreviousPage bool       `json:"hasPreviousPage"`
+	// SearchTruncated reports that a search only covered the newest captured
+	// messages, because matching it required loading them one by one.
+	SearchTruncated bool `json:"searchTruncated,omitempty"`
 }
 
 // ParseMessageOutcome validates and normalizes the requested outcome filter.
@@ -50,22 +92,70 @@ func ParseMessageOutcome(value string) (MessageOutcome, bool) {
 		return MessageOutcomeAccepted, true
 	case MessageOutcomeRejected:
 		return MessageOutcomeRejected, true
+	case MessageOutcomeFailed:
+		return MessageOutcomeFailed, true
 	default:
 		return "", false
 	}
 }
 
-// NewMessage creates a new message with the current timestamp.
+// Matches reports whether the message belongs to the outcome filter.
+func (o MessageOutcome) Matches(message *Message) bool {
+	switch o {
+	case MessageOutcomeAccepted:
+		return !message.ForwardFailed() && message.StatusCode < http.StatusBadRequest
+	case MessageOutcomeRejected:
+		return !message.ForwardFailed() && message.StatusCode >= http.StatusBadRequest
+	case MessageOutcomeFailed:
+		return message.ForwardFailed()
+	default:
+		return true
+	}
+}
+
+// NewMessage creates a new message with the current timestamp. The payload holds
+// the raw request body and may contain arbitrary bytes.
 func NewMessage(method string, path string, query string, payload string, headers map[string][]string) *Message {
-	return &Message{
+	message := &Message{
 		Method:     method,
 		Path:       path,
 		Query:      query,
-		Payload:    payload,
 		Headers:    headers,
 		Time:       time.Now().UTC(),
 		StatusCode: http.StatusOK,
 	}
+	message.SetBody([]byte(payload))
+
+	return message
+}
+
+// SetBody stores the raw body as text when it is valid UTF-8 and as base64 otherwise.
+func (m *Message) SetBody(body []byte) {
+	if utf8.Valid(body) {
+		m.Payload = string(body)
+		m.PayloadEncoding = PayloadEncodingUTF8
+		return
+	}
+
+	m.Payload = base64.StdEncoding.EncodeToString(body)
+	m.PayloadEncoding = PayloadEncodingBase64
+}
+
+// Body returns the raw captured body.
+func (m *Message) Body() []byte {
+	if m.Binary() {
+		body, err := base64.StdEncoding.DecodeString(m.Payload)
+		if err == nil {
+			return body
+		}
+	}
+
+	return []byte(m.Payload)
+}
+
+// Binary indicates whether the captured body is not valid UTF-8 text.
+func (m *Message) Binary() bool {
+	return m.PayloadEncoding == PayloadEncodingBase64
 }
 
 // MarkRejected stores the receiver response for a rejected delivery attempt.
@@ -78,3 +168,26 @@ func (m *Message) MarkRejected(statusCode int, errorMessage string) {
 func (m *Message) Rejected() bool {
 	return m.StatusCode >= http.StatusBadRequest || m.ErrorMessage != ""
 }
+
+// MarkForwarded stores the upstream result of a forwarded delivery. A failed
+// forward is answered with 502 Bad Gateway; otherwise the sender receives the
+// upstream status code.
+func (m *Message) MarkForwarded(result *ForwardResult) {
+	m.Forward = result
+	if result.Failed() {
+		m.StatusCode = http.StatusBadGateway
+		m.ErrorMessage = "Forwarding failed: " + result.ErrorMessage
+		return
+	}
+	m.StatusCode = result.StatusCode
+}
+
+// ForwardFailed indicates whether the delivery could not be forwarded upstream.
+func (m *Message) ForwardFailed() bool {
+	return m.Forward != nil && m.Forward.Failed()
+}
+
+// Failed indicates whether the upstream could not be reached or did not answer.
+func (f *ForwardResult) Failed() bool {
+	return f.ErrorMessage != ""
+}
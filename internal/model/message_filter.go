@@ -0,0 +1,173 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const maxMessageFilterTextLength = 256
+
+// MessageFilter narrows the captured messages of a webhook. Empty fields do
+// not filter; every set field must match.
+type MessageFilter struct {
+	Outcome MessageOutcome
+	// Method matches the request method, ignoring case.
+	Method string
+	// PathPrefix matches the beginning of the request path.
+	PathPrefix string
+	StatusCode int
+	// HeaderName requires the header to be present. HeaderValue additionally
+	// requires one of its values to contain the text, ignoring case.
+	HeaderName  string
+	HeaderValue string
+	// Since and Until bound the receive time; both bounds are inclusive.
+	Since time.Time
+	Until time.Time
+	// Text matches payloads that contain every word of the text, either as a
+	// whole word or as the beginning of one, ignoring case.
+	Text string
+	// JSON matches JSON payloads; other payloads never match.
+	JSON *JSONFilter
+}
+
+// NewMessageFilter normalizes the filter fields.
+func NewMessageFilter(filter MessageFilter) MessageFilter {
+	normalized := MessageFilter{
+		Outcome:     filter.Outcome,
+		Method:      strings.ToUpper(strings.TrimSpace(filter.Method)),
+		PathPrefix:  strings.TrimSpace(filter.PathPrefix),
+		StatusCode:  filter.StatusCode,
+		HeaderName:  strings.TrimSpace(filter.HeaderName),
+		HeaderValue: strings.TrimSpace(filter.HeaderValue),
+		Since:       filter.Since.UTC(),
+		Until:       filter.Until.UTC(),
+		Text:        strings.TrimSpace(filter.Text),
+		JSON:        filter.JSON,
+	}
+	if outcome, ok := ParseMessageOutcome(string(filter.Outcome)); ok {
+		normalized.Outcome = outcome
+	}
+	if normalized.HeaderName != "" {
+		normalized.HeaderName = http.CanonicalHeaderKey(normalized.HeaderName)
+	}
+
+	return normalized
+}
+
+// Validate checks that the filter fields can be matched.
+func (f MessageFilter) Validate() error {
+	if _, ok := ParseMessageOutcome(string(f.Outcome)); !ok {
+		return errors.New("outcome must be one of all, accepted, rejected, failed")
+	}
+	if f.Method != "" && !validHeaderName(f.Method) {
+		return fmt.Errorf("method %q is not a valid request method", f.Method)
+	}
+	if f.StatusCode != 0 && (f.StatusCode < 100 || f.StatusCode > 599) {
+		return errors.New("status must be a status code between 100 and 599")
+	}
+	if f.HeaderName != "" && !validHeaderName(f.HeaderName) {
+		return fmt.Errorf("header %q is not a valid header name", f.HeaderName)
+	}
+	if f.HeaderValue != "" && f.HeaderName == "" {
+		return errors.New("headerValue requires header")
+	}
+	if !f.Since.IsZero() && !f.Until.IsZero() && f.Since.After(f.Until) {
+		return errors.New("since must not be after until")
+	}
+	for _, value := range []string{f.PathPrefix, f.HeaderValue, f.Text} {
+		if len(value) > maxMessageFilterTextLength {
+			return fmt.Errorf("search values must not exceed %d characters", maxMessageFilterTextLength)
+		}
+	}
+
+	return nil
+}
+
+// Searching reports whether the filter narrows messages beyond their outcome.
+func (f MessageFilter) Searching() bool {
+	return f.Method != "" || f.PathPrefix != "" || f.StatusCode != 0 || f.HeaderName != "" ||
+		!f.Since.IsZero() || !f.Until.IsZero() || f.Text != "" || f.JSON != nil
+}
+
+// Matches reports whether the message passes every filter field.
+func (f MessageFilter) Matches(message *Message) bool {
+	switch {
+	case !f.Outcome.Matches(message):
+		return false
+	case f.Method != "" && !strings.EqualFold(message.Method, f.Method):
+		return false
+	case !strings.HasPrefix(message.Path, f.PathPrefix):
+		return false
+	case f.StatusCode != 0 && message.StatusCode != f.StatusCode:
+		return false
+	case !f.Since.IsZero() && message.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && message.Time.After(f.Until):
+		return false
+	case f.HeaderName != "" && !f.matchesHeaders(message.Headers):
+		return false
+	case f.Text != "" && !f.matchesText(message):
+		return false
+	case f.JSON != nil && (message.Binary() || !f.JSON.Matches(message.Body())):
+		return false
+	default:
+		return true
+	}
+}
+
+func (f MessageFilter) matchesHeaders(headers map[string][]string) bool {
+	for name, values := range headers {
+		if !strings.EqualFold(name, f.HeaderName) {
+			continue
+		}
+		if f.HeaderValue == "" {
+			return true
+		}
+		for _, value := range values {
+			if strings.Contains(strings.ToLower(value), strings.ToLower(f.HeaderValue)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (f MessageFilter) matchesText(message *Message) bool {
+	if message.Binary() {
+		return false
+	}
+
+	words := SearchTerms(message.Payload)
+	for _, term := range SearchTerms(f.Text) {
+		found := false
+		for _, word := range words {
+			if strings.HasPrefix(word, term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// SearchTerms splits text into lower-cased words of letters and digits, the
+// way payloads are indexed for full-text search.
+func SearchTerms(text string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(text, func(char rune) bool {
+		return !unicode.IsLetter(char) && !unicode.IsNumber(char)
+	}) {
+		terms = append(terms, strings.ToLower(word))
+	}
+
+	return terms
+}
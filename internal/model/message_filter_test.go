@@ -0,0 +1,64 @@
+package model_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMessageFilterNormalizesFields(t *testing.T) {
+	filter := model.NewMessageFilter(model.MessageFilter{
+		Outcome:    " Rejected ",
+		Method:     " post ",
+		HeaderName: "x-github-event",
+		Text:       "  invoice ",
+	})
+
+	assert.Equal(t, model.MessageOutcomeRejected, filter.Outcome)
+	assert.Equal(t, http.MethodPost, filter.Method)
+	assert.Equal(t, "X-Github-Event", filter.HeaderName)
+	assert.Equal(t, "invoice", filter.Text)
+	assert.True(t, filter.Searching())
+	assert.False(t, model.NewMessageFilter(model.MessageFilter{Outcome: model.MessageOutcomeFailed}).Searching())
+}
+
+func TestMessageFilterValidate(t *testing.T) {
+	now := time.Now()
+	assert.NoError(t, model.MessageFilter{Outcome: model.MessageOutcomeAll, StatusCode: 204, Since: now.Add(-time.Hour), Until: now}.Validate())
+	assert.EqualError(t, model.MessageFilter{StatusCode: 42}.Validate(), "status must be a status code between 100 and 599")
+	assert.EqualError(t, model.MessageFilter{HeaderValue: "push"}.Validate(), "headerValue requires header")
+	assert.EqualError(t, model.MessageFilter{HeaderName: "X Event"}.Validate(), `header "X Event" is not a valid header name`)
+	assert.EqualError(t, model.MessageFilter{Method: "GET /"}.Validate(), `method "GET /" is not a valid request method`)
+	assert.EqualError(t, model.MessageFilter{Since: now, Until: now.Add(-time.Minute)}.Validate(), "since must not be after until")
+	assert.EqualError(t, model.MessageFilter{Outcome: "pending"}.Validate(), "outcome must be one of all, accepted, rejected, failed")
+}
+
+func TestMessageFilterMatches(t *testing.T) {
+	message := model.NewMessage(http.MethodPost, "/hooks/id/stripe", "", `{"type":"invoice.paid","note":"Café"}`, map[string][]string{
+		"Stripe-Signature": {"t=1,v1=abc"},
+	})
+
+	assert.True(t, model.MessageFilter{}.Matches(message))
+	assert.True(t, model.MessageFilter{Method: "post", PathPrefix: "/hooks/id/", StatusCode: http.StatusOK}.Matches(message))
+	assert.False(t, model.MessageFilter{PathPrefix: "/hooks/other"}.Matches(message))
+	assert.False(t, model.MessageFilter{StatusCode: http.StatusUnauthorized}.Matches(message))
+	assert.True(t, model.MessageFilter{HeaderName: "stripe-signature", HeaderValue: "V1=ABC"}.Matches(message))
+	assert.False(t, model.MessageFilter{HeaderName: "Stripe-Signature", HeaderValue: "v1=def"}.Matches(message))
+	assert.True(t, model.MessageFilter{Since: message.Time, Until: message.Time}.Matches(message))
+	assert.False(t, model.MessageFilter{Since: message.Time.Add(time.Second)}.Matches(message))
+	assert.True(t, model.MessageFilter{Text: "invoice PAID"}.Matches(message))
+	assert.True(t, model.MessageFilter{Text: "inv caf"}.Matches(message))
+	assert.False(t, model.MessageFilter{Text: "voice"}.Matches(message))
+	assert.False(t, model.MessageFilter{Text: "invoice refunded"}.Matches(message))
+
+	binary := model.NewMessage(http.MethodPost, "/hooks/id", "", string([]byte{0xff, 'i', 'n', 'v'}), nil)
+	assert.False(t, model.MessageFilter{Text: "inv"}.Matches(binary))
+}
+
+func TestSearchTerms(t *testing.T) {
+	assert.Equal(t, []string{"invoice", "paid", "42", "x", "café"}, model.SearchTerms(`{"Invoice.PAID": 42, "x": "Café"}`))
+	assert.Empty(t, model.SearchTerms(`{"": []}`))
+}
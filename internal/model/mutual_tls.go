@@ -0,0 +1,211 @@
+package model
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const maxMutualTLSCALength = 64 * 1024
+
+// MutualTLS requires deliveries to present a TLS client certificate. The
+// receiver must be served over TLS and ask clients for certificates. When CA
+// certificates are pinned, the client certificate must chain to one of them;
+// when fingerprints are pinned, it must be one of the pinned certificates.
+type MutualTLS struct {
+	Required bool `json:"required"`
+	// CACertificates is a PEM bundle of CA certificates that may issue client
+	// certificates.
+	CACertificates string `json:"caCertificates,omitempty"`
+	// Fingerprints are hex-encoded SHA-256 fingerprints of accepted client
+	// certificates.
+	Fingerprints []string `json:"fingerprints,omitempty"`
+}
+
+// ClientCertificate describes the TLS client certificate presented with a
+// delivery.
+type ClientCertificate struct {
+	Subject     string `json:"subject"`
+	Issuer      string `json:"issuer"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// NewMutualTLS normalizes a configured client certificate requirement and
+// returns nil when nothing is configured. Pinning a CA or fingerprint implies
+// that a certificate is required.
+func NewMutualTLS(mutualTLS *MutualTLS) *MutualTLS {
+	if mutualTLS == nil {
+		return nil
+	}
+
+	normalized := &MutualTLS{
+		Required:       mutualTLS.Required,
+		CACertificates: strings.TrimSpace(mutualTLS.CACertificates),
+	}
+	for _, fingerprint := range mutualTLS.Fingerprints {
+		if fingerprint = normalizeCertificateFingerprint(fingerprint); fingerprint != "" {
+			normalized.Fingerprints = append(normalized.Fingerprints, fingerprint)
+		}
+	}
+	if normalized.CACertificates != "" || len(normalized.Fingerprints) > 0 {
+		normalized.Required = true
+	}
+	if !normalized.Required {
+		return nil
+	}
+
+	return normalized
+}
+
+// Validate checks the pinned CA bundle and fingerprints.
+func (m *MutualTLS) Validate() error {
+	if m.CACertificates != "" {
+		if len(m.CACertificates) > maxMutualTLSCALength {
+			return fmt.Errorf("mutual tls ca certificates must not exceed %d bytes", maxMutualTLSCALength)
+		}
+		if _, err := m.caPool(); err != nil {
+			return err
+		}
+	}
+	for _, fingerprint := range m.Fingerprints {
+		if decoded, err := hex.DecodeString(fingerprint); err != nil || len(decoded) != sha256.Size {
+			return fmt.Errorf("mutual tls fingerprint %q is not a hex-encoded SHA-256 fingerprint", fingerprint)
+		}
+	}
+
+	return nil
+}
+
+// Description summarizes the requirement, e.g. "Mutual TLS (pinned CA, 2 fingerprints)".
+func (m *MutualTLS) Description() string {
+	var pins []string
+	if m.CACertificates != "" {
+		pins = append(pins, "pinned CA")
+	}
+	switch len(m.Fingerprints) {
+	case 0:
+	case 1:
+		pins = append(pins, "1 fingerprint")
+	default:
+		pins = append(pins, fmt.Sprintf("%d fingerprints", len(m.Fingerprints)))
+	}
+	if len(pins) == 0 {
+		return "Mutual TLS (any client certificate)"
+	}
+
+	return "Mutual TLS (" + strings.Join(pins, ", ") + ")"
+}
+
+// failure checks the client certificate of the request at the given time.
+func (m *MutualTLS) failure(r *http.Request, now time.Time) string {
+	if r.TLS == nil {
+		return "Mutual TLS requires the delivery to be made over HTTPS"
+	}
+	if len(r.TLS.PeerCertificates) == 0 {
+		return "Missing TLS client certificate"
+	}
+
+	certificate := r.TLS.PeerCertificates[0]
+	if m.CACertificates != "" {
+		roots, err := m.caPool()
+		if err != nil {
+			return err.Error()
+		}
+		intermediates := x509.NewCertPool()
+		for _, intermediate := range r.TLS.PeerCertificates[1:] {
+			intermediates.AddCert(intermediate)
+		}
+		_, err = certificate.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   now,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+		if err != nil {
+			return fmt.Sprintf("Client certificate %q was not issued by a pinned CA", certificate.Subject.String())
+		}
+	}
+
+	if len(m.Fingerprints) > 0 {
+		fingerprint := CertificateFingerprint(certificate)
+		for _, pinned := range m.Fingerprints {
+			if pinned == fingerprint {
+				return ""
+			}
+		}
+		return fmt.Sprintf("Client certificate fingerprint %s is not pinned", fingerprint)
+	}
+
+	return ""
+}
+
+func (m *MutualTLS) caPool() (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	rest := []byte(m.CACertificates)
+	certificates := 0
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("mutual tls ca certificate %d is invalid", certificates+1)
+		}
+		pool.AddCert(certificate)
+		certificates++
+	}
+	if certificates == 0 {
+		return nil, errors.New("mutual tls ca certificates must contain at least one PEM certificate")
+	}
+
+	return pool, nil
+}
+
+// PresentedClientCertificate describes the client certificate of a TLS
+// connection, or returns nil when none was presented.
+func PresentedClientCertificate(state *tls.ConnectionState) *ClientCertificate {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+
+	certificate := state.PeerCertificates[0]
+	return &ClientCertificate{
+		Subject:     certificate.Subject.String(),
+		Issuer:      certificate.Issuer.String(),
+		Fingerprint: CertificateFingerprint(certificate),
+	}
+}
+
+// CertificateFingerprint returns the lowercase hex SHA-256 fingerprint of the
+// DER-encoded certificate.
+func CertificateFingerprint(certificate *x509.Certificate) string {
+	sum := sha256.Sum256(certificate.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeCertificateFingerprint accepts fingerprints as printed by common
+// tools, e.g. "sha256 Fingerprint=AB:CD:…", "SHA256:AB:CD:…" or "ab cd …".
+func normalizeCertificateFingerprint(fingerprint string) string {
+	if index := strings.LastIndex(fingerprint, "="); index >= 0 {
+		fingerprint = fingerprint[index+1:]
+	}
+	fingerprint = strings.TrimSpace(fingerprint)
+	if prefix, rest, ok := strings.Cut(fingerprint, ":"); ok && strings.EqualFold(strings.ReplaceAll(prefix, "-", ""), "sha256") {
+		fingerprint = rest
+	}
+	fingerprint = strings.NewReplacer(":", "", " ", "").Replace(fingerprint)
+
+	return strings.ToLower(fingerprint)
+}
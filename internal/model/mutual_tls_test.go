@@ -0,0 +1,137 @@
+package model_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testIssuer struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+}
+
+// newTestCertificate issues a client certificate from the issuer, or a
+// self-signed CA certificate when the issuer is nil.
+func newTestCertificate(t *testing.T, commonName string, issuer *testIssuer) *testIssuer {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	parent, signer := template, key
+	if issuer == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		template.KeyUsage = x509.KeyUsageDigitalSignature
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+		parent, signer = issuer.certificate, issuer.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testIssuer{certificate: certificate, key: key}
+}
+
+func certificatePEM(certificate *x509.Certificate) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw}))
+}
+
+func tlsRequest(certificates ...*x509.Certificate) *http.Request {
+	request, _ := http.NewRequest(http.MethodPost, "https://receiver.example/hooks/id", nil)
+	request.TLS = &tls.ConnectionState{PeerCertificates: certificates}
+
+	return request
+}
+
+func TestAuthorizationFailureWithPinnedCA(t *testing.T) {
+	ca := newTestCertificate(t, "Test CA", nil)
+	otherCA := newTestCertificate(t, "Other CA", nil)
+	client := newTestCertificate(t, "sender", ca)
+	stranger := newTestCertificate(t, "stranger", otherCA)
+
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{MutualTLS: &model.MutualTLS{CACertificates: certificatePEM(ca.certificate)}})
+	require.NoError(t, webhook.Validate())
+
+	assert.Empty(t, webhook.AuthorizationFailure(tlsRequest(client.certificate), nil))
+	assert.Equal(t, `Client certificate "CN=stranger" was not issued by a pinned CA`, webhook.AuthorizationFailure(tlsRequest(stranger.certificate), nil))
+	assert.Equal(t, "Missing TLS client certificate", webhook.AuthorizationFailure(tlsRequest(), nil))
+
+	plainRequest, _ := http.NewRequest(http.MethodPost, "/hooks/id", nil)
+	assert.Equal(t, "Mutual TLS requires the delivery to be made over HTTPS", webhook.AuthorizationFailure(plainRequest, nil))
+}
+
+func TestAuthorizationFailureWithPinnedFingerprint(t *testing.T) {
+	ca := newTestCertificate(t, "Test CA", nil)
+	client := newTestCertificate(t, "sender", ca)
+	other := newTestCertificate(t, "other", ca)
+
+	fingerprint := model.CertificateFingerprint(client.certificate)
+	var colonSeparated []string
+	for index := 0; index < len(fingerprint); index += 2 {
+		colonSeparated = append(colonSeparated, strings.ToUpper(fingerprint[index:index+2]))
+	}
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{
+		MutualTLS: &model.MutualTLS{Fingerprints: []string{"sha256 Fingerprint=" + strings.Join(colonSeparated, ":"), " "}},
+	})
+	require.NoError(t, webhook.Validate())
+	assert.Equal(t, &model.MutualTLS{Required: true, Fingerprints: []string{fingerprint}}, webhook.MutualTLS)
+
+	assert.Empty(t, webhook.AuthorizationFailure(tlsRequest(client.certificate), nil))
+	assert.Equal(t, "Client certificate fingerprint "+model.CertificateFingerprint(other.certificate)+" is not pinned", webhook.AuthorizationFailure(tlsRequest(other.certificate), nil))
+}
+
+func TestPresentedClientCertificate(t *testing.T) {
+	ca := newTestCertificate(t, "Test CA", nil)
+	client := newTestCertificate(t, "sender", ca)
+
+	assert.Nil(t, model.PresentedClientCertificate(nil))
+	assert.Nil(t, model.PresentedClientCertificate(&tls.ConnectionState{}))
+	assert.Equal(t, &model.ClientCertificate{
+		Subject:     "CN=sender",
+		Issuer:      "CN=Test CA",
+		Fingerprint: model.CertificateFingerprint(client.certificate),
+	}, model.PresentedClientCertificate(&tls.ConnectionState{PeerCertificates: []*x509.Certificate{client.certificate}}))
+}
+
+func TestNewMutualTLS(t *testing.T) {
+	assert.Nil(t, model.NewMutualTLS(nil))
+	assert.Nil(t, model.NewMutualTLS(&model.MutualTLS{Fingerprints: []string{" "}}))
+	assert.Equal(t, &model.MutualTLS{Required: true}, model.NewMutualTLS(&model.MutualTLS{Required: true}))
+	assert.Equal(t, "Mutual TLS (any client certificate)", model.NewMutualTLS(&model.MutualTLS{Required: true}).Description())
+	assert.Equal(t, "Mutual TLS (pinned CA, 2 fingerprints)", (&model.MutualTLS{CACertificates: "pem", Fingerprints: []string{"a", "b"}}).Description())
+}
+
+func TestValidateRejectsInvalidMutualTLS(t *testing.T) {
+	invalidInputs := map[string]*model.MutualTLS{
+		"mutual tls ca certificates must contain at least one PEM certificate":   {CACertificates: "not a certificate"},
+		`mutual tls fingerprint "abcd" is not a hex-encoded SHA-256 fingerprint`: {Fingerprints: []string{"AB:CD"}},
+	}
+
+	for expected, mutualTLS := range invalidInputs {
+		assert.EqualError(t, model.NewWebhookFromInput(&model.WebhookInput{MutualTLS: mutualTLS}).Validate(), expected)
+	}
+}
@@ -0,0 +1,247 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxMultipartValueBytes limits how much of a non-file multipart part is kept as value.
+const maxMultipartValueBytes = 64 << 10
+
+// ParsedBodyKind names the structure a body was decoded as.
+type ParsedBodyKind string
+
+const (
+	// ParsedBodyJSON is a JSON document.
+	ParsedBodyJSON ParsedBodyKind = "json"
+	// ParsedBodyForm is an application/x-www-form-urlencoded body.
+	ParsedBodyForm ParsedBodyKind = "form"
+	// ParsedBodyMultipart is a multipart body such as multipart/form-data.
+	ParsedBodyMultipart ParsedBodyKind = "multipart"
+	// ParsedBodyXML is an XML document.
+	ParsedBodyXML ParsedBodyKind = "xml"
+)
+
+// ParsedBody is the structured view of a captured body derived from its Content-Type.
+// Error is set when the body does not match its declared type.
+type ParsedBody struct {
+	Kind   ParsedBodyKind  `json:"kind"`
+	JSON   interface{}     `json:"json,omitempty"`
+	Fields []FormField     `json:"fields,omitempty"`
+	Parts  []MultipartPart `json:"parts,omitempty"`
+	XML    string          `json:"xml,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// FormField is a single decoded form-urlencoded pair in body order.
+type FormField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// MultipartPart describes one part of a multipart body. Value is only set for
+// text parts that are not file uploads.
+type MultipartPart struct {
+	Name     string              `json:"name,omitempty"`
+	Filename string              `json:"filename,omitempty"`
+	Headers  map[string][]string `json:"headers"`
+	Size     int64               `json:"size"`
+	Value    string              `json:"value,omitempty"`
+}
+
+// ParseBody decodes the body according to the Content-Type. It returns nil for
+// content types that have no structured view.
+func ParseBody(contentType string, body []byte) *ParsedBody {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return parseJSONBody(body)
+	case mediaType == "application/x-www-form-urlencoded":
+		return parseFormBody(body)
+	case strings.HasPrefix(mediaType, "multipart/"):
+		return parseMultipartBody(params["boundary"], body)
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		return parseXMLBody(body)
+	default:
+		return nil
+	}
+}
+
+// DecodeBody sets ParsedBody from the captured body and its Content-Type header.
+func (m *Message) DecodeBody() {
+	contentType := ""
+	for name, values := range m.Headers {
+		if strings.EqualFold(name, "Content-Type") && len(values) > 0 {
+			contentType = values[0]
+			break
+		}
+	}
+
+	m.ParsedBody = ParseBody(contentType, m.Body())
+}
+
+func parseJSONBody(body []byte) *ParsedBody {
+	parsed := &ParsedBody{Kind: ParsedBodyJSON}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		parsed.Error = fmt.Sprintf("invalid JSON: %s", err)
+		return parsed
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		parsed.Error = "invalid JSON: unexpected data after top-level value"
+		return parsed
+	}
+
+	parsed.JSON = value
+	return parsed
+}
+
+func parseFormBody(body []byte) *ParsedBody {
+	parsed := &ParsedBody{Kind: ParsedBodyForm, Fields: []FormField{}}
+
+	for _, pair := range strings.Split(string(body), "&") {
+		if pair == "" {
+			continue
+		}
+
+		rawName, rawValue, _ := strings.Cut(pair, "=")
+		name, err := url.QueryUnescape(rawName)
+		if err != nil {
+			parsed.Error = fmt.Sprintf("invalid form encoding: %s", err)
+			return parsed
+		}
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			parsed.Error = fmt.Sprintf("invalid form encoding: %s", err)
+			return parsed
+		}
+		parsed.Fields = append(parsed.Fields, FormField{Name: name, Value: value})
+	}
+
+	return parsed
+}
+
+func parseMultipartBody(boundary string, body []byte) *ParsedBody {
+	parsed := &ParsedBody{Kind: ParsedBodyMultipart, Parts: []MultipartPart{}}
+	if boundary == "" {
+		parsed.Error = "invalid multipart body: missing boundary"
+		return parsed
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextRawPart()
+		if errors.Is(err, io.EOF) {
+			return parsed
+		}
+		if err != nil {
+			parsed.Error = fmt.Sprintf("invalid multipart body: %s", err)
+			return parsed
+		}
+
+		var value bytes.Buffer
+		size, err := io.Copy(&value, part)
+		if err != nil {
+			parsed.Error = fmt.Sprintf("invalid multipart body: %s", err)
+			return parsed
+		}
+
+		parsedPart := MultipartPart{
+			Name:     part.FormName(),
+			Filename: part.FileName(),
+			Headers:  part.Header,
+			Size:     size,
+		}
+		if parsedPart.Filename == "" && size <= maxMultipartValueBytes && utf8.Valid(value.Bytes()) {
+			parsedPart.Value = value.String()
+		}
+		parsed.Parts = append(parsed.Parts, parsedPart)
+	}
+}
+
+// parseXMLBody re-indents an XML document. Namespace prefixes are kept as
+// written instead of being resolved.
+func parseXMLBody(body []byte) *ParsedBody {
+	parsed := &ParsedBody{Kind: ParsedBodyXML}
+
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	var indented bytes.Buffer
+	encoder := xml.NewEncoder(&indented)
+	encoder.Indent("", "  ")
+	for {
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			parsed.Error = fmt.Sprintf("invalid XML: %s", err)
+			return parsed
+		}
+
+		switch typed := token.(type) {
+		case xml.CharData:
+			if len(bytes.TrimSpace(typed)) == 0 {
+				continue
+			}
+		case xml.StartElement:
+			typed.Name = prefixedXMLName(typed.Name)
+			attributes := make([]xml.Attr, 0, len(typed.Attr))
+			for _, attribute := range typed.Attr {
+				attributes = append(attributes, xml.Attr{Name: prefixedXMLName(attribute.Name), Value: attribute.Value})
+			}
+			typed.Attr = attributes
+			token = typed
+		case xml.EndElement:
+			typed.Name = prefixedXMLName(typed.Name)
+			token = typed
+		}
+
+		if err := encoder.EncodeToken(xml.CopyToken(token)); err != nil {
+			parsed.Error = fmt.Sprintf("invalid XML: %s", err)
+			return parsed
+		}
+		if _, ok := token.(xml.ProcInst); ok {
+			// The encoder does not indent after the XML declaration.
+			if err := encoder.Flush(); err != nil {
+				parsed.Error = fmt.Sprintf("invalid XML: %s", err)
+				return parsed
+			}
+			indented.WriteByte('\n')
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		parsed.Error = fmt.Sprintf("invalid XML: %s", err)
+		return parsed
+	}
+	if indented.Len() == 0 {
+		parsed.Error = "invalid XML: empty document"
+		return parsed
+	}
+
+	parsed.XML = indented.String()
+	return parsed
+}
+
+func prefixedXMLName(name xml.Name) xml.Name {
+	if name.Space == "" {
+		return name
+	}
+
+	return xml.Name{Local: name.Space + ":" + name.Local}
+}
@@ -0,0 +1,101 @@
+package model_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBodyDecodesJSON(t *testing.T) {
+	parsed := model.ParseBody("application/vnd.github+json; charset=utf-8", []byte(`{"id":12345678901234567890,"tags":["a"]}`))
+	require.NotNil(t, parsed)
+	assert.Equal(t, model.ParsedBodyJSON, parsed.Kind)
+	assert.Empty(t, parsed.Error)
+
+	encoded, err := json.Marshal(parsed.JSON)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":12345678901234567890,"tags":["a"]}`, string(encoded))
+
+	invalid := model.ParseBody("application/json", []byte(`{"id":1} trailing`))
+	require.NotNil(t, invalid)
+	assert.Nil(t, invalid.JSON)
+	assert.Contains(t, invalid.Error, "invalid JSON")
+}
+
+func TestParseBodyDecodesFormFieldsInOrder(t *testing.T) {
+	parsed := model.ParseBody("application/x-www-form-urlencoded", []byte("b=2&a=hello+world&a=%C3%BC&empty="))
+	require.NotNil(t, parsed)
+	assert.Empty(t, parsed.Error)
+	assert.Equal(t, []model.FormField{
+		{Name: "b", Value: "2"},
+		{Name: "a", Value: "hello world"},
+		{Name: "a", Value: "ü"},
+		{Name: "empty", Value: ""},
+	}, parsed.Fields)
+
+	invalid := model.ParseBody("application/x-www-form-urlencoded", []byte("a=%zz"))
+	require.NotNil(t, invalid)
+	assert.Contains(t, invalid.Error, "invalid form encoding")
+}
+
+func TestParseBodyDecodesMultipartParts(t *testing.T) {
+	body := "--boundary\r\n" +
+		"Content-Disposition: form-data; name=\"title\"\r\n\r\n" +
+		"Hello\r\n" +
+		"--boundary\r\n" +
+		"Content-Disposition: form-data; name=\"upload\"; filename=\"image.png\"\r\n" +
+		"Content-Type: image/png\r\n\r\n" +
+		"\x89PNG\x00\x01\r\n" +
+		"--boundary--\r\n"
+
+	parsed := model.ParseBody("multipart/form-data; boundary=boundary", []byte(body))
+	require.NotNil(t, parsed)
+	assert.Empty(t, parsed.Error)
+	require.Len(t, parsed.Parts, 2)
+	assert.Equal(t, "title", parsed.Parts[0].Name)
+	assert.Equal(t, "Hello", parsed.Parts[0].Value)
+	assert.Equal(t, int64(5), parsed.Parts[0].Size)
+	assert.Equal(t, "upload", parsed.Parts[1].Name)
+	assert.Equal(t, "image.png", parsed.Parts[1].Filename)
+	assert.Equal(t, []string{"image/png"}, parsed.Parts[1].Headers["Content-Type"])
+	assert.Equal(t, int64(6), parsed.Parts[1].Size)
+	assert.Empty(t, parsed.Parts[1].Value)
+
+	missingBoundary := model.ParseBody("multipart/form-data", []byte(body))
+	require.NotNil(t, missingBoundary)
+	assert.Contains(t, missingBoundary.Error, "missing boundary")
+}
+
+func TestParseBodyIndentsXML(t *testing.T) {
+	parsed := model.ParseBody("text/xml", []byte(`<?xml version="1.0"?><soap:Envelope xmlns:soap="urn:soap"><soap:Body><id a="1">42</id></soap:Body></soap:Envelope>`))
+	require.NotNil(t, parsed)
+	assert.Empty(t, parsed.Error)
+	assert.Equal(t, `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="urn:soap">
+  <soap:Body>
+    <id a="1">42</id>
+  </soap:Body>
+</soap:Envelope>`, parsed.XML)
+
+	for _, invalid := range []string{`<a><b></a>`, `<a>`, ``} {
+		parsedInvalid := model.ParseBody("application/xml", []byte(invalid))
+		require.NotNil(t, parsedInvalid)
+		assert.Contains(t, parsedInvalid.Error, "invalid XML", invalid)
+	}
+}
+
+func TestParseBodyIgnoresUnstructuredContentTypes(t *testing.T) {
+	assert.Nil(t, model.ParseBody("text/plain", []byte("hello")))
+	assert.Nil(t, model.ParseBody("", []byte(`{}`)))
+}
+
+func TestMessageDecodeBodyUsesContentTypeHeader(t *testing.T) {
+	message := model.NewMessage(http.MethodPost, "/hooks/id", "", "a=1", map[string][]string{"Content-Type": {"application/x-www-form-urlencoded"}})
+	message.DecodeBody()
+	require.NotNil(t, message.ParsedBody)
+	assert.Equal(t, model.ParsedBodyForm, message.ParsedBody.Kind)
+}
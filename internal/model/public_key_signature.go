@@ -0,0 +1,294 @@
+package model
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+)
+
+// SignatureAlgorithm is the public key algorithm a sender signs deliveries with.
+type SignatureAlgorithm string
+
+const (
+	// SignatureEd25519 verifies Ed25519 signatures of the signed content.
+	SignatureEd25519 SignatureAlgorithm = "ed25519"
+	// SignatureRSASHA256 verifies RSASSA-PKCS1-v1_5 signatures over SHA-256.
+	SignatureRSASHA256 SignatureAlgorithm = "rsa-sha256"
+	// SignatureRSAPSSSHA256 verifies RSASSA-PSS signatures over SHA-256.
+	SignatureRSAPSSSHA256 SignatureAlgorithm = "rsa-pss-sha256"
+	// SignatureECDSASHA256 verifies ECDSA signatures over SHA-256, either
+	// ASN.1 DER encoded or as the raw concatenation of r and s.
+	SignatureECDSASHA256 SignatureAlgorithm = "ecdsa-sha256"
+)
+
+const maxPublicKeyLength = 8192
+
+var signatureAlgorithmNames = map[SignatureAlgorithm]string{
+	SignatureEd25519:      "Ed25519",
+	SignatureRSASHA256:    "RSA SHA-256",
+	SignatureRSAPSSSHA256: "RSA-PSS SHA-256",
+	SignatureECDSASHA256:  "ECDSA SHA-256",
+}
+
+// PublicKeySignature verifies deliveries signed with the sender's private key
+// against its public key.
+type PublicKeySignature struct {
+	Algorithm SignatureAlgorithm `json:"algorithm"`
+	// PublicKey is a PEM block, base64 DER, or for Ed25519 the raw 32-byte key
+	// in hex or base64.
+	PublicKey string            `json:"publicKey"`
+	Header    string            `json:"header"`
+	Encoding  SignatureEncoding `json:"encoding,omitempty"`
+	// Prefix is stripped from the signature header before decoding.
+	Prefix string `json:"prefix,omitempty"`
+	// Template builds the signed content with the same placeholders as
+	// HMACOptions.Template, e.g. "{header:X-Signature-Timestamp}{body}".
+	Template string `json:"template,omitempty"`
+}
+
+// NewPublicKeySignature normalizes a configured public key signature and
+// returns nil when nothing is configured.
+func NewPublicKeySignature(signature *PublicKeySignature) *PublicKeySignature {
+	if signature == nil {
+		return nil
+	}
+
+	normalized := &PublicKeySignature{
+		Algorithm: SignatureAlgorithm(strings.ToLower(strings.TrimSpace(string(signature.Algorithm)))),
+		PublicKey: strings.TrimSpace(signature.PublicKey),
+		Header:    strings.TrimSpace(signature.Header),
+		Encoding:  SignatureEncoding(strings.ToLower(strings.TrimSpace(string(signature.Encoding)))),
+		Prefix:    strings.TrimSpace(signature.Prefix),
+		Template:  signature.Template,
+	}
+	if normalized.Encoding == SignatureEncodingHex {
+		normalized.Encoding = ""
+	}
+	if *normalized == (PublicKeySignature{}) {
+		return nil
+	}
+
+	return normalized
+}
+
+// Validate checks the algorithm, that the key parses and matches it, and the
+// header, encoding and template.
+func (s *PublicKeySignature) Validate() error {
+	if _, ok := signatureAlgorithmNames[s.Algorithm]; !ok {
+		return fmt.Errorf("public key signature algorithm must be one of %s, %s, %s or %s", SignatureEd25519, SignatureRSASHA256, SignatureRSAPSSSHA256, SignatureECDSASHA256)
+	}
+	if s.PublicKey == "" {
+		return errors.New("public key signature requires a public key")
+	}
+	if len(s.PublicKey) > maxPublicKeyLength {
+		return fmt.Errorf("public key must not exceed %d characters", maxPublicKeyLength)
+	}
+	if _, err := s.parsedKey(); err != nil {
+		return err
+	}
+	if s.Header == "" {
+		return errors.New("public key signature requires a signature header")
+	}
+	if !validHeaderName(s.Header) {
+		return fmt.Errorf("public key signature header %q is not a valid header name", s.Header)
+	}
+
+	switch s.Encoding {
+	case "", SignatureEncodingHex, SignatureEncodingBase64:
+	default:
+		return fmt.Errorf("public key signature encoding must be %s or %s", SignatureEncodingHex, SignatureEncodingBase64)
+	}
+
+	if strings.ContainsAny(s.Prefix, "\r\n") {
+		return errors.New("public key signature prefix must not contain line breaks")
+	}
+
+	if s.Template != "" {
+		if len(s.Template) > maxSignatureTemplateLength {
+			return fmt.Errorf("signature template must not exceed %d characters", maxSignatureTemplateLength)
+		}
+		if _, err := parseSignatureTemplate(s.Template, "signature"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EffectiveEncoding returns the configured encoding or hex when unset.
+func (s *PublicKeySignature) EffectiveEncoding() SignatureEncoding {
+	if s.Encoding == "" {
+		return SignatureEncodingHex
+	}
+
+	return s.Encoding
+}
+
+// Fingerprint identifies the public key as "SHA256:" followed by the unpadded
+// base64 SHA-256 digest of its DER encoding, or returns an empty string when
+// the key does not parse.
+func (s *PublicKeySignature) Fingerprint() string {
+	key, err := s.parsedKey()
+	if err != nil {
+		return ""
+	}
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return ""
+	}
+
+	digest := sha256.Sum256(der)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(digest[:])
+}
+
+// Description summarizes the scheme without the key itself, e.g.
+// "Ed25519 signature (X-Signature-Ed25519, key SHA256:...)".
+func (s *PublicKeySignature) Description() string {
+	return fmt.Sprintf("%s signature (%s, key %s)", signatureAlgorithmNames[s.Algorithm], s.Header, s.Fingerprint())
+}
+
+// failure verifies the signature header and returns a human-readable failure
+// or an empty string on success.
+func (s *PublicKeySignature) failure(r *http.Request, body []byte) string {
+	name := signatureAlgorithmNames[s.Algorithm]
+	signature := strings.TrimSpace(r.Header.Get(s.Header))
+	if signature == "" {
+		return fmt.Sprintf("Missing %s signature header %q", name, s.Header)
+	}
+
+	content := body
+	if s.Template != "" {
+		rendered, err := renderSignatureTemplate(s.Template, "signature", r, body)
+		if err != nil {
+			return err.Error()
+		}
+		content = rendered
+	}
+
+	if s.Prefix != "" {
+		signature = strings.TrimPrefix(signature, s.Prefix)
+	}
+	provided, ok := decodeSignatureValue(signature, s.EffectiveEncoding())
+	if !ok {
+		return fmt.Sprintf("%s signature in %q is not valid %s", name, s.Header, s.EffectiveEncoding())
+	}
+
+	key, err := s.parsedKey()
+	if err != nil {
+		return err.Error()
+	}
+	if !verifyPublicKeySignature(s.Algorithm, key, content, provided) {
+		return fmt.Sprintf("%s signature in %q did not match", name, s.Header)
+	}
+
+	return ""
+}
+
+func (s *PublicKeySignature) parsedKey() (crypto.PublicKey, error) {
+	key, err := parsePublicKey(s.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches bool
+	switch key.(type) {
+	case ed25519.PublicKey:
+		matches = s.Algorithm == SignatureEd25519
+	case *rsa.PublicKey:
+		matches = s.Algorithm == SignatureRSASHA256 || s.Algorithm == SignatureRSAPSSSHA256
+	case *ecdsa.PublicKey:
+		matches = s.Algorithm == SignatureECDSASHA256
+	}
+	if !matches {
+		return nil, fmt.Errorf("public key does not match the %s algorithm", s.Algorithm)
+	}
+
+	return key, nil
+}
+
+func verifyPublicKeySignature(algorithm SignatureAlgorithm, key crypto.PublicKey, content []byte, signature []byte) bool {
+	if algorithm == SignatureEd25519 {
+		return ed25519.Verify(key.(ed25519.PublicKey), content, signature)
+	}
+
+	digest := sha256.Sum256(content)
+	switch algorithm {
+	case SignatureRSASHA256:
+		return rsa.VerifyPKCS1v15(key.(*rsa.PublicKey), crypto.SHA256, digest[:], signature) == nil
+	case SignatureRSAPSSSHA256:
+		return rsa.VerifyPSS(key.(*rsa.PublicKey), crypto.SHA256, digest[:], signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto}) == nil
+	case SignatureECDSASHA256:
+		ecdsaKey := key.(*ecdsa.PublicKey)
+		if ecdsa.VerifyASN1(ecdsaKey, digest[:], signature) {
+			return true
+		}
+		size := (ecdsaKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(ecdsaKey, digest[:], r, s)
+	}
+
+	return false
+}
+
+// parsePublicKey accepts PEM encoded PKIX or PKCS #1 keys, base64 PKIX DER,
+// and raw Ed25519 keys in hex or base64.
+func parsePublicKey(text string) (crypto.PublicKey, error) {
+	if strings.HasPrefix(text, "-----BEGIN") {
+		block, _ := pem.Decode([]byte(text))
+		if block == nil {
+			return nil, errors.New("public key PEM block could not be decoded")
+		}
+		switch block.Type {
+		case "PUBLIC KEY":
+			return parsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+			if err != nil {
+				return nil, errors.New("public key is not a valid PKCS #1 RSA key")
+			}
+			return key, nil
+		default:
+			return nil, fmt.Errorf("public key PEM block must be PUBLIC KEY or RSA PUBLIC KEY, not %s", block.Type)
+		}
+	}
+
+	raw, err := hex.DecodeString(text)
+	if err != nil {
+		raw, err = base64.StdEncoding.DecodeString(text)
+	}
+	if err != nil {
+		raw, err = base64.RawStdEncoding.DecodeString(text)
+	}
+	if err != nil {
+		return nil, errors.New("public key must be PEM, base64 DER or a hex or base64 Ed25519 key")
+	}
+	if len(raw) == ed25519.PublicKeySize {
+		return ed25519.PublicKey(raw), nil
+	}
+
+	return parsePKIXPublicKey(raw)
+}
+
+func parsePKIXPublicKey(der []byte) (crypto.PublicKey, error) {
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, errors.New("public key is not a valid PKIX key")
+	}
+
+	return key, nil
+}
@@ -0,0 +1,178 @@
+package model_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"testing"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test 1 of RFC 8032, section 7.1: the signature of an empty message.
+const (
+	rfc8032PublicKey = "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a"
+	rfc8032Signature = "e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e065224901555fb8821590a33bacc61e39701cf9b46bd25bf5f0595bbe24655141438e7a100b"
+)
+
+func publicKeyPEM(t *testing.T, key crypto.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestNewPublicKeySignatureDropsEmptyConfiguration(t *testing.T) {
+	assert.Nil(t, model.NewPublicKeySignature(nil))
+	assert.Nil(t, model.NewPublicKeySignature(&model.PublicKeySignature{Encoding: "hex", Header: " "}))
+
+	signature := model.NewPublicKeySignature(&model.PublicKeySignature{Algorithm: " Ed25519 ", PublicKey: rfc8032PublicKey + "\n", Header: " X-Signature "})
+	assert.Equal(t, &model.PublicKeySignature{Algorithm: model.SignatureEd25519, PublicKey: rfc8032PublicKey, Header: "X-Signature"}, signature)
+}
+
+func TestPublicKeySignatureValidate(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	pkcs1 := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)}))
+
+	valid := []*model.PublicKeySignature{
+		{Algorithm: model.SignatureEd25519, PublicKey: rfc8032PublicKey, Header: "X-Signature"},
+		{Algorithm: model.SignatureRSASHA256, PublicKey: pkcs1, Header: "X-Signature", Encoding: model.SignatureEncodingBase64},
+		{Algorithm: model.SignatureRSAPSSSHA256, PublicKey: publicKeyPEM(t, &rsaKey.PublicKey), Header: "X-Signature", Template: "{header:X-Timestamp}.{body}"},
+	}
+	for _, signature := range valid {
+		assert.NoError(t, signature.Validate(), "%+v", signature)
+	}
+
+	invalid := map[string]*model.PublicKeySignature{
+		"public key signature algorithm must be one of ed25519, rsa-sha256, rsa-pss-sha256 or ecdsa-sha256": {Algorithm: "dsa", PublicKey: rfc8032PublicKey, Header: "X-Signature"},
+		"public key signature requires a public key":                                                        {Algorithm: model.SignatureEd25519, Header: "X-Signature"},
+		"public key must be PEM, base64 DER or a hex or base64 Ed25519 key":                                 {Algorithm: model.SignatureEd25519, PublicKey: "not a key!", Header: "X-Signature"},
+		"public key does not match the rsa-sha256 algorithm":                                                {Algorithm: model.SignatureRSASHA256, PublicKey: rfc8032PublicKey, Header: "X-Signature"},
+		"public key signature requires a signature header":                                                  {Algorithm: model.SignatureEd25519, PublicKey: rfc8032PublicKey},
+		"public key signature encoding must be hex or base64":                                               {Algorithm: model.SignatureEd25519, PublicKey: rfc8032PublicKey, Header: "X-Signature", Encoding: "base32"},
+		"signature template has an unclosed placeholder":                                                    {Algorithm: model.SignatureEd25519, PublicKey: rfc8032PublicKey, Header: "X-Signature", Template: "{body"},
+	}
+	for expected, signature := range invalid {
+		assert.EqualError(t, signature.Validate(), expected)
+	}
+}
+
+func TestAuthorizationFailureWithEd25519Signature(t *testing.T) {
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{
+		PublicKeySignature: &model.PublicKeySignature{Algorithm: model.SignatureEd25519, PublicKey: rfc8032PublicKey, Header: "X-Signature-Ed25519"},
+	})
+	require.NoError(t, webhook.Validate())
+	request, _ := http.NewRequest(http.MethodPost, "/hooks/id", nil)
+
+	assert.Equal(t, `Missing Ed25519 signature header "X-Signature-Ed25519"`, webhook.AuthorizationFailure(request, nil))
+
+	request.Header.Set("X-Signature-Ed25519", rfc8032Signature)
+	assert.Empty(t, webhook.AuthorizationFailure(request, nil))
+	assert.Equal(t, `Ed25519 signature in "X-Signature-Ed25519" did not match`, webhook.AuthorizationFailure(request, []byte("{}")))
+
+	request.Header.Set("X-Signature-Ed25519", "zz")
+	assert.Equal(t, `Ed25519 signature in "X-Signature-Ed25519" is not valid hex`, webhook.AuthorizationFailure(request, nil))
+}
+
+func TestAuthorizationFailureWithDiscordStyleTemplate(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	body := []byte(`{"type":1}`)
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{
+		PublicKeySignature: &model.PublicKeySignature{
+			Algorithm: model.SignatureEd25519,
+			PublicKey: base64.StdEncoding.EncodeToString(publicKey),
+			Header:    "X-Signature-Ed25519",
+			Template:  "{header:X-Signature-Timestamp}{body}",
+		},
+	})
+	require.NoError(t, webhook.Validate())
+	request, _ := http.NewRequest(http.MethodPost, "/hooks/id", nil)
+	request.Header.Set("X-Signature-Timestamp", "1700000000")
+	request.Header.Set("X-Signature-Ed25519", hex.EncodeToString(ed25519.Sign(privateKey, append([]byte("1700000000"), body...))))
+
+	assert.Empty(t, webhook.AuthorizationFailure(request, body))
+
+	request.Header.Del("X-Signature-Timestamp")
+	assert.Equal(t, `Missing header "X-Signature-Timestamp" required by the signature template`, webhook.AuthorizationFailure(request, body))
+}
+
+func TestAuthorizationFailureWithRSASignatures(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	body := []byte(`{"event":"created"}`)
+	digest := sha256.Sum256(body)
+	pkcs1v15, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	pss, err := rsa.SignPSS(rand.Reader, privateKey, crypto.SHA256, digest[:], nil)
+	require.NoError(t, err)
+
+	for algorithm, signature := range map[model.SignatureAlgorithm][]byte{model.SignatureRSASHA256: pkcs1v15, model.SignatureRSAPSSSHA256: pss} {
+		webhook := model.NewWebhookFromInput(&model.WebhookInput{
+			PublicKeySignature: &model.PublicKeySignature{
+				Algorithm: algorithm,
+				PublicKey: publicKeyPEM(t, &privateKey.PublicKey),
+				Header:    "X-Signature",
+				Encoding:  model.SignatureEncodingBase64,
+				Prefix:    "rsa=",
+			},
+		})
+		require.NoError(t, webhook.Validate())
+		request, _ := http.NewRequest(http.MethodPost, "/hooks/id", nil)
+		request.Header.Set("X-Signature", "rsa="+base64.StdEncoding.EncodeToString(signature))
+
+		assert.Empty(t, webhook.AuthorizationFailure(request, body), algorithm)
+		assert.NotEmpty(t, webhook.AuthorizationFailure(request, []byte(`{}`)), algorithm)
+	}
+}
+
+func TestAuthorizationFailureWithECDSASignatures(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	body := []byte(`{"event":"created"}`)
+	digest := sha256.Sum256(body)
+	der, err := ecdsa.SignASN1(rand.Reader, privateKey, digest[:])
+	require.NoError(t, err)
+	r, s, err := ecdsa.Sign(rand.Reader, privateKey, digest[:])
+	require.NoError(t, err)
+	raw := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{
+		PublicKeySignature: &model.PublicKeySignature{Algorithm: model.SignatureECDSASHA256, PublicKey: publicKeyPEM(t, &privateKey.PublicKey), Header: "X-Signature"},
+	})
+	require.NoError(t, webhook.Validate())
+	request, _ := http.NewRequest(http.MethodPost, "/hooks/id", nil)
+
+	for _, signature := range [][]byte{der, raw} {
+		request.Header.Set("X-Signature", hex.EncodeToString(signature))
+		assert.Empty(t, webhook.AuthorizationFailure(request, body))
+	}
+	assert.Equal(t, `ECDSA SHA-256 signature in "X-Signature" did not match`, webhook.AuthorizationFailure(request, []byte(`{}`)))
+}
+
+func TestPublicKeySignatureDescriptionShowsFingerprint(t *testing.T) {
+	signature := &model.PublicKeySignature{Algorithm: model.SignatureEd25519, PublicKey: rfc8032PublicKey, Header: "X-Signature-Ed25519"}
+
+	key, _ := hex.DecodeString(rfc8032PublicKey)
+	der, err := x509.MarshalPKIXPublicKey(ed25519.PublicKey(key))
+	require.NoError(t, err)
+	digest := sha256.Sum256(der)
+	fingerprint := "SHA256:" + base64.RawStdEncoding.EncodeToString(digest[:])
+
+	assert.Equal(t, fingerprint, signature.Fingerprint())
+	assert.Equal(t, "Ed25519 signature (X-Signature-Ed25519, key "+fingerprint+")", signature.Description())
+	assert.NotContains(t, signature.Description(), rfc8032PublicKey)
+}
@@ -0,0 +1,337 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxRedactionEntries = 50
+	redactionMask       = "****"
+	// maskVisibleCharacters is how many trailing characters of a masked value
+	// stay readable, so masked secrets can still be told apart.
+	maskVisibleCharacters = 4
+)
+
+// Redaction hides sensitive parts of captured requests before they are
+// stored. Header patterns are exact names or prefixes ending in "*", e.g.
+// "X-Forwarded-*". Dropped headers are not stored at all, masked headers keep
+// only their last four characters. Payload paths mask fields of JSON bodies.
+type Redaction struct {
+	DropHeaders  []string `json:"dropHeaders,omitempty"`
+	MaskHeaders  []string `json:"maskHeaders,omitempty"`
+	PayloadPaths []string `json:"payloadPaths,omitempty"`
+}
+
+// DefaultDroppedHeaders lists the proxy headers dropped from captured requests
+// unless the server configures its own list.
+func DefaultDroppedHeaders() []string {
+	return []string{"Fly-*", "X-Forwarded-*", "Via", "X-Request-Start"}
+}
+
+// NewRedaction normalizes configured redaction and returns nil when nothing is
+// configured.
+func NewRedaction(redaction *Redaction) *Redaction {
+	if redaction == nil {
+		return nil
+	}
+
+	normalized := &Redaction{
+		DropHeaders:  NewHeaderPatterns(redaction.DropHeaders),
+		MaskHeaders:  NewHeaderPatterns(redaction.MaskHeaders),
+		PayloadPaths: trimmedEntries(redaction.PayloadPaths),
+	}
+	if len(normalized.DropHeaders) == 0 && len(normalized.MaskHeaders) == 0 && len(normalized.PayloadPaths) == 0 {
+		return nil
+	}
+
+	return normalized
+}
+
+// NewHeaderPatterns trims and canonicalizes header patterns and drops blank
+// entries.
+func NewHeaderPatterns(patterns []string) []string {
+	var normalized []string
+	for _, pattern := range trimmedEntries(patterns) {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			normalized = append(normalized, http.CanonicalHeaderKey(prefix)+"*")
+			continue
+		}
+		normalized = append(normalized, http.CanonicalHeaderKey(pattern))
+	}
+
+	return normalized
+}
+
+func trimmedEntries(entries []string) []string {
+	var trimmed []string
+	for _, entry := range entries {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			trimmed = append(trimmed, entry)
+		}
+	}
+
+	return trimmed
+}
+
+// Validate checks header patterns and payload paths.
+func (r *Redaction) Validate() error {
+	if len(r.DropHeaders)+len(r.MaskHeaders)+len(r.PayloadPaths) > maxRedactionEntries {
+		return fmt.Errorf("redaction must not contain more than %d entries", maxRedactionEntries)
+	}
+	if err := ValidateHeaderPatterns(r.DropHeaders); err != nil {
+		return err
+	}
+	if err := ValidateHeaderPatterns(r.MaskHeaders); err != nil {
+		return err
+	}
+	for _, path := range r.PayloadPaths {
+		if _, err := ParseJSONPath(path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateHeaderPatterns checks that every pattern is a header name or a
+// non-empty header name prefix followed by "*".
+func ValidateHeaderPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		name := strings.TrimSuffix(pattern, "*")
+		if !validHeaderName(name) {
+			return fmt.Errorf("redacted header %q is not a valid header name or prefix", pattern)
+		}
+	}
+
+	return nil
+}
+
+// RedactHeaders drops or masks captured headers in place. Redactions are
+// consulted in order and the first one naming a header decides; within one
+// redaction, dropping wins over masking. Nil redactions are skipped.
+func RedactHeaders(headers map[string][]string, redactions ...*Redaction) {
+	for name, values := range headers {
+		for _, redaction := range redactions {
+			if redaction == nil {
+				continue
+			}
+			if headerPatternsMatch(redaction.DropHeaders, name) {
+				delete(headers, name)
+				break
+			}
+			if headerPatternsMatch(redaction.MaskHeaders, name) {
+				masked := make([]string, len(values))
+				for index, value := range values {
+					masked[index] = MaskValue(value)
+				}
+				headers[name] = masked
+				break
+			}
+		}
+	}
+}
+
+func headerPatternsMatch(patterns []string, name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+			continue
+		}
+		if name == pattern {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MaskValue hides a secret, keeping its last four characters when the value is
+// long enough that they do not give most of it away.
+func MaskValue(value string) string {
+	if utf8.RuneCountInString(value) <= 2*maskVisibleCharacters {
+		return redactionMask
+	}
+
+	runes := []rune(value)
+	return redactionMask + string(runes[len(runes)-maskVisibleCharacters:])
+}
+
+// MaskPayload masks the configured payload paths of a JSON body. Bodies that
+// are not JSON, or in which no path matches, are returned unchanged. Matched
+// strings and numbers are masked like header values; other values are replaced
+// by "****". Member order and untouched values are preserved.
+func (r *Redaction) MaskPayload(body []byte) []byte {
+	if r == nil || len(r.PayloadPaths) == 0 || !json.Valid(body) {
+		return body
+	}
+
+	masked := json.RawMessage(body)
+	changed := false
+	for _, rawPath := range r.PayloadPaths {
+		path, err := ParseJSONPath(rawPath)
+		if err != nil {
+			continue
+		}
+		if result, ok := maskJSONValue(masked, path.segments); ok {
+			masked, changed = result, true
+		}
+	}
+	if !changed {
+		return body
+	}
+
+	return masked
+}
+
+func maskJSONValue(value json.RawMessage, segments []jsonPathSegment) (json.RawMessage, bool) {
+	if len(segments) == 0 {
+		return maskedJSONValue(value), true
+	}
+
+	segment, rest := segments[0], segments[1:]
+	trimmed := bytes.TrimSpace(value)
+	if len(trimmed) == 0 {
+		return value, false
+	}
+	switch trimmed[0] {
+	case '{':
+		members, err := decodeJSONObject(trimmed)
+		if err != nil {
+			return value, false
+		}
+		changed := false
+		for index := range members {
+			if !segment.matchesKey(members[index].key) {
+				continue
+			}
+			if masked, ok := maskJSONValue(members[index].value, rest); ok {
+				members[index].value, changed = masked, true
+			}
+		}
+		if !changed {
+			return value, false
+		}
+		return encodeJSONObject(members), true
+	case '[':
+		var elements []json.RawMessage
+		if err := json.Unmarshal(trimmed, &elements); err != nil {
+			return value, false
+		}
+		changed := false
+		for index := range elements {
+			if !segment.matchesIndex(index) {
+				continue
+			}
+			if masked, ok := maskJSONValue(elements[index], rest); ok {
+				elements[index], changed = masked, true
+			}
+		}
+		if !changed {
+			return value, false
+		}
+		return encodeJSONArray(elements), true
+	default:
+		return value, false
+	}
+}
+
+func maskedJSONValue(value json.RawMessage) json.RawMessage {
+	trimmed := bytes.TrimSpace(value)
+	masked := redactionMask
+	if len(trimmed) > 0 {
+		switch {
+		case trimmed[0] == '"':
+			var text string
+			if err := json.Unmarshal(trimmed, &text); err == nil {
+				masked = MaskValue(text)
+			}
+		case trimmed[0] == '-' || (trimmed[0] >= '0' && trimmed[0] <= '9'):
+			masked = MaskValue(string(trimmed))
+		}
+	}
+
+	return encodeJSONString(masked)
+}
+
+type jsonMember struct {
+	key   string
+	value json.RawMessage
+}
+
+// decodeJSONObject reads object members in document order.
+func decodeJSONObject(data []byte) ([]jsonMember, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+
+	var members []jsonMember
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, errors.New("object key is not a string")
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		members = append(members, jsonMember{key: key, value: value})
+	}
+
+	return members, nil
+}
+
+func encodeJSONObject(members []jsonMember) json.RawMessage {
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	for index, member := range members {
+		if index > 0 {
+			buffer.WriteByte(',')
+		}
+		buffer.Write(encodeJSONString(member.key))
+		buffer.WriteByte(':')
+		buffer.Write(member.value)
+	}
+	buffer.WriteByte('}')
+
+	return buffer.Bytes()
+}
+
+func encodeJSONArray(elements []json.RawMessage) json.RawMessage {
+	var buffer bytes.Buffer
+	buffer.WriteByte('[')
+	for index, element := range elements {
+		if index > 0 {
+			buffer.WriteByte(',')
+		}
+		buffer.Write(element)
+	}
+	buffer.WriteByte(']')
+
+	return buffer.Bytes()
+}
+
+// encodeJSONString encodes text without escaping HTML characters, so
+// untouched keys keep their original spelling.
+func encodeJSONString(text string) json.RawMessage {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(text)
+
+	return bytes.TrimRight(buffer.Bytes(), "\n")
+}
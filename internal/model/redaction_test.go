@@ -0,0 +1,77 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRedaction(t *testing.T) {
+	assert.Nil(t, model.NewRedaction(nil))
+	assert.Nil(t, model.NewRedaction(&model.Redaction{DropHeaders: []string{" "}, PayloadPaths: []string{"\r"}}))
+	assert.Equal(t, &model.Redaction{
+		DropHeaders:  []string{"X-Internal-*"},
+		MaskHeaders:  []string{"X-Api-Key"},
+		PayloadPaths: []string{"$.password"},
+	}, model.NewRedaction(&model.Redaction{
+		DropHeaders:  []string{" x-internal-* "},
+		MaskHeaders:  []string{"x-api-key\r"},
+		PayloadPaths: []string{" $.password"},
+	}))
+}
+
+func TestValidateRejectsInvalidRedaction(t *testing.T) {
+	invalidInputs := map[string]*model.Redaction{
+		`redacted header "X Secret" is not a valid header name or prefix`: {MaskHeaders: []string{"X Secret"}},
+		`redacted header "*" is not a valid header name or prefix`:        {DropHeaders: []string{"*"}},
+		`json path "$." is invalid: empty key`:                            {PayloadPaths: []string{"$."}},
+	}
+
+	for expected, redaction := range invalidInputs {
+		assert.EqualError(t, model.NewWebhookFromInput(&model.WebhookInput{Redaction: redaction}).Validate(), expected)
+	}
+}
+
+func TestRedactHeaders(t *testing.T) {
+	headers := map[string][]string{
+		"X-Forwarded-For": {"203.0.113.10"},
+		"X-Api-Key":       {"sk_live_1234567890", "short"},
+		"Via":             {"1.1 proxy"},
+		"Content-Type":    {"application/json"},
+	}
+	webhookRedaction := &model.Redaction{MaskHeaders: []string{"X-Forwarded-*", "x-api-key"}}
+	serverRedaction := &model.Redaction{DropHeaders: model.DefaultDroppedHeaders()}
+
+	model.RedactHeaders(headers, webhookRedaction, nil, serverRedaction)
+
+	assert.Equal(t, map[string][]string{
+		"X-Forwarded-For": {"****3.10"},
+		"X-Api-Key":       {"****7890", "****"},
+		"Content-Type":    {"application/json"},
+	}, headers)
+}
+
+func TestMaskValue(t *testing.T) {
+	assert.Equal(t, "****", model.MaskValue(""))
+	assert.Equal(t, "****", model.MaskValue("12345678"))
+	assert.Equal(t, "****6789", model.MaskValue("123456789"))
+	assert.Equal(t, "****ßäöü", model.MaskValue("geheimßäöü"))
+}
+
+func TestMaskPayload(t *testing.T) {
+	redaction := &model.Redaction{PayloadPaths: []string{"$.card.number", "users[*].password", "$.token", "$.missing"}}
+	body := []byte(`{"token":{"a":1},"card":{"number":4111111111111111,"brand":"<visa>"},"users":[{"name":"a","password":"correct horse"},{"name":"b"}]}`)
+
+	assert.JSONEq(t,
+		`{"token":"****","card":{"number":"****1111","brand":"<visa>"},"users":[{"name":"a","password":"****orse"},{"name":"b"}]}`,
+		string(redaction.MaskPayload(body)),
+	)
+	assert.Equal(t, `{"token":"****","card":{"number":"****1111","brand":"<visa>"}}`,
+		string(redaction.MaskPayload([]byte(`{"token": "abc", "card": {"number": 4111111111111111, "brand": "<visa>"}}`))))
+
+	unchanged := []byte(`{"other": true}`)
+	assert.Equal(t, unchanged, redaction.MaskPayload(unchanged))
+	assert.Equal(t, []byte("token=secret"), redaction.MaskPayload([]byte("token=secret")))
+	assert.Equal(t, body, (*model.Redaction)(nil).MaskPayload(body))
+}
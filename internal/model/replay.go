@@ -0,0 +1,86 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ReplayInput is used for unmarshaling replay requests.
+type ReplayInput struct {
+	TargetURL string            `json:"targetUrl"`
+	Headers   map[string]string `json:"headers,omitempty"`
+}
+
+// ReplayAttempt records the result of resending a captured message to a target URL.
+type ReplayAttempt struct {
+	ID            int64     `json:"id"`
+	MessageID     int64     `json:"messageId"`
+	TargetURL     string    `json:"targetUrl"`
+	StatusCode    int       `json:"statusCode,omitempty"`
+	LatencyMillis int64     `json:"latencyMs"`
+	ResponseBody  string    `json:"responseBody,omitempty"`
+	ErrorMessage  string    `json:"error,omitempty"`
+	Time          time.Time `json:"time"`
+}
+
+// Validate validates the target URL and header overrides.
+func (i *ReplayInput) Validate() error {
+	targetURL, err := ParseTargetURL(i.TargetURL)
+	if err != nil {
+		return err
+	}
+	i.TargetURL = targetURL.String()
+
+	normalizedHeaders := make(map[string]string, len(i.Headers))
+	for name, value := range i.Headers {
+		canonicalName := http.CanonicalHeaderKey(strings.TrimSpace(name))
+		if !validHeaderName(canonicalName) {
+			return fmt.Errorf("header name %q is invalid", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("header %q must not contain line breaks", name)
+		}
+		normalizedHeaders[canonicalName] = value
+	}
+	i.Headers = normalizedHeaders
+
+	return nil
+}
+
+// ParseTargetURL validates that the URL is an absolute http or https URL.
+func ParseTargetURL(rawURL string) (*url.URL, error) {
+	trimmedURL := strings.TrimSpace(rawURL)
+	if trimmedURL == "" {
+		return nil, errors.New("target url must not be empty")
+	}
+
+	parsedURL, err := url.Parse(trimmedURL)
+	if err != nil {
+		return nil, errors.New("target url is invalid")
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return nil, errors.New("target url must use http or https")
+	}
+	if parsedURL.Host == "" {
+		return nil, errors.New("target url must be absolute")
+	}
+	if parsedURL.User != nil {
+		return nil, errors.New("target url must not contain credentials")
+	}
+
+	parsedURL.Fragment = ""
+	return parsedURL, nil
+}
+
+// NewReplayAttempt creates a replay attempt for the message with the current timestamp.
+func NewReplayAttempt(messageID int64, targetURL string) *ReplayAttempt {
+	return &ReplayAttempt{
+		MessageID: messageID,
+		TargetURL: targetURL,
+		Time:      time.Now().UTC(),
+	}
+}
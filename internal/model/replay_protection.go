@@ -0,0 +1,163 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMaxTimestampSkew is how far a delivery timestamp may be from the
+// receiver clock when replay protection sets no explicit skew.
+const DefaultMaxTimestampSkew = 5 * time.Minute
+
+const maxTimestampSkewSeconds = 24 * 60 * 60
+
+// unixMillisecondsThreshold separates Unix timestamps in seconds from ones in
+// milliseconds; second-based values stay below it until the year 33658.
+const unixMillisecondsThreshold = 1_000_000_000_000
+
+// ReplayProtection rejects deliveries whose timestamp is too far from the
+// receiver clock and remembers delivery IDs to detect repeated deliveries.
+type ReplayProtection struct {
+	TimestampHeader  string `json:"timestampHeader,omitempty"`
+	MaxSkewSeconds   int    `json:"maxSkewSeconds,omitempty"`
+	DeliveryIDHeader string `json:"deliveryIdHeader,omitempty"`
+	// FlagDuplicates captures repeated delivery IDs as duplicates instead of
+	// rejecting them.
+	FlagDuplicates bool `json:"flagDuplicates,omitempty"`
+}
+
+// NewReplayProtection normalizes configured replay protection and returns nil
+// when nothing is configured.
+func NewReplayProtection(protection *ReplayProtection) *ReplayProtection {
+	if protection == nil {
+		return nil
+	}
+
+	normalized := &ReplayProtection{
+		TimestampHeader:  strings.TrimSpace(protection.TimestampHeader),
+		MaxSkewSeconds:   protection.MaxSkewSeconds,
+		DeliveryIDHeader: strings.TrimSpace(protection.DeliveryIDHeader),
+		FlagDuplicates:   protection.FlagDuplicates,
+	}
+	if *normalized == (ReplayProtection{}) {
+		return nil
+	}
+
+	return normalized
+}
+
+// Validate validates the configured headers and skew.
+func (p *ReplayProtection) Validate() error {
+	if p.TimestampHeader == "" && p.DeliveryIDHeader == "" {
+		return errors.New("replay protection requires a timestamp header or a delivery id header")
+	}
+	if p.TimestampHeader != "" && !validHeaderName(p.TimestampHeader) {
+		return fmt.Errorf("replay protection timestamp header %q is not a valid header name", p.TimestampHeader)
+	}
+	if p.DeliveryIDHeader != "" && !validHeaderName(p.DeliveryIDHeader) {
+		return fmt.Errorf("replay protection delivery id header %q is not a valid header name", p.DeliveryIDHeader)
+	}
+	if p.MaxSkewSeconds != 0 && p.TimestampHeader == "" {
+		return errors.New("replay protection max skew requires a timestamp header")
+	}
+	if p.MaxSkewSeconds < 0 || p.MaxSkewSeconds > maxTimestampSkewSeconds {
+		return fmt.Errorf("replay protection max skew must be between 1 and %d seconds", maxTimestampSkewSeconds)
+	}
+	if p.FlagDuplicates && p.DeliveryIDHeader == "" {
+		return errors.New("flagging duplicates requires a delivery id header")
+	}
+
+	return nil
+}
+
+// EffectiveMaxSkew returns the configured skew or DefaultMaxTimestampSkew when unset.
+func (p *ReplayProtection) EffectiveMaxSkew() time.Duration {
+	if p.MaxSkewSeconds == 0 {
+		return DefaultMaxTimestampSkew
+	}
+
+	return time.Duration(p.MaxSkewSeconds) * time.Second
+}
+
+// TracksDeliveryIDs indicates whether delivery IDs are remembered.
+func (p *ReplayProtection) TracksDeliveryIDs() bool {
+	return p != nil && p.DeliveryIDHeader != ""
+}
+
+// DeliveryID returns the delivery ID sent with the request.
+func (p *ReplayProtection) DeliveryID(r *http.Request) string {
+	if !p.TracksDeliveryIDs() {
+		return ""
+	}
+
+	return strings.TrimSpace(r.Header.Get(p.DeliveryIDHeader))
+}
+
+// DuplicateFailure describes the rejection of a repeated delivery ID.
+func (p *ReplayProtection) DuplicateFailure(deliveryID string) string {
+	return fmt.Sprintf("Duplicate delivery ID %q in %q", deliveryID, p.DeliveryIDHeader)
+}
+
+// Description summarizes the checks, e.g. "Replay protection (timestamp X-Timestamp ±5m0s, delivery ID X-Delivery)".
+func (p *ReplayProtection) Description() string {
+	var checks []string
+	if p.TimestampHeader != "" {
+		checks = append(checks, fmt.Sprintf("timestamp %s ±%s", p.TimestampHeader, p.EffectiveMaxSkew()))
+	}
+	if p.DeliveryIDHeader != "" {
+		check := "delivery ID " + p.DeliveryIDHeader
+		if p.FlagDuplicates {
+			check += ", duplicates flagged"
+		}
+		checks = append(checks, check)
+	}
+
+	return "Replay protection (" + strings.Join(checks, ", ") + ")"
+}
+
+// failure checks the headers of a delivery at the given time. Whether a
+// delivery ID was seen before is decided by the caller, which owns storage.
+func (p *ReplayProtection) failure(r *http.Request, now time.Time) string {
+	if p.TimestampHeader != "" {
+		value := strings.TrimSpace(r.Header.Get(p.TimestampHeader))
+		if value == "" {
+			return fmt.Sprintf("Missing timestamp header %q", p.TimestampHeader)
+		}
+		sentAt, ok := parseDeliveryTimestamp(value)
+		if !ok {
+			return fmt.Sprintf("%s timestamp %q is not a Unix or RFC 3339 timestamp", p.TimestampHeader, value)
+		}
+		if failure := timestampToleranceFailure(p.TimestampHeader, sentAt, now, p.EffectiveMaxSkew()); failure != "" {
+			return failure
+		}
+	}
+
+	if p.DeliveryIDHeader != "" && p.DeliveryID(r) == "" {
+		return fmt.Sprintf("Missing delivery ID header %q", p.DeliveryIDHeader)
+	}
+
+	return ""
+}
+
+// parseDeliveryTimestamp accepts Unix seconds, Unix milliseconds, RFC 3339 and
+// HTTP dates.
+func parseDeliveryTimestamp(value string) (time.Time, bool) {
+	if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if number >= unixMillisecondsThreshold {
+			return time.UnixMilli(number), true
+		}
+		return time.Unix(number, 0), true
+	}
+	if parsed, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return parsed, true
+	}
+	if parsed, err := http.ParseTime(value); err == nil {
+		return parsed, true
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,83 @@
+package model_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReplayProtectionDropsEmptyConfiguration(t *testing.T) {
+	assert.Nil(t, model.NewReplayProtection(nil))
+	assert.Nil(t, model.NewReplayProtection(&model.ReplayProtection{TimestampHeader: "  "}))
+
+	protection := model.NewReplayProtection(&model.ReplayProtection{DeliveryIDHeader: " X-Delivery "})
+	assert.Equal(t, &model.ReplayProtection{DeliveryIDHeader: "X-Delivery"}, protection)
+}
+
+func TestReplayProtectionValidate(t *testing.T) {
+	assert.NoError(t, (&model.ReplayProtection{TimestampHeader: "X-Timestamp", MaxSkewSeconds: 60}).Validate())
+	assert.NoError(t, (&model.ReplayProtection{DeliveryIDHeader: "X-Delivery", FlagDuplicates: true}).Validate())
+
+	invalid := []*model.ReplayProtection{
+		{MaxSkewSeconds: 60},
+		{TimestampHeader: "Bad Header"},
+		{DeliveryIDHeader: "Bad:Header"},
+		{TimestampHeader: "X-Timestamp", MaxSkewSeconds: -1},
+		{TimestampHeader: "X-Timestamp", MaxSkewSeconds: 86401},
+		{TimestampHeader: "X-Timestamp", FlagDuplicates: true},
+	}
+	for _, protection := range invalid {
+		assert.Error(t, protection.Validate(), "%+v", protection)
+	}
+}
+
+func TestAuthorizationFailureChecksTimestampSkew(t *testing.T) {
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{
+		ReplayProtection: &model.ReplayProtection{TimestampHeader: "X-Timestamp", MaxSkewSeconds: 60},
+	})
+	require.NoError(t, webhook.Validate())
+	request, _ := http.NewRequest(http.MethodPost, "/hooks/id", nil)
+
+	assert.Equal(t, `Missing timestamp header "X-Timestamp"`, webhook.AuthorizationFailure(request, nil))
+
+	for _, timestamp := range []string{
+		strconv.FormatInt(time.Now().Unix(), 10),
+		strconv.FormatInt(time.Now().UnixMilli(), 10),
+		time.Now().UTC().Format(time.RFC3339),
+		time.Now().UTC().Format(http.TimeFormat),
+	} {
+		request.Header.Set("X-Timestamp", timestamp)
+		assert.Empty(t, webhook.AuthorizationFailure(request, nil), timestamp)
+	}
+
+	request.Header.Set("X-Timestamp", "yesterday")
+	assert.Equal(t, `X-Timestamp timestamp "yesterday" is not a Unix or RFC 3339 timestamp`, webhook.AuthorizationFailure(request, nil))
+
+	request.Header.Set("X-Timestamp", strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10))
+	assert.Regexp(t, `^X-Timestamp timestamp is 10m[0-9]s away from the receiver clock, beyond the 1m0s tolerance$`, webhook.AuthorizationFailure(request, nil))
+}
+
+func TestAuthorizationFailureRequiresDeliveryID(t *testing.T) {
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{
+		ReplayProtection: &model.ReplayProtection{DeliveryIDHeader: "X-GitHub-Delivery"},
+	})
+	request, _ := http.NewRequest(http.MethodPost, "/hooks/id", nil)
+
+	assert.Equal(t, `Missing delivery ID header "X-GitHub-Delivery"`, webhook.AuthorizationFailure(request, nil))
+
+	request.Header.Set("X-GitHub-Delivery", " 72d3162e ")
+	assert.Empty(t, webhook.AuthorizationFailure(request, nil))
+	assert.Equal(t, "72d3162e", webhook.ReplayProtection.DeliveryID(request))
+	assert.Equal(t, `Duplicate delivery ID "72d3162e" in "X-GitHub-Delivery"`, webhook.ReplayProtection.DuplicateFailure("72d3162e"))
+}
+
+func TestReplayProtectionDescription(t *testing.T) {
+	protection := &model.ReplayProtection{TimestampHeader: "X-Timestamp", DeliveryIDHeader: "X-Delivery", FlagDuplicates: true}
+
+	assert.Equal(t, "Replay protection (timestamp X-Timestamp ±5m0s, delivery ID X-Delivery, duplicates flagged)", protection.Description())
+}
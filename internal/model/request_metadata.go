@@ -0,0 +1,51 @@
+package model
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+)
+
+// TLSDetails describes the TLS connection a delivery arrived on.
+type TLSDetails struct {
+	Version     string `json:"version"`
+	CipherSuite string `json:"cipherSuite"`
+	// ServerName is the SNI host name requested by the client, if any.
+	ServerName string `json:"serverName,omitempty"`
+}
+
+// NewTLSDetails describes a TLS connection, or returns nil for plain HTTP.
+func NewTLSDetails(state *tls.ConnectionState) *TLSDetails {
+	if state == nil {
+		return nil
+	}
+
+	return &TLSDetails{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ServerName:  state.ServerName,
+	}
+}
+
+// CaptureRequestMetadata records who sent the delivery and how. The remote IP
+// is resolved by the caller, which knows whether a client IP header is trusted.
+// The content length is the declared one, or for chunked requests the size of
+// the body as received, which differs from the captured body once payload
+// fields are masked.
+func (m *Message) CaptureRequestMetadata(r *http.Request, remoteIP string, bodyLength int) {
+	m.RemoteIP = remoteIP
+	m.Protocol = r.Proto
+	m.Host = r.Host
+	m.ContentLength = r.ContentLength
+	if m.ContentLength < 0 {
+		m.ContentLength = int64(bodyLength)
+	}
+	m.TLS = NewTLSDetails(r.TLS)
+	m.ClientCertificate = PresentedClientCertificate(r.TLS)
+}
+
+// SetProcessingDuration records how long the receiver took to handle the
+// delivery, including any forwarding.
+func (m *Message) SetProcessingDuration(duration time.Duration) {
+	m.ProcessingMicros = duration.Microseconds()
+}
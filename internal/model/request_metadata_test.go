@@ -0,0 +1,43 @@
+package model_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaptureRequestMetadata(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "http://receiver.example/hooks/id", strings.NewReader("payload"))
+	message := model.NewMessage(request.Method, request.URL.Path, "", "payload", nil)
+	message.CaptureRequestMetadata(request, "192.0.2.1", len("payload"))
+
+	assert.Equal(t, "192.0.2.1", message.RemoteIP)
+	assert.Equal(t, "HTTP/1.1", message.Protocol)
+	assert.Equal(t, "receiver.example", message.Host)
+	assert.Equal(t, int64(7), message.ContentLength)
+	assert.Nil(t, message.TLS)
+}
+
+func TestCaptureRequestMetadataOfChunkedTLSRequest(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "https://receiver.example/hooks/id", nil)
+	request.ContentLength = -1
+	request.TLS = &tls.ConnectionState{Version: tls.VersionTLS13, CipherSuite: tls.TLS_AES_128_GCM_SHA256, ServerName: "receiver.example"}
+	message := model.NewMessage(request.Method, request.URL.Path, "", "****", nil)
+	message.CaptureRequestMetadata(request, "unknown", len("chunked payload"))
+
+	assert.Equal(t, int64(len("chunked payload")), message.ContentLength)
+	assert.Equal(t, &model.TLSDetails{Version: "TLS 1.3", CipherSuite: "TLS_AES_128_GCM_SHA256", ServerName: "receiver.example"}, message.TLS)
+}
+
+func TestMessageProcessingDuration(t *testing.T) {
+	message := model.NewMessage(http.MethodPost, "/hooks/id", "", "", nil)
+	message.SetProcessingDuration(1500 * time.Microsecond)
+
+	assert.Equal(t, int64(1500), message.ProcessingMicros)
+}
@@ -0,0 +1,125 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const maxResponseBodyBytes = 64 << 10
+
+// WebhookResponse describes the response returned to senders for accepted deliveries.
+type WebhookResponse struct {
+	StatusCode  int               `json:"statusCode,omitempty"`
+	ContentType string            `json:"contentType,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        string            `json:"body,omitempty"`
+}
+
+// NewWebhookResponse normalizes a configured response and returns nil when nothing is set.
+func NewWebhookResponse(response *WebhookResponse) *WebhookResponse {
+	if response == nil {
+		return nil
+	}
+
+	normalized := &WebhookResponse{
+		StatusCode:  response.StatusCode,
+		ContentType: strings.TrimSpace(response.ContentType),
+		Body:        response.Body,
+	}
+	if len(response.Headers) > 0 {
+		normalized.Headers = make(map[string]string, len(response.Headers))
+		for name, value := range response.Headers {
+			normalized.Headers[http.CanonicalHeaderKey(strings.TrimSpace(name))] = strings.TrimSpace(value)
+		}
+	}
+
+	if normalized.StatusCode == 0 && normalized.ContentType == "" && len(normalized.Headers) == 0 && normalized.Body == "" {
+		return nil
+	}
+
+	return normalized
+}
+
+// Validate validates the configured status code, headers, and body.
+func (r *WebhookResponse) Validate() error {
+	if r.StatusCode != 0 && (r.StatusCode < 200 || r.StatusCode > 599) {
+		return errors.New("response status code must be between 200 and 599")
+	}
+
+	if strings.ContainsAny(r.ContentType, "\r\n") {
+		return errors.New("response content type must not contain line breaks")
+	}
+
+	for name, value := range r.Headers {
+		if !validHeaderName(name) {
+			return fmt.Errorf("response header name %q is invalid", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("response header %q must not contain line breaks", name)
+		}
+		switch name {
+		case "Content-Length", "Transfer-Encoding", "Connection", "Content-Type":
+			return fmt.Errorf("response header %q cannot be configured", name)
+		}
+	}
+
+	if len(r.Body) > maxResponseBodyBytes {
+		return fmt.Errorf("response body must not exceed %d bytes", maxResponseBodyBytes)
+	}
+
+	if !bodyAllowedForStatus(r.StatusCode) && (r.Body != "" || r.ContentType != "") {
+		return fmt.Errorf("response with status code %d must not have a body or content type", r.StatusCode)
+	}
+
+	return nil
+}
+
+// EffectiveStatusCode returns the configured status code or 200 when unset.
+func (r *WebhookResponse) EffectiveStatusCode() int {
+	if r == nil || r.StatusCode == 0 {
+		return http.StatusOK
+	}
+
+	return r.StatusCode
+}
+
+// HeaderNames returns configured header names in sorted order.
+func (r *WebhookResponse) HeaderNames() []string {
+	names := make([]string, 0, len(r.Headers))
+	for name := range r.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// bodyAllowedForStatus mirrors net/http, which refuses to write a body for
+// 1xx, 204, and 304 responses.
+func bodyAllowedForStatus(statusCode int) bool {
+	switch {
+	case statusCode >= 100 && statusCode < 200:
+		return false
+	case statusCode == http.StatusNoContent, statusCode == http.StatusNotModified:
+		return false
+	}
+
+	return true
+}
+
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for _, char := range name {
+		if char <= ' ' || char >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, char) {
+			return false
+		}
+	}
+
+	return true
+}
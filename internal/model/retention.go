@@ -0,0 +1,68 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultMaxMessages is how many captured messages a webhook keeps by default.
+const DefaultMaxMessages = 100
+
+// RetentionLimits are the server-wide upper bounds for per-webhook retention.
+// Zero values fall back to the defaults.
+type RetentionLimits struct {
+	MaxTTL      time.Duration
+	MaxMessages int
+}
+
+// DefaultRetentionLimits returns limits that only allow the default retention.
+func DefaultRetentionLimits() RetentionLimits {
+	return RetentionLimits{MaxTTL: DefaultWebhookTTL, MaxMessages: DefaultMaxMessages}
+}
+
+// DefaultTTL returns the TTL used when a webhook does not request one.
+func (l RetentionLimits) DefaultTTL() time.Duration {
+	return min(DefaultWebhookTTL, l.TTLLimit())
+}
+
+// DefaultMaxMessages returns the message cap used when a webhook does not request one.
+func (l RetentionLimits) DefaultMaxMessages() int {
+	return min(DefaultMaxMessages, l.MessageLimit())
+}
+
+// TTL validates a requested TTL in seconds against the limits.
+func (l RetentionLimits) TTL(ttlSeconds int) (time.Duration, error) {
+	maxTTL := l.TTLLimit()
+	ttl := time.Duration(ttlSeconds) * time.Second
+	if ttlSeconds <= 0 || ttl > maxTTL {
+		return 0, fmt.Errorf("ttlSeconds must be between 1 and %d", int(maxTTL.Seconds()))
+	}
+
+	return ttl, nil
+}
+
+// MessageCap validates a requested message cap against the limits.
+func (l RetentionLimits) MessageCap(maxMessages int) (int, error) {
+	limit := l.MessageLimit()
+	if maxMessages <= 0 || maxMessages > limit {
+		return 0, fmt.Errorf("maxMessages must be between 1 and %d", limit)
+	}
+
+	return maxMessages, nil
+}
+
+// TTLLimit returns the longest TTL a webhook may request.
+func (l RetentionLimits) TTLLimit() time.Duration {
+	if l.MaxTTL <= 0 {
+		return DefaultWebhookTTL
+	}
+	return l.MaxTTL
+}
+
+// MessageLimit returns the largest message cap a webhook may request.
+func (l RetentionLimits) MessageLimit() int {
+	if l.MaxMessages <= 0 {
+		return DefaultMaxMessages
+	}
+	return l.MaxMessages
+}
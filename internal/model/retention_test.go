@@ -0,0 +1,47 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookApplyRetentionUsesDefaults(t *testing.T) {
+	now := time.Date(2026, 3, 21, 12, 0, 0, 0, time.UTC)
+	webhook := model.NewWebhook("", "", "", "", "", "")
+
+	require.NoError(t, webhook.ApplyRetention(0, 0, model.RetentionLimits{MaxTTL: 7 * 24 * time.Hour, MaxMessages: 5000}, now))
+	assert.Equal(t, now.Add(model.DefaultWebhookTTL), webhook.ExpiresAt)
+	assert.Equal(t, model.DefaultMaxMessages, webhook.MaxMessages)
+
+	require.NoError(t, webhook.ApplyRetention(0, 0, model.RetentionLimits{MaxTTL: time.Hour, MaxMessages: 10}, now))
+	assert.Equal(t, now.Add(time.Hour), webhook.ExpiresAt)
+	assert.Equal(t, 10, webhook.MaxMessages)
+}
+
+func TestWebhookApplyRetentionHonorsRequestWithinLimits(t *testing.T) {
+	now := time.Date(2026, 3, 21, 12, 0, 0, 0, time.UTC)
+	limits := model.RetentionLimits{MaxTTL: 7 * 24 * time.Hour, MaxMessages: 5000}
+	webhook := model.NewWebhook("", "", "", "", "", "")
+
+	require.NoError(t, webhook.ApplyRetention(int((7*24*time.Hour).Seconds()), 5000, limits, now))
+	assert.Equal(t, now.Add(7*24*time.Hour), webhook.ExpiresAt)
+	assert.Equal(t, 5000, webhook.MaxMessages)
+}
+
+func TestWebhookApplyRetentionRejectsRequestBeyondLimits(t *testing.T) {
+	limits := model.DefaultRetentionLimits()
+	webhook := model.NewWebhook("", "", "", "", "", "")
+
+	err := webhook.ApplyRetention(int(model.DefaultWebhookTTL.Seconds())+1, 0, limits, time.Now())
+	assert.EqualError(t, err, "ttlSeconds must be between 1 and 172800")
+
+	err = webhook.ApplyRetention(0, model.DefaultMaxMessages+1, limits, time.Now())
+	assert.EqualError(t, err, "maxMessages must be between 1 and 100")
+
+	assert.Error(t, webhook.ApplyRetention(-1, 0, limits, time.Now()))
+	assert.Error(t, webhook.ApplyRetention(0, -1, limits, time.Now()))
+}
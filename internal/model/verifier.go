@@ -0,0 +1,285 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Verifier names a provider whose signature scheme is verified exactly as the
+// provider documents it. The provider's signing secret is the HMAC secret.
+type Verifier string
+
+const (
+	// VerifierGitHub verifies X-Hub-Signature-256: sha256=<hex HMAC-SHA256 of the body>.
+	VerifierGitHub Verifier = "github"
+	// VerifierStripe verifies Stripe-Signature: t=<unix>,v1=<hex HMAC-SHA256 of "t.body">.
+	VerifierStripe Verifier = "stripe"
+	// VerifierSlack verifies X-Slack-Signature: v0=<hex HMAC-SHA256 of "v0:timestamp:body">.
+	VerifierSlack Verifier = "slack"
+	// VerifierShopify verifies X-Shopify-Hmac-Sha256: <base64 HMAC-SHA256 of the body>.
+	VerifierShopify Verifier = "shopify"
+	// VerifierTwilio verifies X-Twilio-Signature: <base64 HMAC-SHA1 of the URL and form parameters>.
+	VerifierTwilio Verifier = "twilio"
+)
+
+// SignatureTolerance bounds how far the timestamp of a Stripe or Slack
+// signature may be from the receiver clock.
+const SignatureTolerance = 5 * time.Minute
+
+const slackTimestampHeader = "X-Slack-Request-Timestamp"
+
+type verifierPreset struct {
+	name   string
+	header string
+	verify func(r *http.Request, body []byte, secret string, now time.Time) string
+}
+
+var verifierPresets = map[Verifier]verifierPreset{
+	VerifierGitHub:  {name: "GitHub", header: "X-Hub-Signature-256", verify: verifyGitHub},
+	VerifierStripe:  {name: "Stripe", header: "Stripe-Signature", verify: verifyStripe},
+	VerifierSlack:   {name: "Slack", header: "X-Slack-Signature", verify: verifySlack},
+	VerifierShopify: {name: "Shopify", header: "X-Shopify-Hmac-Sha256", verify: verifyShopify},
+	VerifierTwilio:  {name: "Twilio", header: "X-Twilio-Signature", verify: verifyTwilio},
+}
+
+// NewVerifier normalizes a configured verifier name.
+func NewVerifier(name Verifier) Verifier {
+	return Verifier(strings.ToLower(strings.TrimSpace(string(name))))
+}
+
+// Validate checks that the verifier is a known provider.
+func (v Verifier) Validate() error {
+	if _, ok := verifierPresets[v]; !ok {
+		return fmt.Errorf("verifier must be one of %s, %s, %s, %s or %s", VerifierGitHub, VerifierStripe, VerifierSlack, VerifierShopify, VerifierTwilio)
+	}
+
+	return nil
+}
+
+// Header returns the header the provider sends its signature in, or an empty
+// string for unknown verifiers.
+func (v Verifier) Header() string {
+	return verifierPresets[v].header
+}
+
+// Description names the provider signature, e.g. "Stripe signature".
+func (v Verifier) Description() string {
+	return verifierPresets[v].name + " signature"
+}
+
+// VerificationFailure verifies the request signature at the given time and
+// returns a human-readable failure or an empty string on success.
+func (v Verifier) VerificationFailure(r *http.Request, body []byte, secret string, now time.Time) string {
+	preset, ok := verifierPresets[v]
+	if !ok {
+		return fmt.Sprintf("Unknown verifier %q", v)
+	}
+	if r.Header.Get(preset.header) == "" {
+		return fmt.Sprintf("Missing %s signature header %q", preset.name, preset.header)
+	}
+
+	return preset.verify(r, body, secret, now)
+}
+
+func verifyGitHub(r *http.Request, body []byte, secret string, _ time.Time) string {
+	digest, ok := strings.CutPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256=")
+	if !ok {
+		return `GitHub signature in "X-Hub-Signature-256" must start with "sha256="`
+	}
+	if !hexMACEqual(digest, macSum(sha256.New, secret, body)) {
+		return `GitHub signature in "X-Hub-Signature-256" did not match`
+	}
+
+	return ""
+}
+
+func verifyStripe(r *http.Request, body []byte, secret string, now time.Time) string {
+	var timestamp string
+	var signatures []string
+	for _, item := range strings.Split(r.Header.Get("Stripe-Signature"), ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(item), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	if timestamp == "" {
+		return `Stripe-Signature header has no timestamp`
+	}
+	signedAt, failure := parseUnixTimestamp("Stripe-Signature", timestamp)
+	if failure != "" {
+		return failure
+	}
+	if len(signatures) == 0 {
+		return `Stripe-Signature header has no v1 signature`
+	}
+
+	expected := macSum(sha256.New, secret, []byte(timestamp), []byte("."), body)
+	matched := false
+	for _, signature := range signatures {
+		if hexMACEqual(signature, expected) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return `No v1 signature in "Stripe-Signature" matched`
+	}
+
+	return timestampToleranceFailure("Stripe-Signature", signedAt, now, SignatureTolerance)
+}
+
+func verifySlack(r *http.Request, body []byte, secret string, now time.Time) string {
+	timestamp := r.Header.Get(slackTimestampHeader)
+	if timestamp == "" {
+		return fmt.Sprintf("Missing Slack timestamp header %q", slackTimestampHeader)
+	}
+	signedAt, failure := parseUnixTimestamp(slackTimestampHeader, timestamp)
+	if failure != "" {
+		return failure
+	}
+
+	digest, ok := strings.CutPrefix(r.Header.Get("X-Slack-Signature"), "v0=")
+	if !ok {
+		return `Slack signature in "X-Slack-Signature" must start with "v0="`
+	}
+	if !hexMACEqual(digest, macSum(sha256.New, secret, []byte("v0:"+timestamp+":"), body)) {
+		return `Slack signature in "X-Slack-Signature" did not match`
+	}
+
+	return timestampToleranceFailure(slackTimestampHeader, signedAt, now, SignatureTolerance)
+}
+
+func verifyShopify(r *http.Request, body []byte, secret string, _ time.Time) string {
+	provided, err := base64.StdEncoding.DecodeString(strings.TrimSpace(r.Header.Get("X-Shopify-Hmac-Sha256")))
+	if err != nil {
+		return `Shopify signature in "X-Shopify-Hmac-Sha256" is not valid base64`
+	}
+	if !hmac.Equal(provided, macSum(sha256.New, secret, body)) {
+		return `Shopify signature in "X-Shopify-Hmac-Sha256" did not match`
+	}
+
+	return ""
+}
+
+// verifyTwilio signs the public URL followed by the sorted form parameters.
+// JSON requests instead carry a bodySHA256 query parameter and sign the URL
+// alone. The URL is reconstructed from the Host header with and without the
+// default port and for both schemes, since TLS usually ends at a proxy.
+func verifyTwilio(r *http.Request, body []byte, secret string, _ time.Time) string {
+	provided, err := base64.StdEncoding.DecodeString(strings.TrimSpace(r.Header.Get("X-Twilio-Signature")))
+	if err != nil {
+		return `Twilio signature in "X-Twilio-Signature" is not valid base64`
+	}
+
+	var params string
+	if bodyHash := r.URL.Query().Get("bodySHA256"); bodyHash != "" {
+		digest := sha256.Sum256(body)
+		if !strings.EqualFold(bodyHash, hex.EncodeToString(digest[:])) {
+			return "Twilio bodySHA256 query parameter did not match the body"
+		}
+	} else {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return "Twilio form parameters could not be parsed"
+		}
+		params = sortedTwilioParams(form)
+	}
+
+	for _, candidate := range twilioURLCandidates(r) {
+		if hmac.Equal(provided, macSum(sha1.New, secret, []byte(candidate+params))) {
+			return ""
+		}
+	}
+
+	return `Twilio signature in "X-Twilio-Signature" did not match`
+}
+
+func sortedTwilioParams(form url.Values) string {
+	names := make([]string, 0, len(form))
+	for name := range form {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var params strings.Builder
+	for _, name := range names {
+		values := append([]string(nil), form[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			params.WriteString(name)
+			params.WriteString(value)
+		}
+	}
+
+	return params.String()
+}
+
+func twilioURLCandidates(r *http.Request) []string {
+	hostname := r.Host
+	if host, _, err := net.SplitHostPort(r.Host); err == nil {
+		hostname = host
+	}
+
+	var candidates []string
+	for _, scheme := range []string{"https", "http"} {
+		defaultPort := "443"
+		if scheme == "http" {
+			defaultPort = "80"
+		}
+		for _, host := range []string{r.Host, hostname, net.JoinHostPort(hostname, defaultPort)} {
+			candidates = append(candidates, scheme+"://"+host+r.URL.RequestURI())
+		}
+	}
+
+	return candidates
+}
+
+func macSum(newHash func() hash.Hash, secret string, parts ...[]byte) []byte {
+	mac := hmac.New(newHash, []byte(secret))
+	for _, part := range parts {
+		mac.Write(part)
+	}
+	return mac.Sum(nil)
+}
+
+func hexMACEqual(signature string, expected []byte) bool {
+	provided, err := hex.DecodeString(strings.ToLower(strings.TrimSpace(signature)))
+	return err == nil && hmac.Equal(provided, expected)
+}
+
+func parseUnixTimestamp(source string, value string) (time.Time, string) {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Sprintf("%s timestamp %q is not a Unix timestamp", source, value)
+	}
+
+	return time.Unix(seconds, 0), ""
+}
+
+func timestampToleranceFailure(source string, signedAt time.Time, now time.Time, tolerance time.Duration) string {
+	skew := now.Sub(signedAt)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > tolerance {
+		return fmt.Sprintf("%s timestamp is %s away from the receiver clock, beyond the %s tolerance", source, skew.Truncate(time.Second), tolerance)
+	}
+
+	return ""
+}
@@ -0,0 +1,173 @@
+package model_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Example from GitHub's "Validating webhook deliveries" documentation.
+const (
+	githubSecret    = "It's a Secret to Everybody"
+	githubBody      = "Hello, World!"
+	githubSignature = "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"
+)
+
+// Secret and payload of the stripe-go webhook tests, signed at a fixed time.
+const (
+	stripeSecret    = "whsec_test_secret"
+	stripeBody      = "{\n  \"id\": \"evt_test_webhook\",\n  \"object\": \"event\"\n}"
+	stripeTimestamp = 1492774577
+	stripeSignature = "t=1492774577,v1=c2f890decbc5ede7c5060bb9a6d31e0626a4342bacb9aeae2adb1bcea72f9812"
+)
+
+// Shopify publishes no fixed vector; this one was computed with Python's hmac module.
+const (
+	shopifySecret    = "hush"
+	shopifyBody      = `{"id":820982911946154508,"email":"jon@example.com"}`
+	shopifySignature = "D0UMXmhjwBRi4y66TmhJrDlhQABD7zI2fm3p6/QLMo8="
+)
+
+// Examples from the twilio-go request validator tests.
+const (
+	twilioAuthToken     = "12345"
+	twilioURL           = "https://mycompany.com/myapp.php?foo=1&bar=2"
+	twilioFormBody      = "CallSid=CA1234567890ABCDE&Caller=%2B14158675309&Digits=1234&From=%2B14158675309&To=%2B18005551212&ReasonConferenceEnded=test&Reason=Participant"
+	twilioFormSignature = "vOEb5UThFn24KEfnOFLQY2AE5FY="
+	twilioJSONBody      = `{"property": "value", "boolean": true}`
+	twilioJSONBodyHash  = "0a1ff7634d9ab3b95db5c9a2dfe9416e41502b283a80c7cf19632632f96e6620"
+	twilioJSONSignature = "a9nBmqA0ju/hNViExpshrM61xv4="
+)
+
+func signedRequest(t *testing.T, target string, headers map[string]string) *http.Request {
+	t.Helper()
+	request, err := http.NewRequest(http.MethodPost, target, nil)
+	require.NoError(t, err)
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+
+	return request
+}
+
+func TestNewWebhookFromInputDefaultsVerifierHeader(t *testing.T) {
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{Verifier: " Stripe ", HMACSecret: stripeSecret})
+
+	require.NoError(t, webhook.Validate())
+	assert.Equal(t, model.VerifierStripe, webhook.Verifier)
+	assert.Equal(t, "Stripe-Signature", webhook.HMACHeader)
+	assert.True(t, webhook.HasHMAC())
+}
+
+func TestValidateRejectsInvalidVerifierConfiguration(t *testing.T) {
+	invalidInputs := map[string]*model.WebhookInput{
+		"verifier must be one of github, stripe, slack, shopify or twilio": {Verifier: "paypal", HMACSecret: "secret"},
+		"verifier github requires an hmac secret":                          {Verifier: model.VerifierGitHub},
+		`verifier github reads the signature from "X-Hub-Signature-256", not "X-Signature"`: {
+			Verifier: model.VerifierGitHub, HMACHeader: "X-Signature", HMACSecret: "secret",
+		},
+		"hmac options cannot be combined with a verifier": {
+			Verifier: model.VerifierGitHub, HMACSecret: "secret", HMACOptions: &model.HMACOptions{Algorithm: model.HMACSHA1},
+		},
+	}
+
+	for expected, input := range invalidInputs {
+		assert.EqualError(t, model.NewWebhookFromInput(input).Validate(), expected)
+	}
+}
+
+func TestGitHubVerifier(t *testing.T) {
+	request := signedRequest(t, "/hooks/id", map[string]string{"X-Hub-Signature-256": githubSignature})
+	assert.Empty(t, model.VerifierGitHub.VerificationFailure(request, []byte(githubBody), githubSecret, time.Now()))
+
+	request.Header.Set("X-Hub-Signature-256", strings.TrimPrefix(githubSignature, "sha256="))
+	assert.Equal(t, `GitHub signature in "X-Hub-Signature-256" must start with "sha256="`, model.VerifierGitHub.VerificationFailure(request, []byte(githubBody), githubSecret, time.Now()))
+
+	request.Header.Set("X-Hub-Signature-256", githubSignature)
+	assert.Equal(t, `GitHub signature in "X-Hub-Signature-256" did not match`, model.VerifierGitHub.VerificationFailure(request, []byte("Hello, World?"), githubSecret, time.Now()))
+}
+
+func TestStripeVerifier(t *testing.T) {
+	signedAt := time.Unix(stripeTimestamp, 0)
+	request := signedRequest(t, "/hooks/id", map[string]string{"Stripe-Signature": stripeSignature})
+	assert.Empty(t, model.VerifierStripe.VerificationFailure(request, []byte(stripeBody), stripeSecret, signedAt.Add(time.Minute)))
+
+	rolled := signedRequest(t, "/hooks/id", map[string]string{"Stripe-Signature": "t=1492774577,v1=deadbeef," + strings.TrimPrefix(stripeSignature, "t=1492774577,")})
+	assert.Empty(t, model.VerifierStripe.VerificationFailure(rolled, []byte(stripeBody), stripeSecret, signedAt))
+
+	failures := map[string]string{
+		"v1=abc":              "Stripe-Signature header has no timestamp",
+		"t=yesterday,v1=abc":  `Stripe-Signature timestamp "yesterday" is not a Unix timestamp`,
+		"t=1492774577,v0=abc": "Stripe-Signature header has no v1 signature",
+		"t=1492774578,v1=" + strings.TrimPrefix(stripeSignature, "t=1492774577,v1="): `No v1 signature in "Stripe-Signature" matched`,
+	}
+	for header, expected := range failures {
+		request.Header.Set("Stripe-Signature", header)
+		assert.Equal(t, expected, model.VerifierStripe.VerificationFailure(request, []byte(stripeBody), stripeSecret, signedAt))
+	}
+
+	request.Header.Set("Stripe-Signature", stripeSignature)
+	assert.Equal(t,
+		"Stripe-Signature timestamp is 10m0s away from the receiver clock, beyond the 5m0s tolerance",
+		model.VerifierStripe.VerificationFailure(request, []byte(stripeBody), stripeSecret, signedAt.Add(10*time.Minute)),
+	)
+}
+
+func TestSlackVerifier(t *testing.T) {
+	signedAt := time.Unix(1531420618, 0)
+	request := signedRequest(t, "/hooks/id", map[string]string{
+		"X-Slack-Signature":         slackSignature,
+		"X-Slack-Request-Timestamp": slackTimestamp,
+	})
+	assert.Empty(t, model.VerifierSlack.VerificationFailure(request, []byte(slackBody), slackSigningSecret, signedAt))
+
+	assert.Equal(t,
+		"X-Slack-Request-Timestamp timestamp is 1h0m0s away from the receiver clock, beyond the 5m0s tolerance",
+		model.VerifierSlack.VerificationFailure(request, []byte(slackBody), slackSigningSecret, signedAt.Add(-time.Hour)),
+	)
+
+	request.Header.Set("X-Slack-Signature", strings.TrimPrefix(slackSignature, "v0="))
+	assert.Equal(t, `Slack signature in "X-Slack-Signature" must start with "v0="`, model.VerifierSlack.VerificationFailure(request, []byte(slackBody), slackSigningSecret, signedAt))
+
+	request.Header.Set("X-Slack-Signature", slackSignature)
+	request.Header.Del("X-Slack-Request-Timestamp")
+	assert.Equal(t, `Missing Slack timestamp header "X-Slack-Request-Timestamp"`, model.VerifierSlack.VerificationFailure(request, []byte(slackBody), slackSigningSecret, signedAt))
+}
+
+func TestShopifyVerifier(t *testing.T) {
+	request := signedRequest(t, "/hooks/id", map[string]string{"X-Shopify-Hmac-Sha256": shopifySignature})
+	assert.Empty(t, model.VerifierShopify.VerificationFailure(request, []byte(shopifyBody), shopifySecret, time.Now()))
+	assert.Equal(t, `Shopify signature in "X-Shopify-Hmac-Sha256" did not match`, model.VerifierShopify.VerificationFailure(request, []byte(shopifyBody), "other", time.Now()))
+
+	request.Header.Set("X-Shopify-Hmac-Sha256", "not base64!")
+	assert.Equal(t, `Shopify signature in "X-Shopify-Hmac-Sha256" is not valid base64`, model.VerifierShopify.VerificationFailure(request, []byte(shopifyBody), shopifySecret, time.Now()))
+}
+
+func TestTwilioVerifier(t *testing.T) {
+	request := signedRequest(t, twilioURL, map[string]string{"X-Twilio-Signature": twilioFormSignature})
+	assert.Empty(t, model.VerifierTwilio.VerificationFailure(request, []byte(twilioFormBody), twilioAuthToken, time.Now()))
+
+	behindProxy := signedRequest(t, "http://mycompany.com:8080/myapp.php?foo=1&bar=2", map[string]string{"X-Twilio-Signature": twilioFormSignature})
+	assert.Empty(t, model.VerifierTwilio.VerificationFailure(behindProxy, []byte(twilioFormBody), twilioAuthToken, time.Now()))
+
+	assert.Equal(t, `Twilio signature in "X-Twilio-Signature" did not match`, model.VerifierTwilio.VerificationFailure(request, []byte(twilioFormBody+"&Extra=1"), twilioAuthToken, time.Now()))
+
+	jsonRequest := signedRequest(t, twilioURL+"&bodySHA256="+twilioJSONBodyHash, map[string]string{"X-Twilio-Signature": twilioJSONSignature})
+	assert.Empty(t, model.VerifierTwilio.VerificationFailure(jsonRequest, []byte(twilioJSONBody), twilioAuthToken, time.Now()))
+	assert.Equal(t, "Twilio bodySHA256 query parameter did not match the body", model.VerifierTwilio.VerificationFailure(jsonRequest, []byte(`{}`), twilioAuthToken, time.Now()))
+}
+
+func TestAuthorizationFailureUsesVerifier(t *testing.T) {
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{Verifier: model.VerifierGitHub, HMACSecret: githubSecret})
+	request := signedRequest(t, "/hooks/id", nil)
+
+	assert.Equal(t, `Missing GitHub signature header "X-Hub-Signature-256"`, webhook.AuthorizationFailure(request, []byte(githubBody)))
+
+	request.Header.Set("X-Hub-Signature-256", githubSignature)
+	assert.Empty(t, webhook.AuthorizationFailure(request, []byte(githubBody)))
+}
@@ -1,9 +1,8 @@
 package model
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
+	"crypto/rand"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"net/http"
@@ -13,26 +12,57 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// DefaultWebhookTTL is how long a webhook is retained after creation.
+const DefaultWebhookTTL = 48 * time.Hour
+
+const managementSecretBytes = 32
+
 // WebhookInput is used for unmarshaling user input.
 type WebhookInput struct {
-	Username   string `json:"username,omitempty"`
-	Password   string `json:"password,omitempty"`
-	TokenName  string `json:"tokenName,omitempty"`
-	TokenValue string `json:"tokenValue,omitempty"`
-	HMACHeader string `json:"hmacHeader,omitempty"`
-	HMACSecret string `json:"hmacSecret,omitempty"`
+	Username           string              `json:"username,omitempty"`
+	Password           string              `json:"password,omitempty"`
+	TokenName          string              `json:"tokenName,omitempty"`
+	TokenValue         string              `json:"tokenValue,omitempty"`
+	HMACHeader         string              `json:"hmacHeader,omitempty"`
+	HMACSecret         string              `json:"hmacSecret,omitempty"`
+	HMACOptions        *HMACOptions        `json:"hmacOptions,omitempty"`
+	Verifier           Verifier            `json:"verifier,omitempty"`
+	PublicKeySignature *PublicKeySignature `json:"publicKeySignature,omitempty"`
+	JWT                *JWTValidation      `json:"jwt,omitempty"`
+	JWTSecret          string              `json:"jwtSecret,omitempty"`
+	ReplayProtection   *ReplayProtection   `json:"replayProtection,omitempty"`
+	MutualTLS          *MutualTLS          `json:"mutualTls,omitempty"`
+	AllowedCIDRs       []string            `json:"allowedCidrs,omitempty"`
+	Redaction          *Redaction          `json:"redaction,omitempty"`
+	Response           *WebhookResponse    `json:"response,omitempty"`
+	ForwardURL         string              `json:"forwardUrl,omitempty"`
+	TTLSeconds         int                 `json:"ttlSeconds,omitempty"`
+	MaxMessages        int                 `json:"maxMessages,omitempty"`
 }
 
 // Webhook is the validated runtime representation of a configured receiver.
 type Webhook struct {
-	Username   string `json:"username,omitempty"`
-	password   string
-	TokenName  string `json:"tokenName,omitempty"`
-	tokenValue string
-	HMACHeader string `json:"hmacHeader,omitempty"`
-	hmacSecret string
-	ID         string    `json:"id"`
-	ExpiresAt  time.Time `json:"expiresAt"`
+	Username             string `json:"username,omitempty"`
+	password             string
+	TokenName            string `json:"tokenName,omitempty"`
+	tokenValue           string
+	HMACHeader           string `json:"hmacHeader,omitempty"`
+	hmacSecret           string
+	jwtSecret            string
+	managementSecretHash string
+	HMACOptions          *HMACOptions        `json:"hmacOptions,omitempty"`
+	Verifier             Verifier            `json:"verifier,omitempty"`
+	PublicKeySignature   *PublicKeySignature `json:"publicKeySignature,omitempty"`
+	JWT                  *JWTValidation      `json:"jwt,omitempty"`
+	ReplayProtection     *ReplayProtection   `json:"replayProtection,omitempty"`
+	MutualTLS            *MutualTLS          `json:"mutualTls,omitempty"`
+	AllowedCIDRs         []string            `json:"allowedCidrs,omitempty"`
+	Redaction            *Redaction          `json:"redaction,omitempty"`
+	Response             *WebhookResponse    `json:"response,omitempty"`
+	ForwardURL           string              `json:"forwardUrl,omitempty"`
+	MaxMessages          int                 `json:"maxMessages"`
+	ID                   string              `json:"id"`
+	ExpiresAt            time.Time           `json:"expiresAt"`
 }
 
 // NewWebhookFromInput creates Webhook instance based on input
@@ -41,7 +71,7 @@ func NewWebhookFromInput(webhookInput *WebhookInput) *Webhook {
 		webhookInput = &WebhookInput{}
 	}
 
-	return NewWebhook(
+	webhook := NewWebhook(
 		webhookInput.Username,
 		webhookInput.Password,
 		webhookInput.TokenName,
@@ -49,6 +79,47 @@ func NewWebhookFromInput(webhookInput *WebhookInput) *Webhook {
 		webhookInput.HMACHeader,
 		webhookInput.HMACSecret,
 	)
+	webhook.HMACOptions = NewHMACOptions(webhookInput.HMACOptions)
+	webhook.Verifier = NewVerifier(webhookInput.Verifier)
+	webhook.applyVerifierHeader()
+	webhook.PublicKeySignature = NewPublicKeySignature(webhookInput.PublicKeySignature)
+	webhook.JWT = NewJWTValidation(webhookInput.JWT)
+	webhook.jwtSecret = webhookInput.JWTSecret
+	webhook.applyJWTSecret()
+	webhook.ReplayProtection = NewReplayProtection(webhookInput.ReplayProtection)
+	webhook.MutualTLS = NewMutualTLS(webhookInput.MutualTLS)
+	webhook.AllowedCIDRs = NewAllowedCIDRs(webhookInput.AllowedCIDRs)
+	webhook.Redaction = NewRedaction(webhookInput.Redaction)
+	webhook.Response = NewWebhookResponse(webhookInput.Response)
+	webhook.ForwardURL = strings.TrimSpace(webhookInput.ForwardURL)
+
+	return webhook
+}
+
+// ApplyRetention sets the expiry and message cap requested on creation. Zero
+// values select the defaults allowed by the limits.
+func (w *Webhook) ApplyRetention(ttlSeconds int, maxMessages int, limits RetentionLimits, now time.Time) error {
+	ttl := limits.DefaultTTL()
+	if ttlSeconds != 0 {
+		requestedTTL, err := limits.TTL(ttlSeconds)
+		if err != nil {
+			return err
+		}
+		ttl = requestedTTL
+	}
+
+	messageCap := limits.DefaultMaxMessages()
+	if maxMessages != 0 {
+		requestedCap, err := limits.MessageCap(maxMessages)
+		if err != nil {
+			return err
+		}
+		messageCap = requestedCap
+	}
+
+	w.ExpiresAt = now.UTC().Add(ttl)
+	w.MaxMessages = messageCap
+	return nil
 }
 
 // NewWebhook creates webhook based on input
@@ -98,13 +169,105 @@ func (w *Webhook) Validate() error {
 		return errors.New("token name and value must be both set or both empty")
 	}
 
+	if w.JWT != nil {
+		if w.jwtSecret == "" && len(w.JWT.JWKS) == 0 {
+			return errors.New("jwt validation requires a jwt secret or a jwks document")
+		}
+		if w.jwtSecret != "" && len(w.JWT.JWKS) > 0 {
+			return errors.New("jwt secret and jwks cannot both be set")
+		}
+		if w.HasBasicAuth() {
+			return errors.New("jwt validation cannot be combined with basic auth, which also uses the Authorization header")
+		}
+		if strings.EqualFold(w.TokenName, "Authorization") {
+			return errors.New("jwt validation cannot be combined with an Authorization header token")
+		}
+		if err := w.JWT.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if w.Verifier != "" {
+		if err := w.Verifier.Validate(); err != nil {
+			return err
+		}
+		if w.hmacSecret == "" {
+			return fmt.Errorf("verifier %s requires an hmac secret", w.Verifier)
+		}
+		if !strings.EqualFold(w.HMACHeader, w.Verifier.Header()) {
+			return fmt.Errorf("verifier %s reads the signature from %q, not %q", w.Verifier, w.Verifier.Header(), w.HMACHeader)
+		}
+		if w.HMACOptions != nil {
+			return errors.New("hmac options cannot be combined with a verifier")
+		}
+	}
+
 	if (w.HMACHeader != "" && w.hmacSecret == "") || (w.HMACHeader == "" && w.hmacSecret != "") {
 		return errors.New("hmac header and secret must be both set or both empty")
 	}
 
+	if w.HMACOptions != nil {
+		if !w.HasHMAC() {
+			return errors.New("hmac options require an hmac header and secret")
+		}
+		if err := w.HMACOptions.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if w.PublicKeySignature != nil {
+		if err := w.PublicKeySignature.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if w.ReplayProtection != nil {
+		if err := w.ReplayProtection.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if w.MutualTLS != nil {
+		if err := w.MutualTLS.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := validateAllowedCIDRs(w.AllowedCIDRs); err != nil {
+		return err
+	}
+
+	if w.Redaction != nil {
+		if err := w.Redaction.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if w.Response != nil {
+		if err := w.Response.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if w.ForwardURL != "" {
+		target, err := ParseTargetURL(w.ForwardURL)
+		if err != nil {
+			return fmt.Errorf("forward url: %w", err)
+		}
+		if w.Response != nil {
+			return errors.New("forward url and custom response cannot both be set")
+		}
+		w.ForwardURL = target.String()
+	}
+
 	return nil
 }
 
+// HasForwarding indicates whether accepted deliveries are relayed to an upstream.
+func (w *Webhook) HasForwarding() bool {
+	return w.ForwardURL != ""
+}
+
 // HasBasicAuth indicates whether basic auth is configured for the webhook.
 func (w *Webhook) HasBasicAuth() bool {
 	return w.Username != "" && w.password != ""
@@ -115,6 +278,26 @@ func (w *Webhook) HasHeaderToken() bool {
 	return w.TokenName != "" && w.tokenValue != ""
 }
 
+// applyVerifierHeader defaults the HMAC header to the one the verifier's
+// provider sends.
+func (w *Webhook) applyVerifierHeader() {
+	if w.HMACHeader == "" {
+		w.HMACHeader = w.Verifier.Header()
+	}
+}
+
+// HasJWT indicates whether a JWT bearer token is required.
+func (w *Webhook) HasJWT() bool {
+	return w.JWT != nil
+}
+
+// applyJWTSecret enables JWT validation when only a JWT secret is configured.
+func (w *Webhook) applyJWTSecret() {
+	if w.jwtSecret != "" && w.JWT == nil {
+		w.JWT = &JWTValidation{}
+	}
+}
+
 // HasHMAC indicates whether HMAC validation is configured.
 func (w *Webhook) HasHMAC() bool {
 	return w.HMACHeader != "" && w.hmacSecret != ""
@@ -135,6 +318,53 @@ func (w *Webhook) HMACSecret() string {
 	return w.hmacSecret
 }
 
+// JWTSecret returns the configured HS256 JWT secret.
+func (w *Webhook) JWTSecret() string {
+	return w.jwtSecret
+}
+
+// SetJWTSecret restores a persisted JWT secret.
+func (w *Webhook) SetJWTSecret(secret string) {
+	w.jwtSecret = secret
+}
+
+// ManagementSecretHash returns the stored management-secret hash.
+func (w *Webhook) ManagementSecretHash() string {
+	return w.managementSecretHash
+}
+
+// SetManagementSecretHash restores a persisted management-secret hash.
+func (w *Webhook) SetManagementSecretHash(hash string) {
+	w.managementSecretHash = hash
+}
+
+// IssueManagementSecret generates a new management secret and keeps only its hash.
+// The returned secret cannot be recovered later.
+func (w *Webhook) IssueManagementSecret() (string, error) {
+	secretBytes := make([]byte, managementSecretBytes)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", err
+	}
+
+	secret := base64.RawURLEncoding.EncodeToString(secretBytes)
+	w.managementSecretHash = hashPassword(secret)
+	if w.managementSecretHash == "" {
+		return "", errors.New("could not hash management secret")
+	}
+
+	return secret, nil
+}
+
+// ValidateManagementSecret reports whether the secret matches the one issued at creation.
+// Webhooks created before management secrets existed cannot be managed.
+func (w *Webhook) ValidateManagementSecret(secret string) bool {
+	if w.managementSecretHash == "" || secret == "" {
+		return false
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(w.managementSecretHash), []byte(secret)) == nil
+}
+
 // ValidateAuthorization validates authorization based on provided request
 func (w *Webhook) ValidateAuthorization(r *http.Request, body []byte) bool {
 	return w.AuthorizationFailure(r, body) == ""
@@ -142,17 +372,39 @@ func (w *Webhook) ValidateAuthorization(r *http.Request, body []byte) bool {
 
 // AuthorizationFailure returns a human-readable authorization failure or an empty string on success.
 func (w *Webhook) AuthorizationFailure(r *http.Request, body []byte) string {
+	if w.MutualTLS != nil {
+		if failure := w.MutualTLS.failure(r, time.Now()); failure != "" {
+			return failure
+		}
+	}
+
 	if failure := w.readAuthorizationFailure(r); failure != "" {
 		return failure
 	}
 
-	if w.HasHMAC() {
+	if w.HasHMAC() && w.Verifier != "" {
+		if failure := w.Verifier.VerificationFailure(r, body, w.hmacSecret, time.Now()); failure != "" {
+			return failure
+		}
+	} else if w.HasHMAC() {
 		signature := r.Header.Get(w.HMACHeader)
 		if signature == "" {
 			return fmt.Sprintf("Missing HMAC signature header %q", w.HMACHeader)
 		}
-		if !validateHMAC(body, w.hmacSecret, signature) {
-			return fmt.Sprintf("HMAC signature in %q did not match", w.HMACHeader)
+		if failure := w.HMACOptions.signatureFailure(r, body, w.hmacSecret, w.HMACHeader, signature); failure != "" {
+			return failure
+		}
+	}
+
+	if w.PublicKeySignature != nil {
+		if failure := w.PublicKeySignature.failure(r, body); failure != "" {
+			return failure
+		}
+	}
+
+	if w.ReplayProtection != nil {
+		if failure := w.ReplayProtection.failure(r, time.Now()); failure != "" {
+			return failure
 		}
 	}
 
@@ -190,17 +442,13 @@ func (w *Webhook) readAuthorizationFailure(r *http.Request) string {
 		}
 	}
 
-	return ""
-}
-
-func validateHMAC(body []byte, secret string, signature string) bool {
-	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write(body)
-	expectedSignature := hex.EncodeToString(mac.Sum(nil))
-	normalizedSignature := strings.TrimSpace(strings.ToLower(signature))
-	normalizedSignature = strings.TrimPrefix(normalizedSignature, "sha256=")
+	if w.HasJWT() {
+		if failure := w.JWT.failure(r, w.jwtSecret, time.Now()); failure != "" {
+			return failure
+		}
+	}
 
-	return hmac.Equal([]byte(normalizedSignature), []byte(expectedSignature))
+	return ""
 }
 
 func hashPassword(password string) string {
@@ -0,0 +1,119 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// WebhookPatch is used for unmarshaling partial updates of a webhook.
+// Omitted fields keep their current value; empty strings clear a setting.
+type WebhookPatch struct {
+	Username           *string             `json:"username,omitempty"`
+	Password           *string             `json:"password,omitempty"`
+	TokenName          *string             `json:"tokenName,omitempty"`
+	TokenValue         *string             `json:"tokenValue,omitempty"`
+	HMACHeader         *string             `json:"hmacHeader,omitempty"`
+	HMACSecret         *string             `json:"hmacSecret,omitempty"`
+	HMACOptions        *HMACOptions        `json:"hmacOptions,omitempty"`
+	Verifier           *Verifier           `json:"verifier,omitempty"`
+	PublicKeySignature *PublicKeySignature `json:"publicKeySignature,omitempty"`
+	JWT                *JWTValidation      `json:"jwt,omitempty"`
+	JWTSecret          *string             `json:"jwtSecret,omitempty"`
+	ReplayProtection   *ReplayProtection   `json:"replayProtection,omitempty"`
+	MutualTLS          *MutualTLS          `json:"mutualTls,omitempty"`
+	AllowedCIDRs       *[]string           `json:"allowedCidrs,omitempty"`
+	Redaction          *Redaction          `json:"redaction,omitempty"`
+	Response           *WebhookResponse    `json:"response,omitempty"`
+	ForwardURL         *string             `json:"forwardUrl,omitempty"`
+	TTLSeconds         *int                `json:"ttlSeconds,omitempty"`
+	MaxMessages        *int                `json:"maxMessages,omitempty"`
+}
+
+// Apply updates the webhook with the fields set in the patch. A TTL renews the
+// expiry relative to now. The caller must validate the webhook afterwards.
+func (p *WebhookPatch) Apply(webhook *Webhook, limits RetentionLimits, now time.Time) error {
+	if p.TTLSeconds != nil {
+		ttl, err := limits.TTL(*p.TTLSeconds)
+		if err != nil {
+			return err
+		}
+		webhook.ExpiresAt = now.UTC().Add(ttl)
+	}
+	if p.MaxMessages != nil {
+		messageCap, err := limits.MessageCap(*p.MaxMessages)
+		if err != nil {
+			return err
+		}
+		webhook.MaxMessages = messageCap
+	}
+
+	if p.Username != nil {
+		webhook.Username = strings.TrimSpace(*p.Username)
+	}
+	if p.Password != nil {
+		webhook.password = hashSecretOrEmpty(*p.Password)
+	}
+	if p.TokenName != nil {
+		webhook.TokenName = strings.TrimSpace(*p.TokenName)
+	}
+	if p.TokenValue != nil {
+		webhook.tokenValue = hashSecretOrEmpty(*p.TokenValue)
+	}
+	if p.HMACHeader != nil {
+		webhook.HMACHeader = strings.TrimSpace(*p.HMACHeader)
+	}
+	if p.HMACSecret != nil {
+		webhook.hmacSecret = *p.HMACSecret
+	}
+	if p.Verifier != nil {
+		webhook.Verifier = NewVerifier(*p.Verifier)
+	} else if webhook.hmacSecret == "" {
+		// A verifier only describes a configured signature; drop it with the secret.
+		webhook.Verifier = ""
+	}
+	webhook.applyVerifierHeader()
+	if p.HMACOptions != nil {
+		webhook.HMACOptions = NewHMACOptions(p.HMACOptions)
+	} else if !webhook.HasHMAC() {
+		// Options only describe a configured signature; drop them with it.
+		webhook.HMACOptions = nil
+	}
+	if p.PublicKeySignature != nil {
+		webhook.PublicKeySignature = NewPublicKeySignature(p.PublicKeySignature)
+	}
+	if p.JWT != nil {
+		webhook.JWT = NewJWTValidation(p.JWT)
+	}
+	if p.JWTSecret != nil {
+		webhook.jwtSecret = *p.JWTSecret
+	}
+	webhook.applyJWTSecret()
+	if p.ReplayProtection != nil {
+		webhook.ReplayProtection = NewReplayProtection(p.ReplayProtection)
+	}
+	if p.MutualTLS != nil {
+		webhook.MutualTLS = NewMutualTLS(p.MutualTLS)
+	}
+	if p.AllowedCIDRs != nil {
+		webhook.AllowedCIDRs = NewAllowedCIDRs(*p.AllowedCIDRs)
+	}
+	if p.Redaction != nil {
+		webhook.Redaction = NewRedaction(p.Redaction)
+	}
+	if p.Response != nil {
+		webhook.Response = NewWebhookResponse(p.Response)
+	}
+	if p.ForwardURL != nil {
+		webhook.ForwardURL = strings.TrimSpace(*p.ForwardURL)
+	}
+
+	return nil
+}
+
+func hashSecretOrEmpty(secret string) string {
+	if secret == "" {
+		return ""
+	}
+
+	return hashPassword(secret)
+}
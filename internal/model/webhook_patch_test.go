@@ -0,0 +1,114 @@
+package model_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stringPointer(value string) *string {
+	return &value
+}
+
+func intPointer(value int) *int {
+	return &value
+}
+
+func TestWebhookPatchApplyUpdatesOnlyProvidedFields(t *testing.T) {
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{
+		Username:   "alice",
+		Password:   "password",
+		HMACHeader: "X-Signature",
+		HMACSecret: "secret",
+	})
+	originalPasswordHash := webhook.PasswordHash()
+	now := time.Date(2026, 3, 21, 12, 0, 0, 0, time.UTC)
+
+	patch := &model.WebhookPatch{
+		HMACSecret: stringPointer("rotated"),
+		TokenName:  stringPointer(" X-Token "),
+		TokenValue: stringPointer("token"),
+		TTLSeconds: intPointer(3600),
+	}
+	require.NoError(t, patch.Apply(webhook, model.DefaultRetentionLimits(), now))
+	require.NoError(t, webhook.Validate())
+
+	assert.Equal(t, "alice", webhook.Username)
+	assert.Equal(t, originalPasswordHash, webhook.PasswordHash())
+	assert.Equal(t, "X-Signature", webhook.HMACHeader)
+	assert.Equal(t, "rotated", webhook.HMACSecret())
+	assert.Equal(t, "X-Token", webhook.TokenName)
+	assert.True(t, webhook.HasHeaderToken())
+	assert.Equal(t, now.Add(time.Hour), webhook.ExpiresAt)
+}
+
+func TestWebhookPatchApplyClearsSettings(t *testing.T) {
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{
+		Username:    "alice",
+		Password:    "password",
+		HMACHeader:  "X-Signature",
+		HMACSecret:  "secret",
+		HMACOptions: &model.HMACOptions{Algorithm: model.HMACSHA1},
+		Response:    &model.WebhookResponse{StatusCode: http.StatusAccepted},
+	})
+
+	patch := &model.WebhookPatch{
+		Username:   stringPointer(""),
+		Password:   stringPointer(""),
+		HMACHeader: stringPointer(""),
+		HMACSecret: stringPointer(""),
+		Response:   &model.WebhookResponse{},
+	}
+	require.NoError(t, patch.Apply(webhook, model.DefaultRetentionLimits(), time.Now()))
+	require.NoError(t, webhook.Validate())
+
+	assert.False(t, webhook.HasBasicAuth())
+	assert.Empty(t, webhook.PasswordHash())
+	assert.False(t, webhook.HasHMAC())
+	assert.Nil(t, webhook.HMACOptions)
+	assert.Nil(t, webhook.Response)
+}
+
+func TestWebhookPatchApplyVerifier(t *testing.T) {
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{HMACSecret: "secret", HMACHeader: "X-Hub-Signature-256"})
+
+	verifier := model.Verifier("GitHub")
+	require.NoError(t, (&model.WebhookPatch{Verifier: &verifier}).Apply(webhook, model.DefaultRetentionLimits(), time.Now()))
+	require.NoError(t, webhook.Validate())
+	assert.Equal(t, model.VerifierGitHub, webhook.Verifier)
+
+	patch := &model.WebhookPatch{HMACHeader: stringPointer(""), HMACSecret: stringPointer("")}
+	require.NoError(t, patch.Apply(webhook, model.DefaultRetentionLimits(), time.Now()))
+	require.NoError(t, webhook.Validate())
+	assert.Empty(t, webhook.Verifier)
+	assert.False(t, webhook.HasHMAC())
+}
+
+func TestWebhookPatchApplyRejectsTTLOutOfRange(t *testing.T) {
+	for _, ttlSeconds := range []int{0, -1, int(model.DefaultWebhookTTL.Seconds()) + 1} {
+		webhook := model.NewWebhook("", "", "", "", "", "")
+		patch := &model.WebhookPatch{TTLSeconds: intPointer(ttlSeconds)}
+		assert.Error(t, patch.Apply(webhook, model.DefaultRetentionLimits(), time.Now()))
+	}
+}
+
+func TestWebhookManagementSecret(t *testing.T) {
+	webhook := model.NewWebhook("", "", "", "", "", "")
+	assert.False(t, webhook.ValidateManagementSecret(""))
+
+	secret, err := webhook.IssueManagementSecret()
+	require.NoError(t, err)
+	assert.NotEmpty(t, secret)
+	assert.NotEqual(t, secret, webhook.ManagementSecretHash())
+	assert.True(t, webhook.ValidateManagementSecret(secret))
+	assert.False(t, webhook.ValidateManagementSecret(secret+"x"))
+	assert.False(t, webhook.ValidateManagementSecret(""))
+
+	otherSecret, err := model.NewWebhook("", "", "", "", "", "").IssueManagementSecret()
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, otherSecret)
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// KeyRotator is implemented by stores that encrypt data at rest.
+type KeyRotator interface {
+	// RotateEncryptionKeys re-encrypts every stored secret with the current
+	// encryption key and returns the number of rewritten values.
+	RotateEncryptionKeys() (int, error)
+}
+
+// RotateEncryptionKeys re-encrypts every stored HMAC and JWT secret and
+// encrypted message with the current encryption key and returns the number of
+// rewritten rows.
+func (s *SQLiteStore) RotateEncryptionKeys() (int, error) {
+	return rotateEncryptionKeys(s.db, s.cipher, func(query string) string { return query })
+}
+
+// RotateEncryptionKeys re-encrypts every stored HMAC and JWT secret and
+// encrypted message with the current encryption key and returns the number of
+// rewritten rows.
+func (s *PostgresStore) RotateEncryptionKeys() (int, error) {
+	return rotateEncryptionKeys(s.db, s.cipher, postgresPlaceholders)
+}
+
+// rotateEncryptionKeys rewrites all ciphertexts not yet sealed with the current
+// key in a single transaction, so a failed rotation leaves every row readable
+// with the previous keyring.
+func rotateEncryptionKeys(db *sql.DB, secretCipher *secretCipher, rebind func(string) string) (rotated int, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if rollbackErr := tx.Rollback(); err == nil && rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			err = rollbackErr
+		}
+	}()
+
+	rotatedSecrets := 0
+	for _, column := range []webhookSecretColumn{
+		{name: "hmac_secret_ciphertext", label: "HMAC secret"},
+		{name: "jwt_secret_ciphertext", label: "JWT secret"},
+	} {
+		rotated, err := rotateWebhookSecrets(tx, secretCipher, rebind, column)
+		if err != nil {
+			return 0, err
+		}
+		rotatedSecrets += rotated
+	}
+
+	rotatedMessages, err := rotateMessageKeys(tx, secretCipher, rebind)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return rotatedSecrets + rotatedMessages, nil
+}
+
+// webhookSecretColumn names a webhooks column holding an encrypted secret.
+type webhookSecretColumn struct {
+	name  string
+	label string
+}
+
+func rotateWebhookSecrets(tx *sql.Tx, secretCipher *secretCipher, rebind func(string) string, column webhookSecretColumn) (int, error) {
+	rows, err := tx.Query(`SELECT row_id, ` + column.name + ` FROM webhooks WHERE ` + column.name + ` IS NOT NULL`)
+	if err != nil {
+		return 0, err
+	}
+
+	type storedSecret struct {
+		rowID      int64
+		ciphertext []byte
+	}
+	var secrets []storedSecret
+	for rows.Next() {
+		var secret storedSecret
+		if err := rows.Scan(&secret.rowID, &secret.ciphertext); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+		if len(secret.ciphertext) > 0 && !secretCipher.Current(secret.ciphertext) {
+			secrets = append(secrets, secret)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return 0, err
+	}
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+
+	for _, secret := range secrets {
+		plaintext, err := secretCipher.Decrypt(secret.ciphertext)
+		if err != nil {
+			return 0, fmt.Errorf("decrypt %s of webhook row %d: %w", column.label, secret.rowID, err)
+		}
+		ciphertext, err := secretCipher.Encrypt(plaintext)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(rebind(`UPDATE webhooks SET `+column.name+` = ? WHERE row_id = ?`), ciphertext, secret.rowID); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(secrets), nil
+}
+
+func rotateMessageKeys(tx *sql.Tx, secretCipher *secretCipher, rebind func(string) string) (int, error) {
+	rows, err := tx.Query(`SELECT row_id, webhook_id, payload, headers_json FROM messages WHERE encrypted = 1`)
+	if err != nil {
+		return 0, err
+	}
+
+	type storedMessage struct {
+		rowID       int64
+		webhookID   string
+		payload     []byte
+		headersJSON string
+	}
+	var messages []storedMessage
+	for rows.Next() {
+		var message storedMessage
+		if err := rows.Scan(&message.rowID, &message.webhookID, &message.payload, &message.headersJSON); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+		if !secretCipher.Current(message.payload) {
+			messages = append(messages, message)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return 0, err
+	}
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+
+	for _, message := range messages {
+		payload, headersJSON, err := secretCipher.openMessage(message.webhookID, message.rowID, message.payload, message.headersJSON)
+		if err != nil {
+			return 0, fmt.Errorf("decrypt message row %d: %w", message.rowID, err)
+		}
+		sealedPayload, sealedHeaders, err := secretCipher.sealMessage(message.webhookID, message.rowID, payload, headersJSON)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(rebind(`UPDATE messages SET payload = ?, headers_json = ? WHERE row_id = ?`), sealedPayload, sealedHeaders, message.rowID); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(messages), nil
+}
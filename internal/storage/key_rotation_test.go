@@ -0,0 +1,113 @@
+package storage_test
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/achawki/webhook-receiver/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const rotatedEncryptionKey = "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="
+
+func TestSQLiteStoreRotatesEncryptionKeys(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "webhook-receiver.db")
+	store, err := storage.NewSQLiteStore(storePath, testEncryptionKey)
+	require.NoError(t, err)
+	webhookID, err := store.InsertWebhook(model.NewWebhookFromInput(&model.WebhookInput{
+		HMACHeader: "X-Signature",
+		HMACSecret: "secret",
+		JWTSecret:  "jwt-secret",
+	}))
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	store, err = storage.NewSQLiteStore(storePath, rotatedEncryptionKey)
+	require.NoError(t, err)
+	_, err = store.GetWebhook(webhookID)
+	assert.ErrorContains(t, err, "cannot be decrypted")
+	require.NoError(t, store.Close())
+
+	store, err = storage.NewSQLiteStore(storePath, rotatedEncryptionKey, storage.WithPreviousEncryptionKeys(testEncryptionKey))
+	require.NoError(t, err)
+	webhook, err := store.GetWebhook(webhookID)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", webhook.HMACSecret())
+
+	rotated, err := store.RotateEncryptionKeys()
+	require.NoError(t, err)
+	assert.Equal(t, 2, rotated)
+	rotated, err = store.RotateEncryptionKeys()
+	require.NoError(t, err)
+	assert.Zero(t, rotated)
+	require.NoError(t, store.Close())
+
+	store, err = storage.NewSQLiteStore(storePath, rotatedEncryptionKey)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+	webhook, err = store.GetWebhook(webhookID)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", webhook.HMACSecret())
+	assert.Equal(t, "jwt-secret", webhook.JWTSecret())
+}
+
+func TestSQLiteStoreDecryptsCiphertextWithoutKeyID(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "webhook-receiver.db")
+	store, err := storage.NewSQLiteStore(storePath, rotatedEncryptionKey, storage.WithPreviousEncryptionKeys(testEncryptionKey))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+	webhookID, err := store.InsertWebhook(model.NewWebhook("", "", "", "", "X-Signature", "placeholder"))
+	require.NoError(t, err)
+
+	db, err := sql.Open("sqlite3", storePath)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+	_, err = db.Exec(`UPDATE webhooks SET hmac_secret_ciphertext = ? WHERE id = ?`, sealWithoutKeyID(t, testEncryptionKey, "legacy-secret"), webhookID)
+	require.NoError(t, err)
+
+	webhook, err := store.GetWebhook(webhookID)
+	require.NoError(t, err)
+	assert.Equal(t, "legacy-secret", webhook.HMACSecret())
+
+	rotated, err := store.RotateEncryptionKeys()
+	require.NoError(t, err)
+	assert.Equal(t, 1, rotated)
+}
+
+func TestSQLiteStoreValidatesPreviousEncryptionKeys(t *testing.T) {
+	_, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "webhook-receiver.db"), testEncryptionKey, storage.WithPreviousEncryptionKeys(rotatedEncryptionKey, "too-short"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "previous encryption key 2")
+}
+
+// sealWithoutKeyID encrypts like releases before key rotation did: the nonce
+// followed by the AES-GCM ciphertext.
+func sealWithoutKeyID(t *testing.T, encryptionKey string, plaintext string) []byte {
+	t.Helper()
+
+	key, err := base64.StdEncoding.DecodeString(encryptionKey)
+	require.NoError(t, err)
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	nonce := make([]byte, aead.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	require.NoError(t, err)
+
+	return aead.Seal(nonce, nonce, []byte(plaintext), nil)
+}
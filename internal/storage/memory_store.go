@@ -0,0 +1,438 @@
+package storage
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/google/uuid"
+)
+
+// MemoryStore keeps webhooks and messages in process memory. Nothing survives
+// a restart, which suits CI runs and throwaway deployments.
+type MemoryStore struct {
+	mu            sync.RWMutex
+	webhooks      map[string]*memoryWebhook
+	nextWebhookID int64
+	nextMessageID int64
+	nextAttemptID int64
+}
+
+type memoryWebhook struct {
+	webhook *model.Webhook
+	// sequence orders webhooks by creation like the SQL row IDs do.
+	sequence int64
+	// messages are kept oldest first; row IDs increase monotonically.
+	messages []*memoryMessage
+	// deliveryIDs remembers delivery IDs for replay protection.
+	deliveryIDs map[string]struct{}
+}
+
+type memoryMessage struct {
+	message  *model.Message
+	attempts []*model.ReplayAttempt
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{webhooks: map[string]*memoryWebhook{}}
+}
+
+// Close drops all stored data.
+func (s *MemoryStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.webhooks = map[string]*memoryWebhook{}
+	return nil
+}
+
+// InsertWebhook inserts provided webhooks.
+func (s *MemoryStore) InsertWebhook(webhook *model.Webhook) (string, error) {
+	webhookID := uuid.New().String()
+	webhook.ID = webhookID
+	if webhook.ExpiresAt.IsZero() {
+		webhook.ExpiresAt = time.Now().UTC().Add(webhookTTL)
+	} else {
+		webhook.ExpiresAt = webhook.ExpiresAt.UTC()
+	}
+	if webhook.MaxMessages <= 0 {
+		webhook.MaxMessages = defaultMaxMessagesPerWebhook
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextWebhookID++
+	s.webhooks[webhookID] = &memoryWebhook{webhook: cloneWebhook(webhook), sequence: s.nextWebhookID}
+	return webhookID, nil
+}
+
+// GetWebhook retrieves webhook with given ID.
+func (s *MemoryStore) GetWebhook(id string) (*model.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored, err := s.activeWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return cloneWebhook(stored.webhook), nil
+}
+
+// ListWebhooks lists all stored webhooks in reverse creation order.
+func (s *MemoryStore) ListWebhooks() ([]*model.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now().UTC()
+	active := []*memoryWebhook{}
+	for _, stored := range s.webhooks {
+		if stored.webhook.ExpiresAt.After(now) {
+			active = append(active, stored)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].sequence > active[j].sequence
+	})
+
+	webhooks := make([]*model.Webhook, 0, len(active))
+	for _, stored := range active {
+		webhooks = append(webhooks, cloneWebhook(stored.webhook))
+	}
+
+	return webhooks, nil
+}
+
+// UpdateWebhook persists the configuration and retention of an existing webhook.
+// Messages beyond a lowered message cap are deleted right away.
+func (s *MemoryStore) UpdateWebhook(webhook *model.Webhook) error {
+	if webhook.MaxMessages <= 0 {
+		webhook.MaxMessages = defaultMaxMessagesPerWebhook
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.activeWebhook(webhook.ID)
+	if err != nil {
+		return err
+	}
+
+	updated := cloneWebhook(webhook)
+	updated.ExpiresAt = updated.ExpiresAt.UTC()
+	updated.SetManagementSecretHash(stored.webhook.ManagementSecretHash())
+	stored.webhook = updated
+	stored.trim()
+
+	return nil
+}
+
+// DeleteWebhook removes a webhook together with its captured messages and
+// replay history.
+func (s *MemoryStore) DeleteWebhook(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.activeWebhook(id); err != nil {
+		return err
+	}
+
+	delete(s.webhooks, id)
+	return nil
+}
+
+// InsertMessage inserts message for given webhook ID.
+func (s *MemoryStore) InsertMessage(webhookID string, message *model.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.activeWebhook(webhookID)
+	if err != nil {
+		return err
+	}
+
+	s.nextMessageID++
+	message.ID = s.nextMessageID
+	stored.messages = append(stored.messages, &memoryMessage{message: cloneMessage(message)})
+	stored.trim()
+
+	return nil
+}
+
+// GetMessage retrieves a single captured message of the given webhook.
+func (s *MemoryStore) GetMessage(webhookID string, messageID int64) (*model.Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored, err := s.activeMessage(webhookID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	return cloneMessage(stored.message), nil
+}
+
+// DeleteMessage removes a single captured message and its replay history.
+func (s *MemoryStore) DeleteMessage(webhookID string, messageID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.activeWebhook(webhookID)
+	if err != nil {
+		return err
+	}
+
+	for i, message := range stored.messages {
+		if message.message.ID == messageID {
+			stored.messages = append(stored.messages[:i], stored.messages[i+1:]...)
+			return nil
+		}
+	}
+
+	return &MessageNotFoundError{WebhookId: webhookID, MessageId: messageID}
+}
+
+// InsertReplayAttempt records the result of replaying a captured message.
+func (s *MemoryStore) InsertReplayAttempt(webhookID string, attempt *model.ReplayAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.activeMessage(webhookID, attempt.MessageID)
+	if err != nil {
+		return err
+	}
+
+	s.nextAttemptID++
+	attempt.ID = s.nextAttemptID
+	storedAttempt := *attempt
+	storedAttempt.Time = storedAttempt.Time.UTC()
+	stored.attempts = append(stored.attempts, &storedAttempt)
+
+	return nil
+}
+
+// ListReplayAttempts lists replay attempts of a captured message, newest first.
+func (s *MemoryStore) ListReplayAttempts(webhookID string, messageID int64) ([]*model.ReplayAttempt, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored, err := s.activeMessage(webhookID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts := make([]*model.ReplayAttempt, 0, len(stored.attempts))
+	for i := len(stored.attempts) - 1; i >= 0; i-- {
+		attempt := *stored.attempts[i]
+		attempts = append(attempts, &attempt)
+	}
+
+	return attempts, nil
+}
+
+// RememberDeliveryID records a delivery ID for the webhook and reports whether
+// it was new. IDs are kept until the webhook is deleted.
+func (s *MemoryStore) RememberDeliveryID(webhookID string, deliveryID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.activeWebhook(webhookID)
+	if err != nil {
+		return false, err
+	}
+
+	if _, seen := stored.deliveryIDs[deliveryID]; seen {
+		return false, nil
+	}
+	if stored.deliveryIDs == nil {
+		stored.deliveryIDs = map[string]struct{}{}
+	}
+	stored.deliveryIDs[deliveryID] = struct{}{}
+
+	return true, nil
+}
+
+// ForgetDeliveryID removes a remembered delivery ID, so a retry of a delivery
+// that could not be captured or forwarded is accepted again.
+func (s *MemoryStore) ForgetDeliveryID(webhookID string, deliveryID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stored, ok := s.webhooks[webhookID]; ok {
+		delete(stored.deliveryIDs, deliveryID)
+	}
+
+	return nil
+}
+
+// GetMessagePageForWebhook retrieves a page of messages for given webhook ID.
+func (s *MemoryStore) GetMessagePageForWebhook(webhookID string, page int, pageSize int, filter model.MessageFilter) (*model.MessagePage, error) {
+	page, pageSize = normalizePagination(page, pageSize)
+	filter = model.NewMessageFilter(filter)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored, err := s.activeWebhook(webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := columnMessageFilter(filter)
+	candidates := make([]*model.Message, 0, len(stored.messages))
+	for i := len(stored.messages) - 1; i >= 0; i-- {
+		if message := stored.messages[i].message; columns.Matches(message) {
+			candidates = append(candidates, message)
+		}
+	}
+
+	messagePage := pageMatchingMessages(candidates, filter, page, pageSize)
+	for i, message := range messagePage.Messages {
+		messagePage.Messages[i] = cloneMessage(message)
+	}
+
+	return messagePage, nil
+}
+
+// DeleteExpiredWebhooks removes expired webhooks and their captured messages.
+func (s *MemoryStore) DeleteExpiredWebhooks() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	deletedCount := 0
+	for id, stored := range s.webhooks {
+		if !stored.webhook.ExpiresAt.After(now) {
+			delete(s.webhooks, id)
+			deletedCount++
+		}
+	}
+
+	return deletedCount, nil
+}
+
+// activeWebhook returns the stored webhook unless it is missing or expired.
+// Callers must hold the lock.
+func (s *MemoryStore) activeWebhook(id string) (*memoryWebhook, error) {
+	stored, ok := s.webhooks[id]
+	if !ok || !stored.webhook.ExpiresAt.After(time.Now().UTC()) {
+		return nil, &WebhookNotFoundError{WebhookId: id}
+	}
+
+	return stored, nil
+}
+
+// activeMessage returns a stored message of an active webhook. Callers must
+// hold the lock.
+func (s *MemoryStore) activeMessage(webhookID string, messageID int64) (*memoryMessage, error) {
+	stored, err := s.activeWebhook(webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, message := range stored.messages {
+		if message.message.ID == messageID {
+			return message, nil
+		}
+	}
+
+	return nil, &MessageNotFoundError{WebhookId: webhookID, MessageId: messageID}
+}
+
+// trim drops the oldest messages beyond the webhook's message cap.
+func (w *memoryWebhook) trim() {
+	if excess := len(w.messages) - w.webhook.MaxMessages; excess > 0 {
+		w.messages = append([]*memoryMessage{}, w.messages[excess:]...)
+	}
+}
+
+// cloneWebhook copies a webhook so callers cannot mutate stored state.
+func cloneWebhook(webhook *model.Webhook) *model.Webhook {
+	clone := *webhook
+	if webhook.Response != nil {
+		response := *webhook.Response
+		if webhook.Response.Headers != nil {
+			response.Headers = make(map[string]string, len(webhook.Response.Headers))
+			for name, value := range webhook.Response.Headers {
+				response.Headers[name] = value
+			}
+		}
+		clone.Response = &response
+	}
+	if webhook.HMACOptions != nil {
+		hmacOptions := *webhook.HMACOptions
+		clone.HMACOptions = &hmacOptions
+	}
+	if webhook.PublicKeySignature != nil {
+		publicKeySignature := *webhook.PublicKeySignature
+		clone.PublicKeySignature = &publicKeySignature
+	}
+	if webhook.JWT != nil {
+		jwt := *webhook.JWT
+		jwt.JWKS = append(json.RawMessage(nil), webhook.JWT.JWKS...)
+		clone.JWT = &jwt
+	}
+	if webhook.ReplayProtection != nil {
+		replayProtection := *webhook.ReplayProtection
+		clone.ReplayProtection = &replayProtection
+	}
+	if webhook.MutualTLS != nil {
+		mutualTLS := *webhook.MutualTLS
+		mutualTLS.Fingerprints = append([]string(nil), webhook.MutualTLS.Fingerprints...)
+		clone.MutualTLS = &mutualTLS
+	}
+	clone.AllowedCIDRs = append([]string(nil), webhook.AllowedCIDRs...)
+	if webhook.Redaction != nil {
+		clone.Redaction = &model.Redaction{
+			DropHeaders:  append([]string(nil), webhook.Redaction.DropHeaders...),
+			MaskHeaders:  append([]string(nil), webhook.Redaction.MaskHeaders...),
+			PayloadPaths: append([]string(nil), webhook.Redaction.PayloadPaths...),
+		}
+	}
+
+	return &clone
+}
+
+// cloneMessage copies a message so callers cannot mutate stored state. Parsed
+// bodies are derived on read and therefore not kept.
+func cloneMessage(message *model.Message) *model.Message {
+	clone := *message
+	clone.Time = clone.Time.UTC()
+	clone.Headers = cloneHeaders(message.Headers)
+	if clone.Headers == nil {
+		clone.Headers = map[string][]string{}
+	}
+	clone.ParsedBody = nil
+	if message.Forward != nil {
+		forward := *message.Forward
+		forward.Headers = cloneHeaders(message.Forward.Headers)
+		clone.Forward = &forward
+	}
+	if message.TLS != nil {
+		tlsDetails := *message.TLS
+		clone.TLS = &tlsDetails
+	}
+	if message.ClientCertificate != nil {
+		clientCertificate := *message.ClientCertificate
+		clone.ClientCertificate = &clientCertificate
+	}
+
+	return &clone
+}
+
+func cloneHeaders(headers map[string][]string) map[string][]string {
+	if headers == nil {
+		return nil
+	}
+
+	clone := make(map[string][]string, len(headers))
+	for name, values := range headers {
+		clone[name] = append([]string(nil), values...)
+	}
+
+	return clone
+}
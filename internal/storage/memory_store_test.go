@@ -0,0 +1,54 @@
+package storage_test
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/achawki/webhook-receiver/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStoreHandlesConcurrentIngestAndReads(t *testing.T) {
+	store := storage.NewMemoryStore()
+	webhook := model.NewWebhook("", "", "", "", "", "")
+	webhook.MaxMessages = 50
+	webhookID, err := store.InsertWebhook(webhook)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for writer := 0; writer < 8; writer++ {
+		wg.Add(1)
+		go func(writer int) {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				message := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", fmt.Sprintf("%d-%d", writer, i), nil)
+				assert.NoError(t, store.InsertMessage(webhookID, message))
+			}
+		}(writer)
+	}
+	for reader := 0; reader < 4; reader++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				page, err := store.GetMessagePageForWebhook(webhookID, 1, 10, model.MessageFilter{Outcome: model.MessageOutcomeAll})
+				if assert.NoError(t, err) && len(page.Messages) > 0 {
+					page.Messages[0].Headers["X-Mutated"] = []string{"true"}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	page, err := store.GetMessagePageForWebhook(webhookID, 1, 100, model.MessageFilter{Outcome: model.MessageOutcomeAll})
+	require.NoError(t, err)
+	assert.Equal(t, 50, page.TotalMessages)
+	assert.Equal(t, int64(200), page.Messages[0].ID)
+	for _, message := range page.Messages {
+		assert.NotContains(t, message.Headers, "X-Mutated")
+	}
+}
@@ -0,0 +1,196 @@
+package storage_test
+
+import (
+	"database/sql"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/achawki/webhook-receiver/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteStoreEncryptsMessagesAtRest(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "webhook-receiver.db")
+	store, err := storage.NewSQLiteStore(storePath, testEncryptionKey, storage.WithMessageEncryption(true))
+	require.NoError(t, err)
+
+	webhookID, err := store.InsertWebhook(model.NewWebhook("", "", "", "", "", ""))
+	require.NoError(t, err)
+	message := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", `{"email":"jane@example.com"}`, map[string][]string{"X-Customer": {"jane"}})
+	require.NoError(t, store.InsertMessage(webhookID, message))
+
+	storedMessage, err := store.GetMessage(webhookID, message.ID)
+	require.NoError(t, err)
+	assert.Equal(t, `{"email":"jane@example.com"}`, storedMessage.Payload)
+	assert.Equal(t, []string{"jane"}, storedMessage.Headers["X-Customer"])
+
+	page, err := store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAll})
+	require.NoError(t, err)
+	require.Len(t, page.Messages, 1)
+	assert.Equal(t, `{"email":"jane@example.com"}`, page.Messages[0].Payload)
+	require.NoError(t, store.Close())
+
+	db, err := sql.Open("sqlite3", storePath)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+	var (
+		payload     []byte
+		headersJSON string
+		encrypted   bool
+	)
+	require.NoError(t, db.QueryRow(`SELECT payload, headers_json, encrypted FROM messages WHERE row_id = ?`, message.ID).Scan(&payload, &headersJSON, &encrypted))
+	assert.True(t, encrypted)
+	assert.NotContains(t, string(payload), "jane@example.com")
+	assert.NotContains(t, headersJSON, "X-Customer")
+
+	// Rows stay readable after the mode is switched off; new rows are plaintext.
+	store, err = storage.NewSQLiteStore(storePath, testEncryptionKey)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+	require.NoError(t, store.InsertMessage(webhookID, model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", "plain", nil)))
+	page, err = store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAll})
+	require.NoError(t, err)
+	require.Len(t, page.Messages, 2)
+	assert.Equal(t, "plain", page.Messages[0].Payload)
+	assert.Equal(t, `{"email":"jane@example.com"}`, page.Messages[1].Payload)
+}
+
+func TestSQLiteStoreBindsEncryptedMessagesToTheirRow(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "webhook-receiver.db")
+	store, err := storage.NewSQLiteStore(storePath, testEncryptionKey, storage.WithMessageEncryption(true))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+
+	webhookID, err := store.InsertWebhook(model.NewWebhook("", "", "", "", "", ""))
+	require.NoError(t, err)
+	first := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", "first", nil)
+	require.NoError(t, store.InsertMessage(webhookID, first))
+	second := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", "second", nil)
+	require.NoError(t, store.InsertMessage(webhookID, second))
+
+	db, err := sql.Open("sqlite3", storePath)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+	_, err = db.Exec(`UPDATE messages SET payload = (SELECT payload FROM messages WHERE row_id = ?) WHERE row_id = ?`, first.ID, second.ID)
+	require.NoError(t, err)
+
+	_, err = store.GetMessage(webhookID, second.ID)
+	assert.ErrorContains(t, err, "cannot be decrypted")
+	_, err = store.GetMessage(webhookID, first.ID)
+	assert.NoError(t, err)
+}
+
+func TestSQLiteStoreRotatesEncryptedMessages(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "webhook-receiver.db")
+	store, err := storage.NewSQLiteStore(storePath, testEncryptionKey, storage.WithMessageEncryption(true))
+	require.NoError(t, err)
+	webhookID, err := store.InsertWebhook(model.NewWebhook("", "", "", "", "", ""))
+	require.NoError(t, err)
+	message := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", "payload", map[string][]string{"X-Test": {"1"}})
+	require.NoError(t, store.InsertMessage(webhookID, message))
+	require.NoError(t, store.Close())
+
+	store, err = storage.NewSQLiteStore(storePath, rotatedEncryptionKey, storage.WithPreviousEncryptionKeys(testEncryptionKey))
+	require.NoError(t, err)
+	rotated, err := store.RotateEncryptionKeys()
+	require.NoError(t, err)
+	assert.Equal(t, 1, rotated)
+	require.NoError(t, store.Close())
+
+	store, err = storage.NewSQLiteStore(storePath, rotatedEncryptionKey)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+	storedMessage, err := store.GetMessage(webhookID, message.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "payload", storedMessage.Payload)
+	assert.Equal(t, []string{"1"}, storedMessage.Headers["X-Test"])
+}
+
+// BenchmarkSQLiteStoreInsertMessage compares ingest with and without message
+// encryption. Run with: go test ./internal/storage -run '^$' -bench Message
+func BenchmarkSQLiteStoreInsertMessage(b *testing.B) {
+	payload := strings.Repeat(`{"event":"invoice.paid","amount":4200}`, 50)
+	headers := map[string][]string{"Content-Type": {"application/json"}, "X-Request-Id": {"bench"}}
+
+	for _, mode := range messageEncryptionModes {
+		b.Run(mode.name, func(b *testing.B) {
+			store, webhookID := newBenchmarkStore(b, mode.encrypt)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := store.InsertMessage(webhookID, model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", payload, headers)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkSQLiteStoreGetMessagePage measures reading a full page of 25
+// messages, which decrypts every row when encryption is enabled.
+func BenchmarkSQLiteStoreGetMessagePage(b *testing.B) {
+	payload := strings.Repeat(`{"event":"invoice.paid","amount":4200}`, 50)
+	headers := map[string][]string{"Content-Type": {"application/json"}, "X-Request-Id": {"bench"}}
+
+	for _, mode := range messageEncryptionModes {
+		b.Run(mode.name, func(b *testing.B) {
+			store, webhookID := newBenchmarkStore(b, mode.encrypt)
+			for i := 0; i < 100; i++ {
+				if err := store.InsertMessage(webhookID, model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", payload, headers)); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAll}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+var messageEncryptionModes = []struct {
+	name    string
+	encrypt bool
+}{
+	{name: "plaintext", encrypt: false},
+	{name: "encrypted", encrypt: true},
+}
+
+func newBenchmarkStore(b *testing.B, encrypt bool) (*storage.SQLiteStore, string) {
+	b.Helper()
+
+	store, err := storage.NewSQLiteStore(filepath.Join(b.TempDir(), "webhook-receiver.db"), testEncryptionKey, storage.WithMessageEncryption(encrypt))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() {
+		if err := store.Close(); err != nil {
+			b.Error(err)
+		}
+	})
+
+	webhookID, err := store.InsertWebhook(model.NewWebhook("", "", "", "", "", ""))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return store, webhookID
+}
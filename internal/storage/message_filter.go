@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"strings"
+	"time"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+)
+
+// maxSearchCandidates bounds how many rows a search that is matched in Go
+// loads, so a search over a webhook with a large message cap does not read and
+// decrypt every row. Only the newest candidates are searched.
+const maxSearchCandidates = 1000
+
+// receivedAtCondition compares the received_at column with a time bound in
+// the SQL dialect of a store.
+type receivedAtCondition func(operator string, bound time.Time) (string, interface{})
+
+// applyMessageFilter appends the conditions for filter fields kept in plain
+// columns. Headers and payloads may be stored encrypted, so those fields are
+// matched in Go once rows are decrypted; see filtersInGo. A header search
+// still skips unencrypted rows whose headers lack the header name.
+func applyMessageFilter(baseQuery string, baseArgs []interface{}, filter model.MessageFilter, receivedAt receivedAtCondition) (string, []interface{}) {
+	query := baseQuery
+	args := append([]interface{}{}, baseArgs...)
+
+	if querySuffix, queryArgs := outcomeQueryFilter(filter.Outcome); querySuffix != "" {
+		query += querySuffix
+		args = append(args, queryArgs...)
+	}
+	if filter.Method != "" {
+		query += " AND method = ?"
+		args = append(args, filter.Method)
+	}
+	if filter.PathPrefix != "" {
+		query += ` AND path LIKE ? ESCAPE '\'`
+		args = append(args, escapeLikePattern(filter.PathPrefix)+"%")
+	}
+	if filter.StatusCode != 0 {
+		query += " AND status_code = ?"
+		args = append(args, filter.StatusCode)
+	}
+	if !filter.Since.IsZero() {
+		condition, arg := receivedAt(">=", filter.Since)
+		query += " AND " + condition
+		args = append(args, arg)
+	}
+	if !filter.Until.IsZero() {
+		condition, arg := receivedAt("<=", filter.Until)
+		query += " AND " + condition
+		args = append(args, arg)
+	}
+	if filter.HeaderName != "" && !strings.ContainsAny(filter.HeaderName, "&<>") {
+		// Headers are stored as a JSON object, whose encoder escapes &, < and >.
+		query += ` AND (encrypted = 1 OR lower(headers_json) LIKE ? ESCAPE '\')`
+		args = append(args, `%"`+escapeLikePattern(strings.ToLower(filter.HeaderName))+`":%`)
+	}
+
+	return query, args
+}
+
+// filtersInGo reports whether the filter needs fields that SQL cannot match
+// on every row, so candidate rows have to be loaded and matched in Go before
+// they can be paged.
+func filtersInGo(filter model.MessageFilter) bool {
+	return filter.HeaderName != "" || filter.Text != "" || filter.JSON != nil
+}
+
+// columnMessageFilter keeps the filter fields that SQL stores match in plain
+// columns, which select the candidates of a search matched in Go.
+func columnMessageFilter(filter model.MessageFilter) model.MessageFilter {
+	filter.HeaderName = ""
+	filter.HeaderValue = ""
+	filter.Text = ""
+	filter.JSON = nil
+
+	return filter
+}
+
+// pageMatchingMessages pages through messages, newest first, that passed
+// filter.Matches. For filters matched in Go, candidates beyond
+// maxSearchCandidates are not searched and the page is marked as truncated.
+func pageMatchingMessages(candidates []*model.Message, filter model.MessageFilter, page int, pageSize int) *model.MessagePage {
+	searchTruncated := filtersInGo(filter) && len(candidates) > maxSearchCandidates
+	if searchTruncated {
+		candidates = candidates[:maxSearchCandidates]
+	}
+
+	matching := []*model.Message{}
+	for _, message := range candidates {
+		if filter.Matches(message) {
+			matching = append(matching, message)
+		}
+	}
+
+	totalMessages := len(matching)
+	page, totalPages, offset := calculateMessagePage(page, pageSize, totalMessages)
+
+	messages := []*model.Message{}
+	for i := offset; i < totalMessages && i < offset+pageSize; i++ {
+		messages = append(messages, matching[i])
+	}
+
+	return &model.MessagePage{
+		Messages:        messages,
+		Page:            page,
+		PageSize:        pageSize,
+		TotalMessages:   totalMessages,
+		TotalPages:      totalPages,
+		HasNextPage:     totalPages > 0 && page < totalPages,
+		HasPreviousPage: page > 1 && totalPages > 0,
+		SearchTruncated: searchTruncated,
+	}
+}
+
+func escapeLikePattern(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// UnsupportedSchemaVersionError indicates that the database was migrated by a
+// newer release than the running binary.
+type UnsupportedSchemaVersionError struct {
+	DatabaseVersion  int
+	SupportedVersion int
+}
+
+// Error implements the error interface.
+func (e *UnsupportedSchemaVersionError) Error() string {
+	return fmt.Sprintf("Database schema version %d is newer than the supported version %d; upgrade webhook-receiver", e.DatabaseVersion, e.SupportedVersion)
+}
+
+type schemaMigration struct {
+	version    int
+	name       string
+	statements string
+}
+
+// loadMigrations reads the embedded migrations of one store from dir and
+// returns them in version order. Versions must start at 1 without gaps.
+func loadMigrations(files embed.FS, dir string) ([]schemaMigration, error) {
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]schemaMigration, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		versionText, _, ok := strings.Cut(name, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s is not named NNNN_description.sql", name)
+		}
+		version, err := strconv.Atoi(versionText)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s is not named NNNN_description.sql", name)
+		}
+
+		statements, err := fs.ReadFile(files, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, schemaMigration{version: version, name: name, statements: string(statements)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	for i, migration := range migrations {
+		if migration.version != i+1 {
+			return nil, fmt.Errorf("migration %s is out of sequence, expected version %d", migration.name, i+1)
+		}
+	}
+	if len(migrations) == 0 {
+		return nil, errors.New("no migrations embedded in " + dir)
+	}
+
+	return migrations, nil
+}
@@ -0,0 +1 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS encrypted INTEGER NOT NULL DEFAULT 0;
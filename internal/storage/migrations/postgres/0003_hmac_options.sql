@@ -0,0 +1 @@
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS hmac_options_json TEXT NOT NULL DEFAULT '';
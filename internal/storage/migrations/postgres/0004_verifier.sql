@@ -0,0 +1 @@
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS verifier TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,9 @@
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS replay_protection_json TEXT NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS duplicate INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS delivery_ids (
+	webhook_id TEXT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+	delivery_id TEXT NOT NULL,
+	received_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (webhook_id, delivery_id)
+);
@@ -0,0 +1 @@
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS public_key_signature_json TEXT NOT NULL DEFAULT '';
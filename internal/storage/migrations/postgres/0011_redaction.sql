@@ -0,0 +1 @@
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS redaction_json TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,48 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+	row_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	id TEXT NOT NULL UNIQUE,
+	username TEXT NOT NULL DEFAULT '',
+	password_hash TEXT NOT NULL DEFAULT '',
+	token_name TEXT NOT NULL DEFAULT '',
+	token_value_hash TEXT NOT NULL DEFAULT '',
+	hmac_header TEXT NOT NULL DEFAULT '',
+	hmac_secret_ciphertext BLOB,
+	response_json TEXT NOT NULL DEFAULT '',
+	forward_url TEXT NOT NULL DEFAULT '',
+	management_secret_hash TEXT NOT NULL DEFAULT '',
+	max_messages INTEGER NOT NULL DEFAULT 100,
+	expires_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS messages (
+	row_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	webhook_id TEXT NOT NULL,
+	method TEXT NOT NULL,
+	path TEXT NOT NULL,
+	query TEXT NOT NULL DEFAULT '',
+	payload BLOB NOT NULL,
+	payload_encoding TEXT NOT NULL DEFAULT '',
+	headers_json TEXT NOT NULL DEFAULT '{}',
+	status_code INTEGER NOT NULL DEFAULT 200,
+	error_message TEXT NOT NULL DEFAULT '',
+	forward_json TEXT NOT NULL DEFAULT '',
+	forward_failed INTEGER NOT NULL DEFAULT 0,
+	received_at TEXT NOT NULL,
+	FOREIGN KEY (webhook_id) REFERENCES webhooks(id)
+);
+
+CREATE TABLE IF NOT EXISTS replay_attempts (
+	row_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	message_row_id INTEGER NOT NULL,
+	target_url TEXT NOT NULL,
+	status_code INTEGER NOT NULL DEFAULT 0,
+	latency_ms INTEGER NOT NULL DEFAULT 0,
+	response_body TEXT NOT NULL DEFAULT '',
+	error_message TEXT NOT NULL DEFAULT '',
+	attempted_at TEXT NOT NULL,
+	FOREIGN KEY (message_row_id) REFERENCES messages(row_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_expires_at ON webhooks(expires_at);
+CREATE INDEX IF NOT EXISTS idx_messages_webhook_row_id ON messages(webhook_id, row_id);
+CREATE INDEX IF NOT EXISTS idx_replay_attempts_message_row_id ON replay_attempts(message_row_id, row_id);
@@ -0,0 +1 @@
+ALTER TABLE messages ADD COLUMN encrypted INTEGER NOT NULL DEFAULT 0;
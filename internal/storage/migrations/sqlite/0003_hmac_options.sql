@@ -0,0 +1 @@
+ALTER TABLE webhooks ADD COLUMN hmac_options_json TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE webhooks ADD COLUMN verifier TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,10 @@
+ALTER TABLE webhooks ADD COLUMN replay_protection_json TEXT NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN duplicate INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE delivery_ids (
+	webhook_id TEXT NOT NULL,
+	delivery_id TEXT NOT NULL,
+	received_at TEXT NOT NULL,
+	PRIMARY KEY (webhook_id, delivery_id),
+	FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+ALTER TABLE webhooks ADD COLUMN jwt_json TEXT NOT NULL DEFAULT '';
+ALTER TABLE webhooks ADD COLUMN jwt_secret_ciphertext BLOB;
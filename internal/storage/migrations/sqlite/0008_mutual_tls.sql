@@ -0,0 +1,2 @@
+ALTER TABLE webhooks ADD COLUMN mutual_tls_json TEXT NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN client_certificate_json TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE webhooks ADD COLUMN allowed_cidrs_json TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,8 @@
+ALTER TABLE messages ADD COLUMN remote_ip TEXT NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN protocol TEXT NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN host TEXT NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN content_length INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE messages ADD COLUMN tls_version TEXT NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN tls_cipher_suite TEXT NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN tls_server_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN processing_us INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE webhooks ADD COLUMN redaction_json TEXT NOT NULL DEFAULT '';
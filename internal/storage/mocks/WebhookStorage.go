@@ -12,13 +12,78 @@ type WebhookStorage struct {
 	mock.Mock
 }
 
-// GetMessagePageForWebhook provides a mock function with given fields: webhookID, page, pageSize, outcome
-func (_m *WebhookStorage) GetMessagePageForWebhook(webhookID string, page int, pageSize int, outcome model.MessageOutcome) (*model.MessagePage, error) {
-	ret := _m.Called(webhookID, page, pageSize, outcome)
+// DeleteMessage provides a mock function with given fields: webhookID, messageID
+func (_m *WebhookStorage) DeleteMessage(webhookID string, messageID int64) error {
+	ret := _m.Called(webhookID, messageID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, int64) error); ok {
+		r0 = rf(webhookID, messageID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteWebhook provides a mock function with given fields: id
+func (_m *WebhookStorage) DeleteWebhook(id string) error {
+	ret := _m.Called(id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ForgetDeliveryID provides a mock function with given fields: webhookID, deliveryID
+func (_m *WebhookStorage) ForgetDeliveryID(webhookID string, deliveryID string) error {
+	ret := _m.Called(webhookID, deliveryID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(webhookID, deliveryID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetMessage provides a mock function with given fields: webhookID, messageID
+func (_m *WebhookStorage) GetMessage(webhookID string, messageID int64) (*model.Message, error) {
+	ret := _m.Called(webhookID, messageID)
+
+	var r0 *model.Message
+	if rf, ok := ret.Get(0).(func(string, int64) *model.Message); ok {
+		r0 = rf(webhookID, messageID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Message)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, int64) error); ok {
+		r1 = rf(webhookID, messageID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetMessagePageForWebhook provides a mock function with given fields: webhookID, page, pageSize, filter
+func (_m *WebhookStorage) GetMessagePageForWebhook(webhookID string, page int, pageSize int, filter model.MessageFilter) (*model.MessagePage, error) {
+	ret := _m.Called(webhookID, page, pageSize, filter)
 
 	var r0 *model.MessagePage
-	if rf, ok := ret.Get(0).(func(string, int, int, model.MessageOutcome) *model.MessagePage); ok {
-		r0 = rf(webhookID, page, pageSize, outcome)
+	if rf, ok := ret.Get(0).(func(string, int, int, model.MessageFilter) *model.MessagePage); ok {
+		r0 = rf(webhookID, page, pageSize, filter)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*model.MessagePage)
@@ -26,8 +91,8 @@ func (_m *WebhookStorage) GetMessagePageForWebhook(webhookID string, page int, p
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string, int, int, model.MessageOutcome) error); ok {
-		r1 = rf(webhookID, page, pageSize, outcome)
+	if rf, ok := ret.Get(1).(func(string, int, int, model.MessageFilter) error); ok {
+		r1 = rf(webhookID, page, pageSize, filter)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -58,6 +123,29 @@ func (_m *WebhookStorage) GetWebhook(id string) (*model.Webhook, error) {
 	return r0, r1
 }
 
+// ListReplayAttempts provides a mock function with given fields: webhookID, messageID
+func (_m *WebhookStorage) ListReplayAttempts(webhookID string, messageID int64) ([]*model.ReplayAttempt, error) {
+	ret := _m.Called(webhookID, messageID)
+
+	var r0 []*model.ReplayAttempt
+	if rf, ok := ret.Get(0).(func(string, int64) []*model.ReplayAttempt); ok {
+		r0 = rf(webhookID, messageID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.ReplayAttempt)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, int64) error); ok {
+		r1 = rf(webhookID, messageID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListWebhooks provides a mock function with given fields:
 func (_m *WebhookStorage) ListWebhooks() ([]*model.Webhook, error) {
 	ret := _m.Called()
@@ -95,6 +183,20 @@ func (_m *WebhookStorage) InsertMessage(webhookID string, message *model.Message
 	return r0
 }
 
+// InsertReplayAttempt provides a mock function with given fields: webhookID, attempt
+func (_m *WebhookStorage) InsertReplayAttempt(webhookID string, attempt *model.ReplayAttempt) error {
+	ret := _m.Called(webhookID, attempt)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, *model.ReplayAttempt) error); ok {
+		r0 = rf(webhookID, attempt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // InsertWebhook provides a mock function with given fields: webhook
 func (_m *WebhookStorage) InsertWebhook(webhook *model.Webhook) (string, error) {
 	ret := _m.Called(webhook)
@@ -115,3 +217,38 @@ func (_m *WebhookStorage) InsertWebhook(webhook *model.Webhook) (string, error)
 
 	return r0, r1
 }
+
+// RememberDeliveryID provides a mock function with given fields: webhookID, deliveryID
+func (_m *WebhookStorage) RememberDeliveryID(webhookID string, deliveryID string) (bool, error) {
+	ret := _m.Called(webhookID, deliveryID)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string, string) bool); ok {
+		r0 = rf(webhookID, deliveryID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(webhookID, deliveryID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateWebhook provides a mock function with given fields: webhook
+func (_m *WebhookStorage) UpdateWebhook(webhook *model.Webhook) error {
+	ret := _m.Called(webhook)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*model.Webhook) error); ok {
+		r0 = rf(webhook)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
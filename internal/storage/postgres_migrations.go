@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// postgresMigrationFiles holds the ordered up-migrations of the PostgreSQL
+// schema. They share their versions with the SQLite migrations. Migrations up
+// to 0011 use IF NOT EXISTS so databases created before schema versioning are
+// adopted in place; released migrations must never be edited.
+//
+//go:embed migrations/postgres/*.sql
+var postgresMigrationFiles embed.FS
+
+const postgresMigrationsDir = "migrations/postgres"
+
+// postgresSchemaLockID serializes migrations when several instances start at once.
+const postgresSchemaLockID = 7351892041
+
+const postgresSchemaVersionTable = `
+CREATE TABLE IF NOT EXISTS schema_version (
+	version INTEGER PRIMARY KEY,
+	applied_at TIMESTAMPTZ NOT NULL
+);
+`
+
+// PostgresSchemaVersion returns the schema version this binary migrates to.
+func PostgresSchemaVersion() (int, error) {
+	migrations, err := loadMigrations(postgresMigrationFiles, postgresMigrationsDir)
+	if err != nil {
+		return 0, err
+	}
+
+	return migrations[len(migrations)-1].version, nil
+}
+
+// migrate brings the database up to the latest embedded migration. Each
+// migration runs in its own transaction together with its schema_version row
+// and under an advisory lock, so concurrent instances apply it only once and a
+// failed upgrade leaves the database at the previous version.
+func (s *PostgresStore) migrate() error {
+	migrations, err := loadMigrations(postgresMigrationFiles, postgresMigrationsDir)
+	if err != nil {
+		return err
+	}
+
+	for {
+		applied, err := s.applyNextMigration(migrations)
+		if err != nil {
+			return err
+		}
+		if !applied {
+			return nil
+		}
+	}
+}
+
+// applyNextMigration applies the first migration above the current schema
+// version and reports whether there was one.
+func (s *PostgresStore) applyNextMigration(migrations []schemaMigration) (applied bool, err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if rollbackErr := tx.Rollback(); err == nil && rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			err = rollbackErr
+		}
+	}()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, postgresSchemaLockID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(postgresSchemaVersionTable); err != nil {
+		return false, err
+	}
+
+	var currentVersion int
+	if err := tx.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&currentVersion); err != nil {
+		return false, err
+	}
+	latestVersion := migrations[len(migrations)-1].version
+	if currentVersion > latestVersion {
+		return false, &UnsupportedSchemaVersionError{DatabaseVersion: currentVersion, SupportedVersion: latestVersion}
+	}
+	if currentVersion == latestVersion {
+		return false, tx.Commit()
+	}
+
+	migration := migrations[currentVersion]
+	if _, err := tx.Exec(migration.statements); err != nil {
+		return false, fmt.Errorf("apply migration %s: %w", migration.name, err)
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO schema_version (version, applied_at) VALUES ($1, $2)`,
+		migration.version,
+		time.Now().UTC(),
+	); err != nil {
+		return false, fmt.Errorf("apply migration %s: %w", migration.name, err)
+	}
+
+	return true, tx.Commit()
+}
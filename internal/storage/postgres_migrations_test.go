@@ -0,0 +1,146 @@
+package storage_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/achawki/webhook-receiver/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unversionedPostgresSchema is a schema PostgreSQL databases were created with
+// before versioned migrations existed, including columns added ad hoc later.
+const unversionedPostgresSchema = `
+CREATE TABLE webhooks (
+	row_id BIGSERIAL PRIMARY KEY,
+	id TEXT NOT NULL UNIQUE,
+	username TEXT NOT NULL DEFAULT '',
+	password_hash TEXT NOT NULL DEFAULT '',
+	token_name TEXT NOT NULL DEFAULT '',
+	token_value_hash TEXT NOT NULL DEFAULT '',
+	hmac_header TEXT NOT NULL DEFAULT '',
+	hmac_secret_ciphertext BYTEA,
+	hmac_options_json TEXT NOT NULL DEFAULT '',
+	response_json TEXT NOT NULL DEFAULT '',
+	forward_url TEXT NOT NULL DEFAULT '',
+	management_secret_hash TEXT NOT NULL DEFAULT '',
+	max_messages INTEGER NOT NULL DEFAULT 100,
+	expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE messages (
+	row_id BIGSERIAL PRIMARY KEY,
+	webhook_id TEXT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+	method TEXT NOT NULL,
+	path TEXT NOT NULL,
+	query TEXT NOT NULL DEFAULT '',
+	payload BYTEA NOT NULL,
+	payload_encoding TEXT NOT NULL DEFAULT '',
+	headers_json TEXT NOT NULL DEFAULT '{}',
+	status_code INTEGER NOT NULL DEFAULT 200,
+	error_message TEXT NOT NULL DEFAULT '',
+	forward_json TEXT NOT NULL DEFAULT '',
+	forward_failed INTEGER NOT NULL DEFAULT 0,
+	received_at TIMESTAMPTZ NOT NULL,
+	encrypted INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE replay_attempts (
+	row_id BIGSERIAL PRIMARY KEY,
+	message_row_id BIGINT NOT NULL REFERENCES messages(row_id) ON DELETE CASCADE,
+	target_url TEXT NOT NULL,
+	status_code INTEGER NOT NULL DEFAULT 0,
+	latency_ms BIGINT NOT NULL DEFAULT 0,
+	response_body TEXT NOT NULL DEFAULT '',
+	error_message TEXT NOT NULL DEFAULT '',
+	attempted_at TIMESTAMPTZ NOT NULL
+);
+`
+
+func TestPostgresMigrationsMirrorSQLiteVersions(t *testing.T) {
+	postgresVersion, err := storage.PostgresSchemaVersion()
+	require.NoError(t, err)
+	sqliteVersion, err := storage.SQLiteSchemaVersion()
+	require.NoError(t, err)
+
+	assert.Equal(t, sqliteVersion, postgresVersion)
+}
+
+func TestPostgresStoreUpgradesUnversionedSchema(t *testing.T) {
+	dsn := postgresTestDSN(t)
+	schemaDSN := postgresTestSchema(t, openPostgres(t, dsn), dsn)
+	db := openPostgres(t, schemaDSN)
+	_, err := db.Exec(unversionedPostgresSchema + `
+INSERT INTO webhooks (id, username, max_messages, expires_at) VALUES ('existing', 'alice', 5, '2999-01-01T00:00:00Z');
+INSERT INTO messages (webhook_id, method, path, payload, payload_encoding, received_at) VALUES ('existing', 'POST', '/hooks/existing', '\x7b7d', 'utf8', '2026-01-01T00:00:00Z');`)
+	require.NoError(t, err)
+
+	store, err := storage.NewPostgresStore(schemaDSN, testEncryptionKey)
+	require.NoError(t, err)
+
+	webhook, err := store.GetWebhook("existing")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", webhook.Username)
+	assert.Equal(t, 5, webhook.MaxMessages)
+
+	page, err := store.GetMessagePageForWebhook("existing", 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAll})
+	require.NoError(t, err)
+	require.Len(t, page.Messages, 1)
+	assert.Equal(t, "{}", page.Messages[0].Payload)
+	require.NoError(t, store.Close())
+
+	// Reopening must not apply any migration twice.
+	store, err = storage.NewPostgresStore(schemaDSN, testEncryptionKey)
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	latestVersion, err := storage.PostgresSchemaVersion()
+	require.NoError(t, err)
+	expectedVersions := make([]int, 0, latestVersion)
+	for version := 1; version <= latestVersion; version++ {
+		expectedVersions = append(expectedVersions, version)
+	}
+	assert.Equal(t, expectedVersions, postgresSchemaVersions(t, db))
+}
+
+func TestPostgresStoreRefusesNewerSchemaVersion(t *testing.T) {
+	dsn := postgresTestDSN(t)
+	schemaDSN := postgresTestSchema(t, openPostgres(t, dsn), dsn)
+	store, err := storage.NewPostgresStore(schemaDSN, testEncryptionKey)
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	latestVersion, err := storage.PostgresSchemaVersion()
+	require.NoError(t, err)
+	db := openPostgres(t, schemaDSN)
+	_, err = db.Exec(`INSERT INTO schema_version (version, applied_at) VALUES ($1, now())`, latestVersion+1)
+	require.NoError(t, err)
+
+	_, err = storage.NewPostgresStore(schemaDSN, testEncryptionKey)
+	var versionErr *storage.UnsupportedSchemaVersionError
+	require.ErrorAs(t, err, &versionErr)
+	assert.Equal(t, latestVersion+1, versionErr.DatabaseVersion)
+	assert.Equal(t, latestVersion, versionErr.SupportedVersion)
+}
+
+func postgresSchemaVersions(t *testing.T, db *sql.DB) []int {
+	t.Helper()
+
+	rows, err := db.Query(`SELECT version FROM schema_version ORDER BY version`)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, rows.Close())
+	}()
+
+	versions := []int{}
+	for rows.Next() {
+		var version int
+		require.NoError(t, rows.Scan(&version))
+		versions = append(versions, version)
+	}
+	require.NoError(t, rows.Err())
+
+	return versions
+}
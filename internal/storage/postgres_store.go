@@ -0,0 +1,889 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/google/uuid"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+const postgresWebhookColumns = `id, username, password_hash, token_name, token_value_hash, hmac_header, hmac_secret_ciphertext, hmac_options_json, verifier, public_key_signature_json, jwt_json, jwt_secret_ciphertext, replay_protection_json, mutual_tls_json, allowed_cidrs_json, redaction_json, response_json, forward_url, management_secret_hash, max_messages, expires_at`
+
+const postgresMessageColumns = `row_id, method, path, query, payload, headers_json, status_code, error_message, forward_json, received_at, encrypted, duplicate, client_certificate_json, remote_ip, protocol, host, content_length, tls_version, tls_cipher_suite, tls_server_name, processing_us`
+
+// PostgresStore persists webhooks and messages in PostgreSQL. Unlike
+// SQLiteStore it can be shared by several receiver instances.
+type PostgresStore struct {
+	db              *sql.DB
+	cipher          *secretCipher
+	encryptMessages bool
+}
+
+// NewPostgresStore connects to PostgreSQL and migrates the schema if needed.
+func NewPostgresStore(dsn string, encryptionKey string, options ...StoreOption) (*PostgresStore, error) {
+	storeOptions := applyStoreOptions(options)
+	secretCipher, err := newSecretCipher(encryptionKey, storeOptions.previousEncryptionKeys...)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &PostgresStore{db: db, cipher: secretCipher, encryptMessages: storeOptions.encryptMessages}
+	if err := store.init(); err != nil {
+		if closeErr := db.Close(); closeErr != nil {
+			return nil, errors.Join(err, closeErr)
+		}
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Close releases the underlying database resources.
+func (s *PostgresStore) Close() error {
+	return s.db.Close()
+}
+
+// InsertWebhook inserts provided webhooks.
+func (s *PostgresStore) InsertWebhook(webhook *model.Webhook) (string, error) {
+	webhookID := uuid.New().String()
+	webhook.ID = webhookID
+	if webhook.ExpiresAt.IsZero() {
+		webhook.ExpiresAt = time.Now().UTC().Add(webhookTTL)
+	} else {
+		webhook.ExpiresAt = webhook.ExpiresAt.UTC()
+	}
+	if webhook.MaxMessages <= 0 {
+		webhook.MaxMessages = defaultMaxMessagesPerWebhook
+	}
+
+	config, err := s.encodeWebhookConfig(webhook)
+	if err != nil {
+		webhook.ID = ""
+		return "", err
+	}
+
+	_, err = s.db.Exec(
+		`INSERT INTO webhooks (`+postgresWebhookColumns+`)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)`,
+		webhook.ID,
+		webhook.Username,
+		webhook.PasswordHash(),
+		webhook.TokenName,
+		webhook.TokenValueHash(),
+		webhook.HMACHeader,
+		config.hmacSecretCiphertext,
+		config.hmacOptionsJSON,
+		string(webhook.Verifier),
+		config.publicKeySignatureJSON,
+		config.jwtJSON,
+		config.jwtSecretCiphertext,
+		config.replayProtectionJSON,
+		config.mutualTLSJSON,
+		config.allowedCIDRsJSON,
+		config.redactionJSON,
+		config.responseJSON,
+		webhook.ForwardURL,
+		webhook.ManagementSecretHash(),
+		webhook.MaxMessages,
+		webhook.ExpiresAt,
+	)
+	if err != nil {
+		webhook.ID = ""
+		return "", err
+	}
+
+	return webhookID, nil
+}
+
+// GetWebhook retrieves webhook with given ID.
+func (s *PostgresStore) GetWebhook(id string) (*model.Webhook, error) {
+	row := s.db.QueryRow(
+		`SELECT `+postgresWebhookColumns+`
+		 FROM webhooks WHERE id = $1 AND expires_at > $2`,
+		id,
+		time.Now().UTC(),
+	)
+
+	webhook, err := s.scanWebhook(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &WebhookNotFoundError{WebhookId: id}
+		}
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+// ListWebhooks lists all stored webhooks in reverse creation order.
+func (s *PostgresStore) ListWebhooks() (webhooks []*model.Webhook, err error) {
+	rows, err := s.db.Query(
+		`SELECT `+postgresWebhookColumns+`
+		 FROM webhooks
+		 WHERE expires_at > $1
+		 ORDER BY row_id DESC`,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); err == nil && closeErr != nil {
+			err = closeErr
+		}
+	}()
+
+	webhooks = []*model.Webhook{}
+	for rows.Next() {
+		webhook, err := s.scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// UpdateWebhook persists the configuration and retention of an existing webhook.
+// Messages beyond a lowered message cap are deleted right away.
+func (s *PostgresStore) UpdateWebhook(webhook *model.Webhook) (err error) {
+	if webhook.MaxMessages <= 0 {
+		webhook.MaxMessages = defaultMaxMessagesPerWebhook
+	}
+
+	config, err := s.encodeWebhookConfig(webhook)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rollbackErr := tx.Rollback(); err == nil && rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			err = rollbackErr
+		}
+	}()
+
+	result, err := tx.Exec(
+		`UPDATE webhooks
+		 SET username = $1, password_hash = $2, token_name = $3, token_value_hash = $4, hmac_header = $5,
+		     hmac_secret_ciphertext = $6, hmac_options_json = $7, verifier = $8, public_key_signature_json = $9,
+		     jwt_json = $10, jwt_secret_ciphertext = $11, replay_protection_json = $12, mutual_tls_json = $13, allowed_cidrs_json = $14,
+		     redaction_json = $15, response_json = $16, forward_url = $17, max_messages = $18, expires_at = $19
+		 WHERE id = $20 AND expires_at > $21`,
+		webhook.Username,
+		webhook.PasswordHash(),
+		webhook.TokenName,
+		webhook.TokenValueHash(),
+		webhook.HMACHeader,
+		config.hmacSecretCiphertext,
+		config.hmacOptionsJSON,
+		string(webhook.Verifier),
+		config.publicKeySignatureJSON,
+		config.jwtJSON,
+		config.jwtSecretCiphertext,
+		config.replayProtectionJSON,
+		config.mutualTLSJSON,
+		config.allowedCIDRsJSON,
+		config.redactionJSON,
+		config.responseJSON,
+		webhook.ForwardURL,
+		webhook.MaxMessages,
+		webhook.ExpiresAt.UTC(),
+		webhook.ID,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return err
+	}
+
+	updatedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updatedRows == 0 {
+		return &WebhookNotFoundError{WebhookId: webhook.ID}
+	}
+
+	if err := trimMessages(postgresExecer{tx}, webhook.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteWebhook removes a webhook and, through cascading foreign keys, its
+// captured messages and replay history.
+func (s *PostgresStore) DeleteWebhook(id string) error {
+	result, err := s.db.Exec(`DELETE FROM webhooks WHERE id = $1 AND expires_at > $2`, id, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	deletedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deletedRows == 0 {
+		return &WebhookNotFoundError{WebhookId: id}
+	}
+
+	return nil
+}
+
+// InsertMessage inserts message for given webhook ID. The webhook row is locked
+// so concurrent inserts from several instances trim consistently.
+func (s *PostgresStore) InsertMessage(webhookID string, message *model.Message) (err error) {
+	headersJSON, err := json.Marshal(message.Headers)
+	if err != nil {
+		return err
+	}
+
+	forwardJSON, err := marshalForwardResult(message.Forward)
+	if err != nil {
+		return err
+	}
+
+	clientCertificateJSON, err := marshalClientCertificate(message.ClientCertificate)
+	if err != nil {
+		return err
+	}
+	tlsVersion, tlsCipherSuite, tlsServerName := tlsDetailColumns(message.TLS)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rollbackErr := tx.Rollback(); err == nil && rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			err = rollbackErr
+		}
+	}()
+
+	var locked int
+	err = tx.QueryRow(
+		`SELECT 1 FROM webhooks WHERE id = $1 AND expires_at > $2 FOR UPDATE`,
+		webhookID,
+		time.Now().UTC(),
+	).Scan(&locked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &WebhookNotFoundError{WebhookId: webhookID}
+	}
+	if err != nil {
+		return err
+	}
+
+	forwardFailed := 0
+	if message.ForwardFailed() {
+		forwardFailed = 1
+	}
+	duplicate := 0
+	if message.Duplicate {
+		duplicate = 1
+	}
+
+	payload, storedHeaders, encrypted := message.Body(), string(headersJSON), 0
+	if s.encryptMessages {
+		// The row ID is part of the associated data, so the ciphertext is
+		// written once the insert has assigned it.
+		payload, storedHeaders, encrypted = []byte{}, "", 1
+	}
+
+	var messageID int64
+	err = tx.QueryRow(
+		`INSERT INTO messages (webhook_id, method, path, query, payload, payload_encoding, headers_json, status_code, error_message, forward_json, forward_failed, received_at, encrypted, duplicate, client_certificate_json, remote_ip, protocol, host, content_length, tls_version, tls_cipher_suite, tls_server_name, processing_us)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
+		 RETURNING row_id`,
+		webhookID,
+		message.Method,
+		message.Path,
+		message.Query,
+		payload,
+		string(message.PayloadEncoding),
+		storedHeaders,
+		message.StatusCode,
+		message.ErrorMessage,
+		forwardJSON,
+		forwardFailed,
+		message.Time.UTC(),
+		encrypted,
+		duplicate,
+		clientCertificateJSON,
+		message.RemoteIP,
+		message.Protocol,
+		message.Host,
+		message.ContentLength,
+		tlsVersion,
+		tlsCipherSuite,
+		tlsServerName,
+		message.ProcessingMicros,
+	).Scan(&messageID)
+	if err != nil {
+		return err
+	}
+
+	if s.encryptMessages {
+		sealedPayload, sealedHeaders, err := s.cipher.sealMessage(webhookID, messageID, message.Body(), string(headersJSON))
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE messages SET payload = $1, headers_json = $2 WHERE row_id = $3`, sealedPayload, sealedHeaders, messageID); err != nil {
+			return err
+		}
+	}
+
+	if err := trimMessages(postgresExecer{tx}, webhookID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	message.ID = messageID
+	return nil
+}
+
+// GetMessage retrieves a single captured message of the given webhook.
+func (s *PostgresStore) GetMessage(webhookID string, messageID int64) (*model.Message, error) {
+	exists, err := s.webhookExists(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, &WebhookNotFoundError{WebhookId: webhookID}
+	}
+
+	row := s.db.QueryRow(
+		`SELECT `+postgresMessageColumns+`
+		 FROM messages
+		 WHERE webhook_id = $1 AND row_id = $2`,
+		webhookID,
+		messageID,
+	)
+
+	message, err := s.scanMessage(row, webhookID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &MessageNotFoundError{WebhookId: webhookID, MessageId: messageID}
+		}
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// DeleteMessage removes a single captured message and its replay history.
+func (s *PostgresStore) DeleteMessage(webhookID string, messageID int64) error {
+	exists, err := s.webhookExists(webhookID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &WebhookNotFoundError{WebhookId: webhookID}
+	}
+
+	result, err := s.db.Exec(`DELETE FROM messages WHERE webhook_id = $1 AND row_id = $2`, webhookID, messageID)
+	if err != nil {
+		return err
+	}
+
+	deletedCount, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deletedCount == 0 {
+		return &MessageNotFoundError{WebhookId: webhookID, MessageId: messageID}
+	}
+
+	return nil
+}
+
+// InsertReplayAttempt records the result of replaying a captured message.
+func (s *PostgresStore) InsertReplayAttempt(webhookID string, attempt *model.ReplayAttempt) error {
+	if _, err := s.GetMessage(webhookID, attempt.MessageID); err != nil {
+		return err
+	}
+
+	return s.db.QueryRow(
+		`INSERT INTO replay_attempts (message_row_id, target_url, status_code, latency_ms, response_body, error_message, attempted_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING row_id`,
+		attempt.MessageID,
+		attempt.TargetURL,
+		attempt.StatusCode,
+		attempt.LatencyMillis,
+		attempt.ResponseBody,
+		attempt.ErrorMessage,
+		attempt.Time.UTC(),
+	).Scan(&attempt.ID)
+}
+
+// ListReplayAttempts lists replay attempts of a captured message, newest first.
+func (s *PostgresStore) ListReplayAttempts(webhookID string, messageID int64) (attempts []*model.ReplayAttempt, err error) {
+	if _, err := s.GetMessage(webhookID, messageID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(
+		`SELECT row_id, message_row_id, target_url, status_code, latency_ms, response_body, error_message, attempted_at
+		 FROM replay_attempts
+		 WHERE message_row_id = $1
+		 ORDER BY row_id DESC`,
+		messageID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); err == nil && closeErr != nil {
+			err = closeErr
+		}
+	}()
+
+	attempts = []*model.ReplayAttempt{}
+	for rows.Next() {
+		var attempt model.ReplayAttempt
+		if err := rows.Scan(&attempt.ID, &attempt.MessageID, &attempt.TargetURL, &attempt.StatusCode, &attempt.LatencyMillis, &attempt.ResponseBody, &attempt.ErrorMessage, &attempt.Time); err != nil {
+			return nil, err
+		}
+		attempt.Time = attempt.Time.UTC()
+		attempts = append(attempts, &attempt)
+	}
+
+	return attempts, rows.Err()
+}
+
+// GetMessagePageForWebhook retrieves a page of messages for given webhook ID.
+func (s *PostgresStore) GetMessagePageForWebhook(webhookID string, page int, pageSize int, filter model.MessageFilter) (messagePage *model.MessagePage, err error) {
+	page, pageSize = normalizePagination(page, pageSize)
+	filter = model.NewMessageFilter(filter)
+
+	exists, err := s.webhookExists(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, &WebhookNotFoundError{WebhookId: webhookID}
+	}
+
+	if filtersInGo(filter) {
+		candidates, err := s.loadMessagesForWebhook(webhookID, filter, maxSearchCandidates+1, 0)
+		if err != nil {
+			return nil, err
+		}
+		return pageMatchingMessages(candidates, filter, page, pageSize), nil
+	}
+
+	countQuery, countArgs := applyMessageFilter(`SELECT COUNT(*) FROM messages WHERE webhook_id = ?`, []interface{}{webhookID}, filter, postgresReceivedAt)
+	var totalMessages int
+	if err := s.db.QueryRow(postgresPlaceholders(countQuery), countArgs...).Scan(&totalMessages); err != nil {
+		return nil, err
+	}
+
+	page, totalPages, offset := calculateMessagePage(page, pageSize, totalMessages)
+
+	messages, err := s.loadMessagesForWebhook(webhookID, filter, pageSize, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	messagePage = &model.MessagePage{
+		Messages:        messages,
+		Page:            page,
+		PageSize:        pageSize,
+		TotalMessages:   totalMessages,
+		TotalPages:      totalPages,
+		HasNextPage:     totalPages > 0 && page < totalPages,
+		HasPreviousPage: page > 1 && totalPages > 0,
+	}
+
+	return messagePage, nil
+}
+
+// loadMessagesForWebhook loads up to limit matching messages, newest first.
+func (s *PostgresStore) loadMessagesForWebhook(webhookID string, filter model.MessageFilter, limit int, offset int) (messages []*model.Message, err error) {
+	messageQuery, messageArgs := applyMessageFilter(
+		`SELECT `+postgresMessageColumns+`
+		 FROM messages
+		 WHERE webhook_id = ?`,
+		[]interface{}{webhookID},
+		filter,
+		postgresReceivedAt,
+	)
+	messageQuery += `
+		 ORDER BY row_id DESC
+		 LIMIT ? OFFSET ?`
+	messageArgs = append(messageArgs, limit, offset)
+
+	rows, err := s.db.Query(postgresPlaceholders(messageQuery), messageArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); err == nil && closeErr != nil {
+			err = closeErr
+		}
+	}()
+
+	messages = []*model.Message{}
+	for rows.Next() {
+		message, scanErr := s.scanMessage(rows, webhookID)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, rows.Err()
+}
+
+// RememberDeliveryID records a delivery ID for the webhook and reports whether
+// it was new. IDs are kept until the webhook is deleted.
+func (s *PostgresStore) RememberDeliveryID(webhookID string, deliveryID string) (bool, error) {
+	exists, err := s.webhookExists(webhookID)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		return false, &WebhookNotFoundError{WebhookId: webhookID}
+	}
+
+	result, err := s.db.Exec(
+		`INSERT INTO delivery_ids (webhook_id, delivery_id, received_at) VALUES ($1, $2, $3)
+		 ON CONFLICT (webhook_id, delivery_id) DO NOTHING`,
+		webhookID,
+		deliveryID,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return inserted == 1, nil
+}
+
+// ForgetDeliveryID removes a remembered delivery ID, so a retry of a delivery
+// that could not be captured or forwarded is accepted again.
+func (s *PostgresStore) ForgetDeliveryID(webhookID string, deliveryID string) error {
+	_, err := s.db.Exec(`DELETE FROM delivery_ids WHERE webhook_id = $1 AND delivery_id = $2`, webhookID, deliveryID)
+
+	return err
+}
+
+// DeleteExpiredWebhooks removes expired webhooks and their captured messages.
+func (s *PostgresStore) DeleteExpiredWebhooks() (int, error) {
+	result, err := s.db.Exec(`DELETE FROM webhooks WHERE expires_at <= $1`, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	deletedRows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(deletedRows), nil
+}
+
+func (s *PostgresStore) init() error {
+	if err := s.migrate(); err != nil {
+		return err
+	}
+
+	_, err := s.DeleteExpiredWebhooks()
+	return err
+}
+
+func (s *PostgresStore) webhookExists(webhookID string) (bool, error) {
+	var exists int
+	err := s.db.QueryRow(
+		`SELECT 1 FROM webhooks WHERE id = $1 AND expires_at > $2`,
+		webhookID,
+		time.Now().UTC(),
+	).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// encodedWebhookConfig holds the column values of a webhook configuration
+// that are encrypted or serialized before being stored.
+type encodedWebhookConfig struct {
+	hmacSecretCiphertext   []byte
+	hmacOptionsJSON        string
+	publicKeySignatureJSON string
+	jwtJSON                string
+	jwtSecretCiphertext    []byte
+	replayProtectionJSON   string
+	mutualTLSJSON          string
+	allowedCIDRsJSON       string
+	redactionJSON          string
+	responseJSON           string
+}
+
+func (s *PostgresStore) encodeWebhookConfig(webhook *model.Webhook) (encodedWebhookConfig, error) {
+	var config encodedWebhookConfig
+	if webhook.HasHMAC() {
+		var err error
+		config.hmacSecretCiphertext, err = s.cipher.Encrypt(webhook.HMACSecret())
+		if err != nil {
+			return encodedWebhookConfig{}, err
+		}
+	}
+
+	var err error
+	config.hmacOptionsJSON, err = marshalHMACOptions(webhook.HMACOptions)
+	if err != nil {
+		return encodedWebhookConfig{}, err
+	}
+
+	config.publicKeySignatureJSON, err = marshalPublicKeySignature(webhook.PublicKeySignature)
+	if err != nil {
+		return encodedWebhookConfig{}, err
+	}
+
+	config.jwtJSON, config.jwtSecretCiphertext, err = encodeJWT(s.cipher, webhook)
+	if err != nil {
+		return encodedWebhookConfig{}, err
+	}
+
+	config.replayProtectionJSON, err = marshalReplayProtection(webhook.ReplayProtection)
+	if err != nil {
+		return encodedWebhookConfig{}, err
+	}
+
+	config.mutualTLSJSON, err = marshalMutualTLS(webhook.MutualTLS)
+	if err != nil {
+		return encodedWebhookConfig{}, err
+	}
+
+	config.allowedCIDRsJSON, err = marshalAllowedCIDRs(webhook.AllowedCIDRs)
+	if err != nil {
+		return encodedWebhookConfig{}, err
+	}
+
+	config.redactionJSON, err = marshalRedaction(webhook.Redaction)
+	if err != nil {
+		return encodedWebhookConfig{}, err
+	}
+
+	config.responseJSON, err = marshalWebhookResponse(webhook.Response)
+	if err != nil {
+		return encodedWebhookConfig{}, err
+	}
+
+	return config, nil
+}
+
+func (s *PostgresStore) scanWebhook(scanner rowScanner) (*model.Webhook, error) {
+	var (
+		id                     string
+		username               string
+		passwordHash           string
+		tokenName              string
+		tokenValueHash         string
+		hmacHeader             string
+		hmacSecretCiphertext   []byte
+		hmacOptionsJSON        string
+		verifier               string
+		publicKeySignatureJSON string
+		jwtJSON                string
+		jwtSecretCiphertext    []byte
+		replayProtectionJSON   string
+		mutualTLSJSON          string
+		allowedCIDRsJSON       string
+		redactionJSON          string
+		responseJSON           string
+		forwardURL             string
+		managementSecretHash   string
+		maxMessages            int
+		expiresAt              time.Time
+	)
+
+	if err := scanner.Scan(&id, &username, &passwordHash, &tokenName, &tokenValueHash, &hmacHeader, &hmacSecretCiphertext, &hmacOptionsJSON, &verifier, &publicKeySignatureJSON, &jwtJSON, &jwtSecretCiphertext, &replayProtectionJSON, &mutualTLSJSON, &allowedCIDRsJSON, &redactionJSON, &responseJSON, &forwardURL, &managementSecretHash, &maxMessages, &expiresAt); err != nil {
+		return nil, err
+	}
+
+	hmacSecret := ""
+	if len(hmacSecretCiphertext) > 0 {
+		decryptedSecret, err := s.cipher.Decrypt(hmacSecretCiphertext)
+		if err != nil {
+			return nil, err
+		}
+		hmacSecret = decryptedSecret
+	}
+
+	response, err := unmarshalWebhookResponse(responseJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	hmacOptions, err := unmarshalHMACOptions(hmacOptionsJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKeySignature, err := unmarshalPublicKeySignature(publicKeySignatureJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	jwt, err := unmarshalJWTValidation(jwtJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	jwtSecret := ""
+	if len(jwtSecretCiphertext) > 0 {
+		jwtSecret, err = s.cipher.Decrypt(jwtSecretCiphertext)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	replayProtection, err := unmarshalReplayProtection(replayProtectionJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	mutualTLS, err := unmarshalMutualTLS(mutualTLSJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	allowedCIDRs, err := unmarshalAllowedCIDRs(allowedCIDRsJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	redaction, err := unmarshalRedaction(redactionJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := model.NewStoredWebhook(id, username, passwordHash, tokenName, tokenValueHash, hmacHeader, hmacSecret, expiresAt)
+	webhook.HMACOptions = hmacOptions
+	webhook.Verifier = model.Verifier(verifier)
+	webhook.PublicKeySignature = publicKeySignature
+	webhook.JWT = jwt
+	webhook.SetJWTSecret(jwtSecret)
+	webhook.ReplayProtection = replayProtection
+	webhook.MutualTLS = mutualTLS
+	webhook.AllowedCIDRs = allowedCIDRs
+	webhook.Redaction = redaction
+	webhook.Response = response
+	webhook.ForwardURL = forwardURL
+	webhook.MaxMessages = maxMessages
+	webhook.SetManagementSecretHash(managementSecretHash)
+
+	return webhook, nil
+}
+
+// scanMessage reads a message row, decrypting payload and headers when the
+// row was stored encrypted.
+func (s *PostgresStore) scanMessage(scanner rowScanner, webhookID string) (*model.Message, error) {
+	var (
+		message               model.Message
+		payload               []byte
+		headersJSON           string
+		forwardJSON           string
+		encrypted             int
+		duplicate             int
+		clientCertificateJSON string
+		tlsVersion            string
+		tlsCipherSuite        string
+		tlsServerName         string
+	)
+
+	if err := scanner.Scan(&message.ID, &message.Method, &message.Path, &message.Query, &payload, &headersJSON, &message.StatusCode, &message.ErrorMessage, &forwardJSON, &message.Time, &encrypted, &duplicate, &clientCertificateJSON, &message.RemoteIP, &message.Protocol, &message.Host, &message.ContentLength, &tlsVersion, &tlsCipherSuite, &tlsServerName, &message.ProcessingMicros); err != nil {
+		return nil, err
+	}
+
+	if encrypted != 0 {
+		var err error
+		payload, headersJSON, err = s.cipher.openMessage(webhookID, message.ID, payload, headersJSON)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	message.Headers = map[string][]string{}
+	if headersJSON != "" && headersJSON != "null" {
+		if err := json.Unmarshal([]byte(headersJSON), &message.Headers); err != nil {
+			return nil, err
+		}
+	}
+
+	forward, err := unmarshalForwardResult(forwardJSON)
+	if err != nil {
+		return nil, err
+	}
+	clientCertificate, err := unmarshalClientCertificate(clientCertificateJSON)
+	if err != nil {
+		return nil, err
+	}
+	message.Forward = forward
+	message.Duplicate = duplicate != 0
+	message.ClientCertificate = clientCertificate
+	message.TLS = tlsDetailsFromColumns(tlsVersion, tlsCipherSuite, tlsServerName)
+	message.SetBody(payload)
+	message.Time = message.Time.UTC()
+
+	return &message, nil
+}
+
+func postgresReceivedAt(operator string, bound time.Time) (string, interface{}) {
+	return "received_at " + operator + " ?", bound.UTC()
+}
+
+// postgresPlaceholders rewrites the ? placeholders shared with the SQLite
+// queries into PostgreSQL's numbered $n form.
+func postgresPlaceholders(query string) string {
+	var rewritten strings.Builder
+	position := 0
+	for _, char := range query {
+		if char == '?' {
+			position++
+			rewritten.WriteString("$" + strconv.Itoa(position))
+			continue
+		}
+		rewritten.WriteRune(char)
+	}
+
+	return rewritten.String()
+}
+
+// postgresExecer lets the shared SQLite statements run against PostgreSQL.
+type postgresExecer struct {
+	db sqlExecer
+}
+
+func (e postgresExecer) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return e.db.Exec(postgresPlaceholders(query), args...)
+}
@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// keyedCiphertextVersion marks ciphertexts that carry the ID of the key they
+// were sealed with. Ciphertexts written before key rotation existed start
+// directly with the nonce.
+const keyedCiphertextVersion byte = 1
+
+const keyIDSize = 4
+
+// StoreOption configures a persistent store.
+type StoreOption func(*storeOptions)
+
+type storeOptions struct {
+	previousEncryptionKeys []string
+	encryptMessages        bool
+}
+
+// WithPreviousEncryptionKeys adds retired keys that are still accepted for
+// decryption. New ciphertexts are always sealed with the current key.
+func WithPreviousEncryptionKeys(keys ...string) StoreOption {
+	return func(options *storeOptions) {
+		options.previousEncryptionKeys = append(options.previousEncryptionKeys, keys...)
+	}
+}
+
+func applyStoreOptions(options []StoreOption) storeOptions {
+	applied := storeOptions{}
+	for _, option := range options {
+		option(&applied)
+	}
+
+	return applied
+}
+
+// secretCipher is an AES-GCM keyring. It encrypts with the current key and
+// decrypts with any key in the ring, identified by the key ID prefixed to the
+// ciphertext.
+type secretCipher struct {
+	current *keyringEntry
+	keys    []*keyringEntry
+}
+
+type keyringEntry struct {
+	id   [keyIDSize]byte
+	aead cipher.AEAD
+}
+
+func newSecretCipher(encryptionKey string, previousKeys ...string) (*secretCipher, error) {
+	current, err := newKeyringEntry(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	secretCipher := &secretCipher{current: current, keys: []*keyringEntry{current}}
+	for i, previousKey := range previousKeys {
+		entry, err := newKeyringEntry(previousKey)
+		if err != nil {
+			return nil, fmt.Errorf("previous encryption key %d: %w", i+1, err)
+		}
+		if secretCipher.key(entry.id) != nil {
+			continue
+		}
+		secretCipher.keys = append(secretCipher.keys, entry)
+	}
+
+	return secretCipher, nil
+}
+
+func newKeyringEntry(encryptionKey string) (*keyringEntry, error) {
+	key, err := decodeEncryptionKey(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &keyringEntry{aead: aead}
+	digest := sha256.Sum256(key)
+	copy(entry.id[:], digest[:keyIDSize])
+
+	return entry, nil
+}
+
+func (c *secretCipher) Encrypt(plaintext string) ([]byte, error) {
+	return c.Seal([]byte(plaintext), nil)
+}
+
+func (c *secretCipher) Decrypt(ciphertext []byte) (string, error) {
+	plaintext, err := c.Open(ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// Seal encrypts plaintext with the current key, binding the associated data.
+func (c *secretCipher) Seal(plaintext []byte, associatedData []byte) ([]byte, error) {
+	nonce := make([]byte, c.current.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := make([]byte, 0, 1+keyIDSize+len(nonce)+len(plaintext)+c.current.aead.Overhead())
+	sealed = append(sealed, keyedCiphertextVersion)
+	sealed = append(sealed, c.current.id[:]...)
+	sealed = append(sealed, nonce...)
+	return c.current.aead.Seal(sealed, nonce, plaintext, associatedData), nil
+}
+
+// Open decrypts a ciphertext sealed with any key in the ring.
+func (c *secretCipher) Open(ciphertext []byte, associatedData []byte) ([]byte, error) {
+	if len(ciphertext) > 1+keyIDSize && ciphertext[0] == keyedCiphertextVersion {
+		var id [keyIDSize]byte
+		copy(id[:], ciphertext[1:1+keyIDSize])
+		if entry := c.key(id); entry != nil {
+			if plaintext, err := entry.open(ciphertext[1+keyIDSize:], associatedData); err == nil {
+				return plaintext, nil
+			}
+		}
+	}
+
+	// Unversioned ciphertexts, or a nonce that merely looks like a key ID.
+	for _, entry := range c.keys {
+		if plaintext, err := entry.open(ciphertext, associatedData); err == nil {
+			return plaintext, nil
+		}
+	}
+
+	return nil, errors.New("ciphertext cannot be decrypted with any configured encryption key")
+}
+
+// Current reports whether the ciphertext is already sealed with the current key.
+func (c *secretCipher) Current(ciphertext []byte) bool {
+	return len(ciphertext) > 1+keyIDSize &&
+		ciphertext[0] == keyedCiphertextVersion &&
+		bytes.Equal(ciphertext[1:1+keyIDSize], c.current.id[:])
+}
+
+func (c *secretCipher) key(id [keyIDSize]byte) *keyringEntry {
+	for _, entry := range c.keys {
+		if entry.id == id {
+			return entry
+		}
+	}
+
+	return nil
+}
+
+func (e *keyringEntry) open(ciphertext []byte, associatedData []byte) ([]byte, error) {
+	nonceSize := e.aead.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return nil, errors.New("ciphertext is too short")
+	}
+
+	return e.aead.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], associatedData)
+}
+
+func decodeEncryptionKey(encryptionKey string) ([]byte, error) {
+	trimmedKey := strings.TrimSpace(encryptionKey)
+	if trimmedKey == "" {
+		return nil, errors.New("encryption key must not be empty")
+	}
+
+	decoders := []func(string) ([]byte, error){
+		base64.StdEncoding.DecodeString,
+		base64.RawStdEncoding.DecodeString,
+		hex.DecodeString,
+	}
+
+	for _, decode := range decoders {
+		decoded, err := decode(trimmedKey)
+		if err != nil {
+			continue
+		}
+
+		if len(decoded) == 32 {
+			return decoded, nil
+		}
+	}
+
+	return nil, errors.New("encryption key must be base64 or hex encoded 32-byte data")
+}
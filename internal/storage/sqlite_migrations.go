@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+)
+
+// sqliteMigrationFiles holds the ordered up-migrations of the SQLite schema.
+// Files are named NNNN_description.sql and applied in version order; released
+// migrations must never be edited, only followed by new ones.
+//
+//go:embed migrations/sqlite/*.sql
+var sqliteMigrationFiles embed.FS
+
+const sqliteMigrationsDir = "migrations/sqlite"
+
+const sqliteSchemaVersionTable = `
+CREATE TABLE IF NOT EXISTS schema_version (
+	version INTEGER PRIMARY KEY,
+	applied_at TEXT NOT NULL
+);
+`
+
+// sqliteAddedColumns lists columns added to databases created before schema
+// versioning. They are added while adopting such a database at version 1.
+var sqliteAddedColumns = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{table: "webhooks", column: "response_json", definition: "TEXT NOT NULL DEFAULT ''"},
+	{table: "webhooks", column: "forward_url", definition: "TEXT NOT NULL DEFAULT ''"},
+	{table: "webhooks", column: "management_secret_hash", definition: "TEXT NOT NULL DEFAULT ''"},
+	{table: "webhooks", column: "max_messages", definition: "INTEGER NOT NULL DEFAULT 100"},
+	{table: "messages", column: "payload_encoding", definition: "TEXT NOT NULL DEFAULT ''"},
+	{table: "messages", column: "forward_json", definition: "TEXT NOT NULL DEFAULT ''"},
+	{table: "messages", column: "forward_failed", definition: "INTEGER NOT NULL DEFAULT 0"},
+}
+
+// SQLiteSchemaVersion returns the schema version this binary migrates to.
+func SQLiteSchemaVersion() (int, error) {
+	migrations, err := loadMigrations(sqliteMigrationFiles, sqliteMigrationsDir)
+	if err != nil {
+		return 0, err
+	}
+
+	return migrations[len(migrations)-1].version, nil
+}
+
+// migrate brings the database up to the latest embedded migration. Each
+// migration runs in its own transaction together with its schema_version row,
+// so a failed upgrade leaves the database at the previous version.
+func (s *SQLiteStore) migrate() error {
+	migrations, err := loadMigrations(sqliteMigrationFiles, sqliteMigrationsDir)
+	if err != nil {
+		return err
+	}
+	latestVersion := migrations[len(migrations)-1].version
+
+	if _, err := s.db.Exec(sqliteSchemaVersionTable); err != nil {
+		return err
+	}
+
+	var currentVersion int
+	if err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&currentVersion); err != nil {
+		return err
+	}
+	if currentVersion > latestVersion {
+		return &UnsupportedSchemaVersionError{DatabaseVersion: currentVersion, SupportedVersion: latestVersion}
+	}
+
+	for _, migration := range migrations {
+		if migration.version <= currentVersion {
+			continue
+		}
+		if err := s.applyMigration(migration); err != nil {
+			return fmt.Errorf("apply migration %s: %w", migration.name, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *SQLiteStore) applyMigration(migration schemaMigration) (err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rollbackErr := tx.Rollback(); err == nil && rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			err = rollbackErr
+		}
+	}()
+
+	if migration.version == 1 {
+		if err := adoptUnversionedSchema(tx); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(migration.statements); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO schema_version (version, applied_at) VALUES (?, ?)`,
+		migration.version,
+		time.Now().UTC().Format(sqliteTimeFormat),
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// adoptUnversionedSchema upgrades a database created before schema versioning
+// so that the first migration finds every column it expects. It does nothing
+// for new databases.
+func adoptUnversionedSchema(tx *sql.Tx) error {
+	exists, err := tableExists(tx, "messages")
+	if err != nil || !exists {
+		return err
+	}
+
+	for _, added := range sqliteAddedColumns {
+		exists, err := columnExists(tx, added.table, added.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", added.table, added.column, added.definition)); err != nil {
+			return err
+		}
+	}
+
+	return migrateLegacyPayloads(tx)
+}
+
+// migrateLegacyPayloads converts payloads stored as TEXT before binary capture
+// existed into BLOBs and records their encoding.
+func migrateLegacyPayloads(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT row_id, CAST(payload AS BLOB) FROM messages WHERE payload_encoding = ''`)
+	if err != nil {
+		return err
+	}
+
+	type legacyPayload struct {
+		rowID   int64
+		payload []byte
+	}
+	var legacyPayloads []legacyPayload
+	for rows.Next() {
+		var legacy legacyPayload
+		if err := rows.Scan(&legacy.rowID, &legacy.payload); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		legacyPayloads = append(legacyPayloads, legacy)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return err
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	for _, legacy := range legacyPayloads {
+		message := &model.Message{}
+		message.SetBody(legacy.payload)
+		if _, err := tx.Exec(
+			`UPDATE messages SET payload = ?, payload_encoding = ? WHERE row_id = ?`,
+			message.Body(),
+			string(message.PayloadEncoding),
+			legacy.rowID,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func tableExists(tx *sql.Tx, table string) (bool, error) {
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&count); err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func columnExists(tx *sql.Tx, table string, column string) (exists bool, err error) {
+	rows, err := tx.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); err == nil && closeErr != nil {
+			err = closeErr
+		}
+	}()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+
+	return false, rows.Err()
+}
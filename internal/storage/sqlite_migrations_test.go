@@ -0,0 +1,163 @@
+package storage_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/achawki/webhook-receiver/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unversionedSchema is the schema databases were created with before
+// versioned migrations existed.
+const unversionedSchema = `
+CREATE TABLE webhooks (
+	row_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	id TEXT NOT NULL UNIQUE,
+	username TEXT NOT NULL DEFAULT '',
+	password_hash TEXT NOT NULL DEFAULT '',
+	token_name TEXT NOT NULL DEFAULT '',
+	token_value_hash TEXT NOT NULL DEFAULT '',
+	hmac_header TEXT NOT NULL DEFAULT '',
+	hmac_secret_ciphertext BLOB,
+	response_json TEXT NOT NULL DEFAULT '',
+	forward_url TEXT NOT NULL DEFAULT '',
+	management_secret_hash TEXT NOT NULL DEFAULT '',
+	max_messages INTEGER NOT NULL DEFAULT 100,
+	expires_at TEXT NOT NULL
+);
+
+CREATE TABLE messages (
+	row_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	webhook_id TEXT NOT NULL,
+	method TEXT NOT NULL,
+	path TEXT NOT NULL,
+	query TEXT NOT NULL DEFAULT '',
+	payload BLOB NOT NULL,
+	payload_encoding TEXT NOT NULL DEFAULT '',
+	headers_json TEXT NOT NULL DEFAULT '{}',
+	status_code INTEGER NOT NULL DEFAULT 200,
+	error_message TEXT NOT NULL DEFAULT '',
+	forward_json TEXT NOT NULL DEFAULT '',
+	forward_failed INTEGER NOT NULL DEFAULT 0,
+	received_at TEXT NOT NULL,
+	FOREIGN KEY (webhook_id) REFERENCES webhooks(id)
+);
+
+CREATE TABLE replay_attempts (
+	row_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	message_row_id INTEGER NOT NULL,
+	target_url TEXT NOT NULL,
+	status_code INTEGER NOT NULL DEFAULT 0,
+	latency_ms INTEGER NOT NULL DEFAULT 0,
+	response_body TEXT NOT NULL DEFAULT '',
+	error_message TEXT NOT NULL DEFAULT '',
+	attempted_at TEXT NOT NULL,
+	FOREIGN KEY (message_row_id) REFERENCES messages(row_id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_webhooks_expires_at ON webhooks(expires_at);
+CREATE INDEX idx_messages_webhook_row_id ON messages(webhook_id, row_id);
+CREATE INDEX idx_replay_attempts_message_row_id ON replay_attempts(message_row_id, row_id);
+`
+
+func TestSQLiteStoreUpgradesUnversionedCurrentSchema(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "webhook-receiver.db")
+	db, err := sql.Open("sqlite3", storePath)
+	require.NoError(t, err)
+	_, err = db.Exec(unversionedSchema + `
+INSERT INTO webhooks (id, username, max_messages, expires_at) VALUES ('existing', 'alice', 5, '2999-01-01T00:00:00Z');
+INSERT INTO messages (webhook_id, method, path, payload, payload_encoding, received_at) VALUES ('existing', 'POST', '/hooks/existing', CAST('{}' AS BLOB), 'utf8', '2026-01-01T00:00:00Z');`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	store, err := storage.NewSQLiteStore(storePath, testEncryptionKey)
+	require.NoError(t, err)
+
+	webhook, err := store.GetWebhook("existing")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", webhook.Username)
+	assert.Equal(t, 5, webhook.MaxMessages)
+
+	page, err := store.GetMessagePageForWebhook("existing", 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAll})
+	require.NoError(t, err)
+	require.Len(t, page.Messages, 1)
+	assert.Equal(t, "{}", page.Messages[0].Payload)
+	require.NoError(t, store.Close())
+
+	// Reopening must not apply any migration twice.
+	store, err = storage.NewSQLiteStore(storePath, testEncryptionKey)
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	latestVersion, err := storage.SQLiteSchemaVersion()
+	require.NoError(t, err)
+	versions := schemaVersions(t, storePath)
+	require.Len(t, versions, latestVersion)
+	assert.Equal(t, latestVersion, versions[len(versions)-1])
+}
+
+func TestSQLiteStoreRecordsSchemaVersionForNewDatabase(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "webhook-receiver.db")
+	store, err := storage.NewSQLiteStore(storePath, testEncryptionKey)
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	latestVersion, err := storage.SQLiteSchemaVersion()
+	require.NoError(t, err)
+	expectedVersions := make([]int, 0, latestVersion)
+	for version := 1; version <= latestVersion; version++ {
+		expectedVersions = append(expectedVersions, version)
+	}
+	assert.Equal(t, expectedVersions, schemaVersions(t, storePath))
+}
+
+func TestSQLiteStoreRefusesNewerSchemaVersion(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "webhook-receiver.db")
+	store, err := storage.NewSQLiteStore(storePath, testEncryptionKey)
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	latestVersion, err := storage.SQLiteSchemaVersion()
+	require.NoError(t, err)
+	db, err := sql.Open("sqlite3", storePath)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO schema_version (version, applied_at) VALUES (?, '2999-01-01T00:00:00Z')`, latestVersion+1)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	_, err = storage.NewSQLiteStore(storePath, testEncryptionKey)
+	var versionErr *storage.UnsupportedSchemaVersionError
+	require.ErrorAs(t, err, &versionErr)
+	assert.Equal(t, latestVersion+1, versionErr.DatabaseVersion)
+	assert.Equal(t, latestVersion, versionErr.SupportedVersion)
+}
+
+func schemaVersions(t *testing.T, storePath string) []int {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", storePath)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+
+	rows, err := db.Query(`SELECT version FROM schema_version ORDER BY version`)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, rows.Close())
+	}()
+
+	versions := []int{}
+	for rows.Next() {
+		var version int
+		require.NoError(t, rows.Scan(&version))
+		versions = append(versions, version)
+	}
+	require.NoError(t, rows.Err())
+
+	return versions
+}
@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+)
+
+// sqliteBareJSONKey matches object keys that need no quoting in SQLite JSON
+// paths.
+var sqliteBareJSONKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// sqliteMessageSearchTriggers keep message_search in step with inserted,
+// re-encoded and deleted messages, including rows removed when messages are
+// trimmed or their webhook is deleted. Encrypted and binary payloads are never
+// indexed.
+var sqliteMessageSearchTriggers = []struct {
+	name       string
+	definition string
+}{
+	{
+		name: "message_search_insert",
+		definition: `CREATE TRIGGER message_search_insert AFTER INSERT ON messages
+			WHEN NEW.encrypted = 0 AND NEW.payload_encoding = 'utf8'
+			BEGIN
+				INSERT INTO message_search (rowid, payload) VALUES (NEW.row_id, CAST(NEW.payload AS TEXT));
+			END`,
+	},
+	{
+		name: "message_search_update",
+		definition: `CREATE TRIGGER message_search_update AFTER UPDATE OF payload, payload_encoding, encrypted ON messages
+			BEGIN
+				DELETE FROM message_search WHERE rowid = OLD.row_id;
+				INSERT INTO message_search (rowid, payload)
+					SELECT NEW.row_id, CAST(NEW.payload AS TEXT)
+					WHERE NEW.encrypted = 0 AND NEW.payload_encoding = 'utf8';
+			END`,
+	},
+	{
+		name: "message_search_delete",
+		definition: `CREATE TRIGGER message_search_delete AFTER DELETE ON messages
+			BEGIN
+				DELETE FROM message_search WHERE rowid = OLD.row_id;
+			END`,
+	},
+}
+
+// initMessageSearch maintains the FTS5 index over message payloads. FTS5 is
+// only compiled into SQLite with the sqlite_fts5 build tag, so the index is
+// set up here rather than in a migration: without FTS5 the triggers are
+// dropped and text searches match payloads in Go instead. When the triggers
+// have to be created, rows captured in the meantime are indexed from scratch.
+func (s *SQLiteStore) initMessageSearch() (err error) {
+	var fts5 bool
+	if err := s.db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&fts5); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rollbackErr := tx.Rollback(); err == nil && rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			err = rollbackErr
+		}
+	}()
+
+	if !fts5 {
+		for _, trigger := range sqliteMessageSearchTriggers {
+			if _, err := tx.Exec(`DROP TRIGGER IF EXISTS ` + trigger.name); err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	}
+
+	if _, err := tx.Exec(
+		`CREATE VIRTUAL TABLE IF NOT EXISTS message_search USING fts5(
+			payload,
+			content = '',
+			contentless_delete = 1,
+			tokenize = 'unicode61 remove_diacritics 0'
+		)`,
+	); err != nil {
+		return err
+	}
+
+	var existingTriggers int
+	if err := tx.QueryRow(
+		`SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'message\_search\_%' ESCAPE '\'`,
+	).Scan(&existingTriggers); err != nil {
+		return err
+	}
+	if existingTriggers != len(sqliteMessageSearchTriggers) {
+		for _, trigger := range sqliteMessageSearchTriggers {
+			if _, err := tx.Exec(`DROP TRIGGER IF EXISTS ` + trigger.name); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(trigger.definition); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec(`INSERT INTO message_search (message_search) VALUES ('delete-all')`); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(
+			`INSERT INTO message_search (rowid, payload)
+			 SELECT row_id, CAST(payload AS TEXT) FROM messages
+			 WHERE encrypted = 0 AND payload_encoding = 'utf8'`,
+		); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.fullTextSearch = true
+	return nil
+}
+
+// fullTextQuery matches every term as a word or word prefix. Terms only hold
+// letters and digits, so quoting them cannot change the query syntax.
+func fullTextQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + term + `"*`
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// sqliteJSONFilterCondition translates a JSON filter into json_type and
+// json_extract calls over text payloads. It reports false for filters SQLite
+// paths cannot express, such as wildcards, which are then only matched in Go.
+// Encrypted rows pass the condition so that they can be matched after
+// decryption.
+func sqliteJSONFilterCondition(filter *model.JSONFilter) (string, []interface{}, bool) {
+	path, ok := sqliteJSONPath(filter.Path)
+	if !ok {
+		return "", nil, false
+	}
+
+	const document = "CAST(payload AS TEXT)"
+	jsonType := "json_type(" + document + ", ?)"
+	jsonValue := "json_extract(" + document + ", ?)"
+
+	var (
+		condition string
+		args      []interface{}
+	)
+	switch literal := filter.Value.(type) {
+	case nil, bool:
+		typeName := "null"
+		if literal != nil {
+			typeName = fmt.Sprint(literal)
+		}
+		if filter.Operator == model.JSONFilterExists {
+			condition, args = jsonType+" IS NOT NULL", []interface{}{path}
+		} else if filter.Operator == model.JSONFilterEqual {
+			condition, args = jsonType+" = ?", []interface{}{path, typeName}
+		} else {
+			condition, args = jsonType+" != ?", []interface{}{path, typeName}
+		}
+	case string, json.Number:
+		typeCondition := jsonType + " = 'text'"
+		var value interface{} = literal
+		if number, ok := literal.(json.Number); ok {
+			typeCondition = jsonType + " IN ('integer', 'real')"
+			if integer, err := number.Int64(); err == nil {
+				value = integer
+			} else if float, err := number.Float64(); err == nil {
+				value = float
+			} else {
+				return "", nil, false
+			}
+		}
+		if filter.Operator == model.JSONFilterNotEqual {
+			condition = jsonType + " IS NOT NULL AND NOT (" + typeCondition + " AND " + jsonValue + " = ?)"
+			args = []interface{}{path, path, path, value}
+		} else {
+			condition = typeCondition + " AND " + jsonValue + " " + string(filter.Operator) + " ?"
+			args = []interface{}{path, path, value}
+		}
+	default:
+		return "", nil, false
+	}
+
+	return "(encrypted = 1 OR (payload_encoding = 'utf8' AND CASE WHEN json_valid(" + document + ") THEN " + condition + " ELSE 0 END))", args, true
+}
+
+// sqliteJSONPath spells a path without wildcards in SQLite's JSON path
+// syntax, quoting keys that are not plain identifiers.
+func sqliteJSONPath(path model.JSONPath) (string, bool) {
+	steps, ok := path.Steps()
+	if !ok {
+		return "", false
+	}
+
+	var builder strings.Builder
+	builder.WriteString("$")
+	for _, step := range steps {
+		switch {
+		case step.IsIndex:
+			fmt.Fprintf(&builder, "[%d]", step.Index)
+		case sqliteBareJSONKey.MatchString(step.Key):
+			builder.WriteString("." + step.Key)
+		case !strings.Contains(step.Key, `"`):
+			builder.WriteString(`."` + step.Key + `"`)
+		default:
+			return "", false
+		}
+	}
+
+	return builder.String(), true
+}
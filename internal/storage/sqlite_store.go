@@ -1,19 +1,12 @@
 package storage
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
 	"database/sql"
-	"encoding/base64"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/achawki/webhook-receiver/internal/model"
@@ -23,53 +16,25 @@ import (
 )
 
 const sqliteTimeFormat = time.RFC3339Nano
-const webhookTTL = 48 * time.Hour
+const webhookTTL = model.DefaultWebhookTTL
 const defaultMessagePageSize = 25
 const maxMessagePageSize = 100
-const maxMessagesPerWebhook = 100
-
-const sqliteIndexes = `
-CREATE INDEX IF NOT EXISTS idx_webhooks_expires_at ON webhooks(expires_at);
-CREATE INDEX IF NOT EXISTS idx_messages_webhook_row_id ON messages(webhook_id, row_id);
-`
-
-const sqliteSchema = `
-CREATE TABLE IF NOT EXISTS webhooks (
-	row_id INTEGER PRIMARY KEY AUTOINCREMENT,
-	id TEXT NOT NULL UNIQUE,
-	username TEXT NOT NULL DEFAULT '',
-	password_hash TEXT NOT NULL DEFAULT '',
-	token_name TEXT NOT NULL DEFAULT '',
-	token_value_hash TEXT NOT NULL DEFAULT '',
-	hmac_header TEXT NOT NULL DEFAULT '',
-	hmac_secret_ciphertext BLOB,
-	expires_at TEXT NOT NULL
-);
-
-CREATE TABLE IF NOT EXISTS messages (
-	row_id INTEGER PRIMARY KEY AUTOINCREMENT,
-	webhook_id TEXT NOT NULL,
-	method TEXT NOT NULL,
-	path TEXT NOT NULL,
-	query TEXT NOT NULL DEFAULT '',
-	payload TEXT NOT NULL,
-	headers_json TEXT NOT NULL DEFAULT '{}',
-	status_code INTEGER NOT NULL DEFAULT 200,
-	error_message TEXT NOT NULL DEFAULT '',
-	received_at TEXT NOT NULL,
-	FOREIGN KEY (webhook_id) REFERENCES webhooks(id)
-);
-`
+const defaultMaxMessagesPerWebhook = model.DefaultMaxMessages
 
 // SQLiteStore persists webhooks and messages in SQLite.
 type SQLiteStore struct {
-	db     *sql.DB
-	cipher *secretCipher
+	db              *sql.DB
+	cipher          *secretCipher
+	encryptMessages bool
+	// fullTextSearch is set when SQLite was built with FTS5 and payloads are
+	// indexed in message_search.
+	fullTextSearch bool
 }
 
 // NewSQLiteStore creates or loads a SQLite-backed store.
-func NewSQLiteStore(path string, encryptionKey string) (*SQLiteStore, error) {
-	secretCipher, err := newSecretCipher(encryptionKey)
+func NewSQLiteStore(path string, encryptionKey string, options ...StoreOption) (*SQLiteStore, error) {
+	storeOptions := applyStoreOptions(options)
+	secretCipher, err := newSecretCipher(encryptionKey, storeOptions.previousEncryptionKeys...)
 	if err != nil {
 		return nil, err
 	}
@@ -86,7 +51,7 @@ func NewSQLiteStore(path string, encryptionKey string) (*SQLiteStore, error) {
 	db.SetMaxOpenConns(1)
 	db.SetMaxIdleConns(1)
 
-	store := &SQLiteStore{db: db, cipher: secretCipher}
+	store := &SQLiteStore{db: db, cipher: secretCipher, encryptMessages: storeOptions.encryptMessages}
 	if err := store.init(); err != nil {
 		if closeErr := db.Close(); closeErr != nil {
 			return nil, errors.Join(err, closeErr)
@@ -111,6 +76,9 @@ func (s *SQLiteStore) InsertWebhook(webhook *model.Webhook) (string, error) {
 	} else {
 		webhook.ExpiresAt = webhook.ExpiresAt.UTC()
 	}
+	if webhook.MaxMessages <= 0 {
+		webhook.MaxMessages = defaultMaxMessagesPerWebhook
+	}
 
 	var encryptedHMACSecret []byte
 	var err error
@@ -122,9 +90,57 @@ func (s *SQLiteStore) InsertWebhook(webhook *model.Webhook) (string, error) {
 		}
 	}
 
+	responseJSON, err := marshalWebhookResponse(webhook.Response)
+	if err != nil {
+		webhook.ID = ""
+		return "", err
+	}
+
+	hmacOptionsJSON, err := marshalHMACOptions(webhook.HMACOptions)
+	if err != nil {
+		webhook.ID = ""
+		return "", err
+	}
+
+	publicKeySignatureJSON, err := marshalPublicKeySignature(webhook.PublicKeySignature)
+	if err != nil {
+		webhook.ID = ""
+		return "", err
+	}
+
+	jwtJSON, encryptedJWTSecret, err := encodeJWT(s.cipher, webhook)
+	if err != nil {
+		webhook.ID = ""
+		return "", err
+	}
+
+	replayProtectionJSON, err := marshalReplayProtection(webhook.ReplayProtection)
+	if err != nil {
+		webhook.ID = ""
+		return "", err
+	}
+
+	mutualTLSJSON, err := marshalMutualTLS(webhook.MutualTLS)
+	if err != nil {
+		webhook.ID = ""
+		return "", err
+	}
+
+	allowedCIDRsJSON, err := marshalAllowedCIDRs(webhook.AllowedCIDRs)
+	if err != nil {
+		webhook.ID = ""
+		return "", err
+	}
+
+	redactionJSON, err := marshalRedaction(webhook.Redaction)
+	if err != nil {
+		webhook.ID = ""
+		return "", err
+	}
+
 	_, err = s.db.Exec(
-		`INSERT INTO webhooks (id, username, password_hash, token_name, token_value_hash, hmac_header, hmac_secret_ciphertext, expires_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		`INSERT INTO webhooks (id, username, password_hash, token_name, token_value_hash, hmac_header, hmac_secret_ciphertext, hmac_options_json, verifier, public_key_signature_json, jwt_json, jwt_secret_ciphertext, replay_protection_json, mutual_tls_json, allowed_cidrs_json, redaction_json, response_json, forward_url, management_secret_hash, max_messages, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		webhook.ID,
 		webhook.Username,
 		webhook.PasswordHash(),
@@ -132,6 +148,19 @@ func (s *SQLiteStore) InsertWebhook(webhook *model.Webhook) (string, error) {
 		webhook.TokenValueHash(),
 		webhook.HMACHeader,
 		encryptedHMACSecret,
+		hmacOptionsJSON,
+		string(webhook.Verifier),
+		publicKeySignatureJSON,
+		jwtJSON,
+		encryptedJWTSecret,
+		replayProtectionJSON,
+		mutualTLSJSON,
+		allowedCIDRsJSON,
+		redactionJSON,
+		responseJSON,
+		webhook.ForwardURL,
+		webhook.ManagementSecretHash(),
+		webhook.MaxMessages,
 		webhook.ExpiresAt.Format(sqliteTimeFormat),
 	)
 	if err != nil {
@@ -146,7 +175,7 @@ func (s *SQLiteStore) InsertWebhook(webhook *model.Webhook) (string, error) {
 func (s *SQLiteStore) GetWebhook(id string) (*model.Webhook, error) {
 	now := time.Now().UTC().Format(sqliteTimeFormat)
 	row := s.db.QueryRow(
-		`SELECT id, username, password_hash, token_name, token_value_hash, hmac_header, hmac_secret_ciphertext, expires_at
+		`SELECT id, username, password_hash, token_name, token_value_hash, hmac_header, hmac_secret_ciphertext, hmac_options_json, verifier, public_key_signature_json, jwt_json, jwt_secret_ciphertext, replay_protection_json, mutual_tls_json, allowed_cidrs_json, redaction_json, response_json, forward_url, management_secret_hash, max_messages, expires_at
 		 FROM webhooks WHERE id = ? AND expires_at > ?`,
 		id,
 		now,
@@ -167,7 +196,7 @@ func (s *SQLiteStore) GetWebhook(id string) (*model.Webhook, error) {
 func (s *SQLiteStore) ListWebhooks() (webhooks []*model.Webhook, err error) {
 	now := time.Now().UTC().Format(sqliteTimeFormat)
 	rows, err := s.db.Query(
-		`SELECT id, username, password_hash, token_name, token_value_hash, hmac_header, hmac_secret_ciphertext, expires_at
+		`SELECT id, username, password_hash, token_name, token_value_hash, hmac_header, hmac_secret_ciphertext, hmac_options_json, verifier, public_key_signature_json, jwt_json, jwt_secret_ciphertext, replay_protection_json, mutual_tls_json, allowed_cidrs_json, redaction_json, response_json, forward_url, management_secret_hash, max_messages, expires_at
 		 FROM webhooks
 		 WHERE expires_at > ?
 		 ORDER BY row_id DESC`,
@@ -194,6 +223,137 @@ func (s *SQLiteStore) ListWebhooks() (webhooks []*model.Webhook, err error) {
 	return webhooks, rows.Err()
 }
 
+// UpdateWebhook persists the configuration and retention of an existing webhook.
+// Messages beyond a lowered message cap are deleted right away.
+func (s *SQLiteStore) UpdateWebhook(webhook *model.Webhook) error {
+	if webhook.MaxMessages <= 0 {
+		webhook.MaxMessages = defaultMaxMessagesPerWebhook
+	}
+
+	var encryptedHMACSecret []byte
+	var err error
+	if webhook.HasHMAC() {
+		encryptedHMACSecret, err = s.cipher.Encrypt(webhook.HMACSecret())
+		if err != nil {
+			return err
+		}
+	}
+
+	responseJSON, err := marshalWebhookResponse(webhook.Response)
+	if err != nil {
+		return err
+	}
+
+	hmacOptionsJSON, err := marshalHMACOptions(webhook.HMACOptions)
+	if err != nil {
+		return err
+	}
+
+	publicKeySignatureJSON, err := marshalPublicKeySignature(webhook.PublicKeySignature)
+	if err != nil {
+		return err
+	}
+
+	jwtJSON, encryptedJWTSecret, err := encodeJWT(s.cipher, webhook)
+	if err != nil {
+		return err
+	}
+
+	replayProtectionJSON, err := marshalReplayProtection(webhook.ReplayProtection)
+	if err != nil {
+		return err
+	}
+
+	mutualTLSJSON, err := marshalMutualTLS(webhook.MutualTLS)
+	if err != nil {
+		return err
+	}
+
+	allowedCIDRsJSON, err := marshalAllowedCIDRs(webhook.AllowedCIDRs)
+	if err != nil {
+		return err
+	}
+
+	redactionJSON, err := marshalRedaction(webhook.Redaction)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC().Format(sqliteTimeFormat)
+	result, err := s.db.Exec(
+		`UPDATE webhooks
+		 SET username = ?, password_hash = ?, token_name = ?, token_value_hash = ?, hmac_header = ?,
+		     hmac_secret_ciphertext = ?, hmac_options_json = ?, verifier = ?, public_key_signature_json = ?, jwt_json = ?, jwt_secret_ciphertext = ?, replay_protection_json = ?, mutual_tls_json = ?, allowed_cidrs_json = ?, redaction_json = ?, response_json = ?, forward_url = ?, max_messages = ?, expires_at = ?
+		 WHERE id = ? AND expires_at > ?`,
+		webhook.Username,
+		webhook.PasswordHash(),
+		webhook.TokenName,
+		webhook.TokenValueHash(),
+		webhook.HMACHeader,
+		encryptedHMACSecret,
+		hmacOptionsJSON,
+		string(webhook.Verifier),
+		publicKeySignatureJSON,
+		jwtJSON,
+		encryptedJWTSecret,
+		replayProtectionJSON,
+		mutualTLSJSON,
+		allowedCIDRsJSON,
+		redactionJSON,
+		responseJSON,
+		webhook.ForwardURL,
+		webhook.MaxMessages,
+		webhook.ExpiresAt.UTC().Format(sqliteTimeFormat),
+		webhook.ID,
+		now,
+	)
+	if err != nil {
+		return err
+	}
+
+	updatedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updatedRows == 0 {
+		return &WebhookNotFoundError{WebhookId: webhook.ID}
+	}
+
+	return trimMessages(s.db, webhook.ID)
+}
+
+// DeleteWebhook removes a webhook and its captured messages.
+func (s *SQLiteStore) DeleteWebhook(id string) (err error) {
+	now := time.Now().UTC().Format(sqliteTimeFormat)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rollbackErr := tx.Rollback(); err == nil && rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			err = rollbackErr
+		}
+	}()
+
+	exists, err := s.webhookExistsTx(tx, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &WebhookNotFoundError{WebhookId: id}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM messages WHERE webhook_id = ?`, id); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM webhooks WHERE id = ? AND expires_at > ?`, id, now); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 // InsertMessage inserts message for given webhook ID.
 func (s *SQLiteStore) InsertMessage(webhookID string, message *model.Message) (err error) {
 	headersJSON, err := json.Marshal(message.Headers)
@@ -201,6 +361,17 @@ func (s *SQLiteStore) InsertMessage(webhookID string, message *model.Message) (e
 		return err
 	}
 
+	forwardJSON, err := marshalForwardResult(message.Forward)
+	if err != nil {
+		return err
+	}
+
+	clientCertificateJSON, err := marshalClientCertificate(message.ClientCertificate)
+	if err != nil {
+		return err
+	}
+	tlsVersion, tlsCipherSuite, tlsServerName := tlsDetailColumns(message.TLS)
+
 	tx, err := s.db.Begin()
 	if err != nil {
 		return err
@@ -219,46 +390,219 @@ func (s *SQLiteStore) InsertMessage(webhookID string, message *model.Message) (e
 		return &WebhookNotFoundError{WebhookId: webhookID}
 	}
 
-	_, err = tx.Exec(
-		`INSERT INTO messages (webhook_id, method, path, query, payload, headers_json, status_code, error_message, received_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+	payload, storedHeaders := message.Body(), string(headersJSON)
+	if s.encryptMessages {
+		// The row ID is part of the associated data, so the ciphertext is
+		// written once the insert has assigned it.
+		payload, storedHeaders = []byte{}, ""
+	}
+
+	result, err := tx.Exec(
+		`INSERT INTO messages (webhook_id, method, path, query, payload, payload_encoding, headers_json, status_code, error_message, forward_json, forward_failed, received_at, encrypted, duplicate, client_certificate_json, remote_ip, protocol, host, content_length, tls_version, tls_cipher_suite, tls_server_name, processing_us)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		webhookID,
 		message.Method,
 		message.Path,
 		message.Query,
-		message.Payload,
-		string(headersJSON),
+		payload,
+		string(message.PayloadEncoding),
+		storedHeaders,
 		message.StatusCode,
 		message.ErrorMessage,
+		forwardJSON,
+		message.ForwardFailed(),
 		message.Time.Format(sqliteTimeFormat),
+		s.encryptMessages,
+		message.Duplicate,
+		clientCertificateJSON,
+		message.RemoteIP,
+		message.Protocol,
+		message.Host,
+		message.ContentLength,
+		tlsVersion,
+		tlsCipherSuite,
+		tlsServerName,
+		message.ProcessingMicros,
 	)
 	if err != nil {
 		return err
 	}
 
-	if _, err := tx.Exec(
+	messageID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if s.encryptMessages {
+		sealedPayload, sealedHeaders, err := s.cipher.sealMessage(webhookID, messageID, message.Body(), string(headersJSON))
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE messages SET payload = ?, headers_json = ? WHERE row_id = ?`, sealedPayload, sealedHeaders, messageID); err != nil {
+			return err
+		}
+	}
+
+	if err := trimMessages(tx, webhookID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	message.ID = messageID
+	return nil
+}
+
+// trimMessages deletes the oldest messages beyond the webhook's message cap.
+func trimMessages(db sqlExecer, webhookID string) error {
+	_, err := db.Exec(
 		`DELETE FROM messages
 		 WHERE webhook_id = ?
 		   AND row_id NOT IN (
 			SELECT row_id FROM messages
 			WHERE webhook_id = ?
 			ORDER BY row_id DESC
-			LIMIT ?
+			LIMIT (SELECT max_messages FROM webhooks WHERE id = ?)
 		   )`,
 		webhookID,
 		webhookID,
-		maxMessagesPerWebhook,
-	); err != nil {
+		webhookID,
+	)
+	return err
+}
+
+// GetMessage retrieves a single captured message of the given webhook.
+func (s *SQLiteStore) GetMessage(webhookID string, messageID int64) (*model.Message, error) {
+	exists, err := s.webhookExists(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, &WebhookNotFoundError{WebhookId: webhookID}
+	}
+
+	row := s.db.QueryRow(
+		`SELECT row_id, method, path, query, payload, headers_json, status_code, error_message, forward_json, received_at, encrypted, duplicate, client_certificate_json, remote_ip, protocol, host, content_length, tls_version, tls_cipher_suite, tls_server_name, processing_us
+		 FROM messages
+		 WHERE webhook_id = ? AND row_id = ?`,
+		webhookID,
+		messageID,
+	)
+
+	message, err := s.scanStoredMessage(row, webhookID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &MessageNotFoundError{WebhookId: webhookID, MessageId: messageID}
+		}
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// DeleteMessage removes a single captured message and its replay history.
+func (s *SQLiteStore) DeleteMessage(webhookID string, messageID int64) error {
+	exists, err := s.webhookExists(webhookID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &WebhookNotFoundError{WebhookId: webhookID}
+	}
+
+	result, err := s.db.Exec(`DELETE FROM messages WHERE webhook_id = ? AND row_id = ?`, webhookID, messageID)
+	if err != nil {
 		return err
 	}
 
-	return tx.Commit()
+	deletedCount, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deletedCount == 0 {
+		return &MessageNotFoundError{WebhookId: webhookID, MessageId: messageID}
+	}
+
+	return nil
+}
+
+// InsertReplayAttempt records the result of replaying a captured message.
+func (s *SQLiteStore) InsertReplayAttempt(webhookID string, attempt *model.ReplayAttempt) error {
+	if _, err := s.GetMessage(webhookID, attempt.MessageID); err != nil {
+		return err
+	}
+
+	result, err := s.db.Exec(
+		`INSERT INTO replay_attempts (message_row_id, target_url, status_code, latency_ms, response_body, error_message, attempted_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		attempt.MessageID,
+		attempt.TargetURL,
+		attempt.StatusCode,
+		attempt.LatencyMillis,
+		attempt.ResponseBody,
+		attempt.ErrorMessage,
+		attempt.Time.Format(sqliteTimeFormat),
+	)
+	if err != nil {
+		return err
+	}
+
+	attemptID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	attempt.ID = attemptID
+
+	return nil
+}
+
+// ListReplayAttempts lists replay attempts of a captured message, newest first.
+func (s *SQLiteStore) ListReplayAttempts(webhookID string, messageID int64) (attempts []*model.ReplayAttempt, err error) {
+	if _, err := s.GetMessage(webhookID, messageID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(
+		`SELECT row_id, message_row_id, target_url, status_code, latency_ms, response_body, error_message, attempted_at
+		 FROM replay_attempts
+		 WHERE message_row_id = ?
+		 ORDER BY row_id DESC`,
+		messageID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); err == nil && closeErr != nil {
+			err = closeErr
+		}
+	}()
+
+	attempts = []*model.ReplayAttempt{}
+	for rows.Next() {
+		var (
+			attempt     model.ReplayAttempt
+			attemptedAt string
+		)
+		if err := rows.Scan(&attempt.ID, &attempt.MessageID, &attempt.TargetURL, &attempt.StatusCode, &attempt.LatencyMillis, &attempt.ResponseBody, &attempt.ErrorMessage, &attemptedAt); err != nil {
+			return nil, err
+		}
+		attempt.Time, err = time.Parse(sqliteTimeFormat, attemptedAt)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, &attempt)
+	}
+
+	return attempts, rows.Err()
 }
 
 // GetMessagePageForWebhook retrieves a page of messages for given webhook ID.
-func (s *SQLiteStore) GetMessagePageForWebhook(webhookID string, page int, pageSize int, outcome model.MessageOutcome) (messagePage *model.MessagePage, err error) {
+func (s *SQLiteStore) GetMessagePageForWebhook(webhookID string, page int, pageSize int, filter model.MessageFilter) (messagePage *model.MessagePage, err error) {
 	page, pageSize = normalizePagination(page, pageSize)
-	outcome, _ = model.ParseMessageOutcome(string(outcome))
+	filter = model.NewMessageFilter(filter)
 
 	exists, err := s.webhookExists(webhookID)
 	if err != nil {
@@ -268,14 +612,22 @@ func (s *SQLiteStore) GetMessagePageForWebhook(webhookID string, page int, pageS
 		return nil, &WebhookNotFoundError{WebhookId: webhookID}
 	}
 
-	totalMessages, err := s.countMessagesForWebhook(webhookID, outcome)
+	if filtersInGo(filter) {
+		candidates, err := s.loadMessagesForWebhook(webhookID, filter, maxSearchCandidates+1, 0)
+		if err != nil {
+			return nil, err
+		}
+		return pageMatchingMessages(candidates, filter, page, pageSize), nil
+	}
+
+	totalMessages, err := s.countMessagesForWebhook(webhookID, filter)
 	if err != nil {
 		return nil, err
 	}
 
 	page, totalPages, offset := calculateMessagePage(page, pageSize, totalMessages)
 
-	messages, err := s.loadMessagesForWebhook(webhookID, pageSize, offset, outcome)
+	messages, err := s.loadMessagesForWebhook(webhookID, filter, pageSize, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -293,11 +645,11 @@ func (s *SQLiteStore) GetMessagePageForWebhook(webhookID string, page int, pageS
 	return messagePage, nil
 }
 
-func (s *SQLiteStore) countMessagesForWebhook(webhookID string, outcome model.MessageOutcome) (int, error) {
-	countQuery, countArgs := applyOutcomeFilter(
+func (s *SQLiteStore) countMessagesForWebhook(webhookID string, filter model.MessageFilter) (int, error) {
+	countQuery, countArgs := s.applyMessageFilter(
 		`SELECT COUNT(*) FROM messages WHERE webhook_id = ?`,
 		[]interface{}{webhookID},
-		outcome,
+		filter,
 	)
 
 	var totalMessages int
@@ -322,18 +674,19 @@ func calculateMessagePage(page int, pageSize int, totalMessages int) (int, int,
 	return page, totalPages, offset
 }
 
-func (s *SQLiteStore) loadMessagesForWebhook(webhookID string, pageSize int, offset int, outcome model.MessageOutcome) (messages []*model.Message, err error) {
-	messageQuery, messageArgs := applyOutcomeFilter(
-		`SELECT method, path, query, payload, headers_json, status_code, error_message, received_at
+// loadMessagesForWebhook loads up to limit matching messages, newest first.
+func (s *SQLiteStore) loadMessagesForWebhook(webhookID string, filter model.MessageFilter, limit int, offset int) (messages []*model.Message, err error) {
+	messageQuery, messageArgs := s.applyMessageFilter(
+		`SELECT row_id, method, path, query, payload, headers_json, status_code, error_message, forward_json, received_at, encrypted, duplicate, client_certificate_json, remote_ip, protocol, host, content_length, tls_version, tls_cipher_suite, tls_server_name, processing_us
 		 FROM messages
 		 WHERE webhook_id = ?`,
 		[]interface{}{webhookID},
-		outcome,
+		filter,
 	)
 	messageQuery += `
 		 ORDER BY row_id DESC
 		 LIMIT ? OFFSET ?`
-	messageArgs = append(messageArgs, pageSize, offset)
+	messageArgs = append(messageArgs, limit, offset)
 
 	rows, err := s.db.Query(messageQuery, messageArgs...)
 	if err != nil {
@@ -347,7 +700,7 @@ func (s *SQLiteStore) loadMessagesForWebhook(webhookID string, pageSize int, off
 
 	messages = []*model.Message{}
 	for rows.Next() {
-		message, scanErr := scanStoredMessage(rows)
+		message, scanErr := s.scanStoredMessage(rows, webhookID)
 		if scanErr != nil {
 			return nil, scanErr
 		}
@@ -361,22 +714,45 @@ func (s *SQLiteStore) loadMessagesForWebhook(webhookID string, pageSize int, off
 	return messages, nil
 }
 
-func scanStoredMessage(rows *sql.Rows) (*model.Message, error) {
+// scanStoredMessage reads a message row, decrypting payload and headers when
+// the row was stored encrypted.
+func (s *SQLiteStore) scanStoredMessage(scanner rowScanner, webhookID string) (*model.Message, error) {
 	var (
-		method       string
-		path         string
-		query        string
-		payload      string
-		headersJSON  string
-		statusCode   int
-		errorMessage string
-		receivedAt   string
+		rowID                 int64
+		method                string
+		path                  string
+		query                 string
+		payload               []byte
+		headersJSON           string
+		statusCode            int
+		errorMessage          string
+		forwardJSON           string
+		receivedAt            string
+		encrypted             bool
+		duplicate             bool
+		clientCertificateJSON string
+		remoteIP              string
+		protocol              string
+		host                  string
+		contentLength         int64
+		tlsVersion            string
+		tlsCipherSuite        string
+		tlsServerName         string
+		processingMicros      int64
 	)
 
-	if err := rows.Scan(&method, &path, &query, &payload, &headersJSON, &statusCode, &errorMessage, &receivedAt); err != nil {
+	if err := scanner.Scan(&rowID, &method, &path, &query, &payload, &headersJSON, &statusCode, &errorMessage, &forwardJSON, &receivedAt, &encrypted, &duplicate, &clientCertificateJSON, &remoteIP, &protocol, &host, &contentLength, &tlsVersion, &tlsCipherSuite, &tlsServerName, &processingMicros); err != nil {
 		return nil, err
 	}
 
+	if encrypted {
+		var err error
+		payload, headersJSON, err = s.cipher.openMessage(webhookID, rowID, payload, headersJSON)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	headers := map[string][]string{}
 	if headersJSON != "" && headersJSON != "null" {
 		if err := json.Unmarshal([]byte(headersJSON), &headers); err != nil {
@@ -384,42 +760,75 @@ func scanStoredMessage(rows *sql.Rows) (*model.Message, error) {
 		}
 	}
 
-	message := &model.Message{
-		Method:       method,
-		Path:         path,
-		Query:        query,
-		Payload:      payload,
-		Headers:      headers,
-		StatusCode:   statusCode,
-		ErrorMessage: errorMessage,
-	}
-	parsedTime, err := time.Parse(sqliteTimeFormat, receivedAt)
+	forward, err := unmarshalForwardResult(forwardJSON)
 	if err != nil {
 		return nil, err
 	}
-	message.Time = parsedTime
 
-	return message, nil
-}
+	clientCertificate, err := unmarshalClientCertificate(clientCertificateJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &model.Message{
+		ID:                rowID,
+		Method:            method,
+		Path:              path,
+		Query:             query,
+		Headers:           headers,
+		StatusCode:        statusCode,
+		ErrorMessage:      errorMessage,
+		Forward:           forward,
+		Duplicate:         duplicate,
+		RemoteIP:          remoteIP,
+		Protocol:          protocol,
+		Host:              host,
+		ContentLength:     contentLength,
+		TLS:               tlsDetailsFromColumns(tlsVersion, tlsCipherSuite, tlsServerName),
+		ClientCertificate: clientCertificate,
+		ProcessingMicros:  processingMicros,
+	}
+	message.SetBody(payload)
+	parsedTime, err := time.Parse(sqliteTimeFormat, receivedAt)
+	if err != nil {
+		return nil, err
+	}
+	message.Time = parsedTime
 
-func applyOutcomeFilter(baseQuery string, baseArgs []interface{}, outcome model.MessageOutcome) (string, []interface{}) {
-	query := baseQuery
-	args := append([]interface{}{}, baseArgs...)
+	return message, nil
+}
 
-	if querySuffix, queryArgs := outcomeQueryFilter(outcome); querySuffix != "" {
-		query += querySuffix
-		args = append(args, queryArgs...)
+// applyMessageFilter adds the column conditions of the filter and, when the
+// payload index is available, narrows text searches to indexed matches. JSON
+// filters are narrowed with json_extract where SQLite can express them.
+// Encrypted rows are not indexed and are matched after decryption instead.
+func (s *SQLiteStore) applyMessageFilter(baseQuery string, baseArgs []interface{}, filter model.MessageFilter) (string, []interface{}) {
+	query, args := applyMessageFilter(baseQuery, baseArgs, filter, sqliteReceivedAt)
+	if terms := model.SearchTerms(filter.Text); s.fullTextSearch && len(terms) > 0 {
+		query += ` AND (encrypted = 1 OR row_id IN (SELECT rowid FROM message_search WHERE message_search MATCH ?))`
+		args = append(args, fullTextQuery(terms))
+	}
+	if filter.JSON != nil {
+		if condition, conditionArgs, ok := sqliteJSONFilterCondition(filter.JSON); ok {
+			query += " AND " + condition
+			args = append(args, conditionArgs...)
+		}
 	}
 
 	return query, args
 }
 
+// sqliteReceivedAt compares received_at as a time, since RFC 3339 timestamps
+// with trimmed fractional seconds do not sort as text.
+func sqliteReceivedAt(operator string, bound time.Time) (string, interface{}) {
+	return "julianday(received_at) " + operator + " julianday(?)", bound.UTC().Format(sqliteTimeFormat)
+}
+
 func (s *SQLiteStore) init() error {
-	if _, err := s.db.Exec(sqliteSchema); err != nil {
+	if err := s.migrate(); err != nil {
 		return err
 	}
-
-	if _, err := s.db.Exec(sqliteIndexes); err != nil {
+	if err := s.initMessageSearch(); err != nil {
 		return err
 	}
 
@@ -460,19 +869,36 @@ type rowScanner interface {
 	Scan(dest ...interface{}) error
 }
 
+type sqlExecer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
 func (s *SQLiteStore) scanWebhook(scanner rowScanner) (*model.Webhook, error) {
 	var (
-		id                   string
-		username             string
-		passwordHash         string
-		tokenName            string
-		tokenValueHash       string
-		hmacHeader           string
-		hmacSecretCiphertext []byte
-		expiresAtRaw         string
+		id                     string
+		username               string
+		passwordHash           string
+		tokenName              string
+		tokenValueHash         string
+		hmacHeader             string
+		hmacSecretCiphertext   []byte
+		hmacOptionsJSON        string
+		verifier               string
+		publicKeySignatureJSON string
+		jwtJSON                string
+		jwtSecretCiphertext    []byte
+		replayProtectionJSON   string
+		mutualTLSJSON          string
+		allowedCIDRsJSON       string
+		redactionJSON          string
+		responseJSON           string
+		forwardURL             string
+		managementSecretHash   string
+		maxMessages            int
+		expiresAtRaw           string
 	)
 
-	if err := scanner.Scan(&id, &username, &passwordHash, &tokenName, &tokenValueHash, &hmacHeader, &hmacSecretCiphertext, &expiresAtRaw); err != nil {
+	if err := scanner.Scan(&id, &username, &passwordHash, &tokenName, &tokenValueHash, &hmacHeader, &hmacSecretCiphertext, &hmacOptionsJSON, &verifier, &publicKeySignatureJSON, &jwtJSON, &jwtSecretCiphertext, &replayProtectionJSON, &mutualTLSJSON, &allowedCIDRsJSON, &redactionJSON, &responseJSON, &forwardURL, &managementSecretHash, &maxMessages, &expiresAtRaw); err != nil {
 		return nil, err
 	}
 
@@ -490,123 +916,444 @@ func (s *SQLiteStore) scanWebhook(scanner rowScanner) (*model.Webhook, error) {
 		return nil, err
 	}
 
-	return model.NewStoredWebhook(id, username, passwordHash, tokenName, tokenValueHash, hmacHeader, hmacSecret, expiresAt), nil
-}
+	response, err := unmarshalWebhookResponse(responseJSON)
+	if err != nil {
+		return nil, err
+	}
 
-// DeleteExpiredWebhooks removes expired webhooks and their captured messages.
-func (s *SQLiteStore) DeleteExpiredWebhooks() (deletedCount int, err error) {
-	cutoff := time.Now().UTC().Format(sqliteTimeFormat)
-	tx, err := s.db.Begin()
+	hmacOptions, err := unmarshalHMACOptions(hmacOptionsJSON)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	defer func() {
-		if rollbackErr := tx.Rollback(); err == nil && rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
-			err = rollbackErr
+
+	publicKeySignature, err := unmarshalPublicKeySignature(publicKeySignatureJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	jwt, err := unmarshalJWTValidation(jwtJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	jwtSecret := ""
+	if len(jwtSecretCiphertext) > 0 {
+		jwtSecret, err = s.cipher.Decrypt(jwtSecretCiphertext)
+		if err != nil {
+			return nil, err
 		}
-	}()
+	}
 
-	if _, err := tx.Exec(
-		`DELETE FROM messages
-		 WHERE webhook_id IN (
-			SELECT id FROM webhooks WHERE expires_at <= ?
-		 )`,
-		cutoff,
-	); err != nil {
-		return 0, err
+	replayProtection, err := unmarshalReplayProtection(replayProtectionJSON)
+	if err != nil {
+		return nil, err
 	}
 
-	result, err := tx.Exec(`DELETE FROM webhooks WHERE expires_at <= ?`, cutoff)
+	mutualTLS, err := unmarshalMutualTLS(mutualTLSJSON)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if err := tx.Commit(); err != nil {
-		return 0, err
+	allowedCIDRs, err := unmarshalAllowedCIDRs(allowedCIDRsJSON)
+	if err != nil {
+		return nil, err
 	}
 
-	deletedRows, err := result.RowsAffected()
+	redaction, err := unmarshalRedaction(redactionJSON)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	deletedCount = int(deletedRows)
-	return deletedCount, nil
+	webhook := model.NewStoredWebhook(id, username, passwordHash, tokenName, tokenValueHash, hmacHeader, hmacSecret, expiresAt)
+	webhook.HMACOptions = hmacOptions
+	webhook.Verifier = model.Verifier(verifier)
+	webhook.PublicKeySignature = publicKeySignature
+	webhook.JWT = jwt
+	webhook.SetJWTSecret(jwtSecret)
+	webhook.ReplayProtection = replayProtection
+	webhook.MutualTLS = mutualTLS
+	webhook.AllowedCIDRs = allowedCIDRs
+	webhook.Redaction = redaction
+	webhook.Response = response
+	webhook.ForwardURL = forwardURL
+	webhook.MaxMessages = maxMessages
+	webhook.SetManagementSecretHash(managementSecretHash)
+
+	return webhook, nil
 }
 
-type secretCipher struct {
-	aead cipher.AEAD
+// encodeJWT serializes the JWT validation and encrypts its secret.
+func encodeJWT(secretCipher *secretCipher, webhook *model.Webhook) (string, []byte, error) {
+	jwtJSON, err := marshalJWTValidation(webhook.JWT)
+	if err != nil {
+		return "", nil, err
+	}
+	if webhook.JWTSecret() == "" {
+		return jwtJSON, nil, nil
+	}
+
+	encryptedSecret, err := secretCipher.Encrypt(webhook.JWTSecret())
+	if err != nil {
+		return "", nil, err
+	}
+
+	return jwtJSON, encryptedSecret, nil
 }
 
-func newSecretCipher(encryptionKey string) (*secretCipher, error) {
-	key, err := decodeEncryptionKey(encryptionKey)
+func marshalWebhookResponse(response *model.WebhookResponse) (string, error) {
+	if response == nil {
+		return "", nil
+	}
+
+	responseJSON, err := json.Marshal(response)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	block, err := aes.NewCipher(key)
+	return string(responseJSON), nil
+}
+
+func marshalHMACOptions(options *model.HMACOptions) (string, error) {
+	if options == nil {
+		return "", nil
+	}
+
+	optionsJSON, err := json.Marshal(options)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	aead, err := cipher.NewGCM(block)
+	return string(optionsJSON), nil
+}
+
+func marshalPublicKeySignature(signature *model.PublicKeySignature) (string, error) {
+	if signature == nil {
+		return "", nil
+	}
+
+	signatureJSON, err := json.Marshal(signature)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	return &secretCipher{aead: aead}, nil
+	return string(signatureJSON), nil
 }
 
-func (c *secretCipher) Encrypt(plaintext string) ([]byte, error) {
-	nonce := make([]byte, c.aead.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return nil, err
+func marshalJWTValidation(validation *model.JWTValidation) (string, error) {
+	if validation == nil {
+		return "", nil
+	}
+
+	validationJSON, err := json.Marshal(validation)
+	if err != nil {
+		return "", err
 	}
 
-	ciphertext := c.aead.Seal(nil, nonce, []byte(plaintext), nil)
-	return append(nonce, ciphertext...), nil
+	return string(validationJSON), nil
 }
 
-func (c *secretCipher) Decrypt(ciphertext []byte) (string, error) {
-	nonceSize := c.aead.NonceSize()
-	if len(ciphertext) < nonceSize {
-		return "", errors.New("ciphertext is too short")
+func marshalReplayProtection(protection *model.ReplayProtection) (string, error) {
+	if protection == nil {
+		return "", nil
 	}
 
-	nonce := ciphertext[:nonceSize]
-	encrypted := ciphertext[nonceSize:]
-	plaintext, err := c.aead.Open(nil, nonce, encrypted, nil)
+	protectionJSON, err := json.Marshal(protection)
 	if err != nil {
 		return "", err
 	}
 
-	return string(plaintext), nil
+	return string(protectionJSON), nil
 }
 
-func decodeEncryptionKey(encryptionKey string) ([]byte, error) {
-	trimmedKey := strings.TrimSpace(encryptionKey)
-	if trimmedKey == "" {
-		return nil, errors.New("encryption key must not be empty")
+func marshalMutualTLS(mutualTLS *model.MutualTLS) (string, error) {
+	if mutualTLS == nil {
+		return "", nil
 	}
 
-	decoders := []func(string) ([]byte, error){
-		base64.StdEncoding.DecodeString,
-		base64.RawStdEncoding.DecodeString,
-		hex.DecodeString,
+	mutualTLSJSON, err := json.Marshal(mutualTLS)
+	if err != nil {
+		return "", err
 	}
 
-	for _, decode := range decoders {
-		decoded, err := decode(trimmedKey)
-		if err != nil {
-			continue
-		}
+	return string(mutualTLSJSON), nil
+}
+
+func marshalAllowedCIDRs(cidrs []string) (string, error) {
+	if len(cidrs) == 0 {
+		return "", nil
+	}
+
+	cidrsJSON, err := json.Marshal(cidrs)
+	if err != nil {
+		return "", err
+	}
+
+	return string(cidrsJSON), nil
+}
+
+func marshalRedaction(redaction *model.Redaction) (string, error) {
+	if redaction == nil {
+		return "", nil
+	}
+
+	redactionJSON, err := json.Marshal(redaction)
+	if err != nil {
+		return "", err
+	}
+
+	return string(redactionJSON), nil
+}
+
+func marshalClientCertificate(certificate *model.ClientCertificate) (string, error) {
+	if certificate == nil {
+		return "", nil
+	}
+
+	certificateJSON, err := json.Marshal(certificate)
+	if err != nil {
+		return "", err
+	}
+
+	return string(certificateJSON), nil
+}
+
+// tlsDetailColumns splits TLS details into their message columns; plain HTTP
+// deliveries store empty strings.
+func tlsDetailColumns(details *model.TLSDetails) (string, string, string) {
+	if details == nil {
+		return "", "", ""
+	}
+
+	return details.Version, details.CipherSuite, details.ServerName
+}
+
+func tlsDetailsFromColumns(version string, cipherSuite string, serverName string) *model.TLSDetails {
+	if version == "" {
+		return nil
+	}
+
+	return &model.TLSDetails{Version: version, CipherSuite: cipherSuite, ServerName: serverName}
+}
+
+func marshalForwardResult(result *model.ForwardResult) (string, error) {
+	if result == nil {
+		return "", nil
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+
+	return string(resultJSON), nil
+}
+
+func unmarshalForwardResult(resultJSON string) (*model.ForwardResult, error) {
+	if resultJSON == "" || resultJSON == "null" {
+		return nil, nil
+	}
+
+	var result model.ForwardResult
+	if err := json.Unmarshal([]byte(resultJSON), &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func unmarshalWebhookResponse(responseJSON string) (*model.WebhookResponse, error) {
+	if responseJSON == "" || responseJSON == "null" {
+		return nil, nil
+	}
+
+	var response model.WebhookResponse
+	if err := json.Unmarshal([]byte(responseJSON), &response); err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+func unmarshalHMACOptions(optionsJSON string) (*model.HMACOptions, error) {
+	if optionsJSON == "" || optionsJSON == "null" {
+		return nil, nil
+	}
+
+	var options model.HMACOptions
+	if err := json.Unmarshal([]byte(optionsJSON), &options); err != nil {
+		return nil, err
+	}
+
+	return &options, nil
+}
+
+func unmarshalPublicKeySignature(signatureJSON string) (*model.PublicKeySignature, error) {
+	if signatureJSON == "" || signatureJSON == "null" {
+		return nil, nil
+	}
+
+	var signature model.PublicKeySignature
+	if err := json.Unmarshal([]byte(signatureJSON), &signature); err != nil {
+		return nil, err
+	}
+
+	return &signature, nil
+}
+
+func unmarshalJWTValidation(validationJSON string) (*model.JWTValidation, error) {
+	if validationJSON == "" || validationJSON == "null" {
+		return nil, nil
+	}
+
+	var validation model.JWTValidation
+	if err := json.Unmarshal([]byte(validationJSON), &validation); err != nil {
+		return nil, err
+	}
+
+	return &validation, nil
+}
+
+func unmarshalReplayProtection(protectionJSON string) (*model.ReplayProtection, error) {
+	if protectionJSON == "" || protectionJSON == "null" {
+		return nil, nil
+	}
+
+	var protection model.ReplayProtection
+	if err := json.Unmarshal([]byte(protectionJSON), &protection); err != nil {
+		return nil, err
+	}
+
+	return &protection, nil
+}
+
+func unmarshalMutualTLS(mutualTLSJSON string) (*model.MutualTLS, error) {
+	if mutualTLSJSON == "" || mutualTLSJSON == "null" {
+		return nil, nil
+	}
+
+	var mutualTLS model.MutualTLS
+	if err := json.Unmarshal([]byte(mutualTLSJSON), &mutualTLS); err != nil {
+		return nil, err
+	}
+
+	return &mutualTLS, nil
+}
+
+func unmarshalAllowedCIDRs(cidrsJSON string) ([]string, error) {
+	if cidrsJSON == "" || cidrsJSON == "null" {
+		return nil, nil
+	}
+
+	var cidrs []string
+	if err := json.Unmarshal([]byte(cidrsJSON), &cidrs); err != nil {
+		return nil, err
+	}
+
+	return cidrs, nil
+}
+
+func unmarshalRedaction(redactionJSON string) (*model.Redaction, error) {
+	if redactionJSON == "" || redactionJSON == "null" {
+		return nil, nil
+	}
+
+	var redaction model.Redaction
+	if err := json.Unmarshal([]byte(redactionJSON), &redaction); err != nil {
+		return nil, err
+	}
+
+	return &redaction, nil
+}
+
+func unmarshalClientCertificate(certificateJSON string) (*model.ClientCertificate, error) {
+	if certificateJSON == "" || certificateJSON == "null" {
+		return nil, nil
+	}
+
+	var certificate model.ClientCertificate
+	if err := json.Unmarshal([]byte(certificateJSON), &certificate); err != nil {
+		return nil, err
+	}
+
+	return &certificate, nil
+}
+
+// RememberDeliveryID records a delivery ID for the webhook and reports whether
+// it was new. IDs are kept until the webhook is deleted.
+func (s *SQLiteStore) RememberDeliveryID(webhookID string, deliveryID string) (bool, error) {
+	exists, err := s.webhookExists(webhookID)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		return false, &WebhookNotFoundError{WebhookId: webhookID}
+	}
 
-		if len(decoded) == 32 {
-			return decoded, nil
+	result, err := s.db.Exec(
+		`INSERT OR IGNORE INTO delivery_ids (webhook_id, delivery_id, received_at) VALUES (?, ?, ?)`,
+		webhookID,
+		deliveryID,
+		time.Now().UTC().Format(sqliteTimeFormat),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return inserted == 1, nil
+}
+
+// ForgetDeliveryID removes a remembered delivery ID, so a retry of a delivery
+// that could not be captured or forwarded is accepted again.
+func (s *SQLiteStore) ForgetDeliveryID(webhookID string, deliveryID string) error {
+	_, err := s.db.Exec(`DELETE FROM delivery_ids WHERE webhook_id = ? AND delivery_id = ?`, webhookID, deliveryID)
+
+	return err
+}
+
+// DeleteExpiredWebhooks removes expired webhooks and their captured messages.
+func (s *SQLiteStore) DeleteExpiredWebhooks() (deletedCount int, err error) {
+	cutoff := time.Now().UTC().Format(sqliteTimeFormat)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if rollbackErr := tx.Rollback(); err == nil && rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			err = rollbackErr
 		}
+	}()
+
+	if _, err := tx.Exec(
+		`DELETE FROM messages
+		 WHERE webhook_id IN (
+			SELECT id FROM webhooks WHERE expires_at <= ?
+		 )`,
+		cutoff,
+	); err != nil {
+		return 0, err
 	}
 
-	return nil, errors.New("encryption key must be base64 or hex encoded 32-byte data")
+	result, err := tx.Exec(`DELETE FROM webhooks WHERE expires_at <= ?`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	deletedRows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	deletedCount = int(deletedRows)
+	return deletedCount, nil
 }
 
 func normalizePagination(page int, pageSize int) (int, int) {
@@ -626,9 +1373,11 @@ func normalizePagination(page int, pageSize int) (int, int) {
 func outcomeQueryFilter(outcome model.MessageOutcome) (string, []interface{}) {
 	switch outcome {
 	case model.MessageOutcomeAccepted:
-		return " AND status_code < ?", []interface{}{400}
+		return " AND status_code < ? AND forward_failed = 0", []interface{}{400}
 	case model.MessageOutcomeRejected:
-		return " AND status_code >= ?", []interface{}{400}
+		return " AND status_code >= ? AND forward_failed = 0", []interface{}{400}
+	case model.MessageOutcomeFailed:
+		return " AND forward_failed = 1", nil
 	default:
 		return "", nil
 	}
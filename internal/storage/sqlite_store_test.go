@@ -2,6 +2,7 @@ package storage_test
 
 import (
 	"bytes"
+	"database/sql"
 	"fmt"
 	"net/http"
 	"os"
@@ -67,7 +68,7 @@ func TestSQLiteStorePersistsWebhooksAndMessages(t *testing.T) {
 	assert.True(t, reloadedWebhook.HasHeaderToken())
 	assert.True(t, reloadedWebhook.HasHMAC())
 
-	messagePage, err := reloadedStore.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageOutcomeAll)
+	messagePage, err := reloadedStore.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAll})
 	require.NoError(t, err)
 	require.Len(t, messagePage.Messages, 1)
 	assert.Equal(t, 1, messagePage.Page)
@@ -114,7 +115,7 @@ func TestSQLiteStorePersistsRejectedMessageResult(t *testing.T) {
 	message.MarkRejected(http.StatusUnauthorized, "Missing basic auth credentials")
 	require.NoError(t, store.InsertMessage(webhookID, message))
 
-	messagePage, err := store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageOutcomeAll)
+	messagePage, err := store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAll})
 	require.NoError(t, err)
 	require.Len(t, messagePage.Messages, 1)
 	assert.Equal(t, http.StatusUnauthorized, messagePage.Messages[0].StatusCode)
@@ -157,7 +158,7 @@ func TestSQLiteStoreHidesExpiredWebhook(t *testing.T) {
 	_, ok := err.(*storage.WebhookNotFoundError)
 	assert.True(t, ok)
 
-	messages, err := store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageOutcomeAll)
+	messages, err := store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAll})
 	require.Error(t, err)
 	assert.Nil(t, messages)
 }
@@ -201,7 +202,7 @@ func TestSQLiteStorePaginatesMessagesNewestFirst(t *testing.T) {
 	require.NoError(t, store.InsertMessage(webhookID, model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", `{"message":"second"}`, nil)))
 	require.NoError(t, store.InsertMessage(webhookID, model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", `{"message":"third"}`, nil)))
 
-	firstPage, err := store.GetMessagePageForWebhook(webhookID, 1, 2, model.MessageOutcomeAll)
+	firstPage, err := store.GetMessagePageForWebhook(webhookID, 1, 2, model.MessageFilter{Outcome: model.MessageOutcomeAll})
 	require.NoError(t, err)
 	require.Len(t, firstPage.Messages, 2)
 	assert.Equal(t, 3, firstPage.TotalMessages)
@@ -211,7 +212,7 @@ func TestSQLiteStorePaginatesMessagesNewestFirst(t *testing.T) {
 	assert.Equal(t, `{"message":"third"}`, firstPage.Messages[0].Payload)
 	assert.Equal(t, `{"message":"second"}`, firstPage.Messages[1].Payload)
 
-	secondPage, err := store.GetMessagePageForWebhook(webhookID, 2, 2, model.MessageOutcomeAll)
+	secondPage, err := store.GetMessagePageForWebhook(webhookID, 2, 2, model.MessageFilter{Outcome: model.MessageOutcomeAll})
 	require.NoError(t, err)
 	require.Len(t, secondPage.Messages, 1)
 	assert.False(t, secondPage.HasNextPage)
@@ -234,7 +235,7 @@ func TestSQLiteStoreClampsPageBeyondLastPage(t *testing.T) {
 	require.NoError(t, store.InsertMessage(webhookID, model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", `{"message":"second"}`, nil)))
 	require.NoError(t, store.InsertMessage(webhookID, model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", `{"message":"third"}`, nil)))
 
-	page, err := store.GetMessagePageForWebhook(webhookID, 999, 2, model.MessageOutcomeAll)
+	page, err := store.GetMessagePageForWebhook(webhookID, 999, 2, model.MessageFilter{Outcome: model.MessageOutcomeAll})
 	require.NoError(t, err)
 	require.Len(t, page.Messages, 1)
 	assert.Equal(t, 2, page.Page)
@@ -262,12 +263,12 @@ func TestSQLiteStoreFiltersMessagesByOutcome(t *testing.T) {
 	require.NoError(t, store.InsertMessage(webhookID, acceptedMessage))
 	require.NoError(t, store.InsertMessage(webhookID, rejectedMessage))
 
-	acceptedPage, err := store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageOutcomeAccepted)
+	acceptedPage, err := store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAccepted})
 	require.NoError(t, err)
 	require.Len(t, acceptedPage.Messages, 1)
 	assert.Equal(t, `{"message":"accepted"}`, acceptedPage.Messages[0].Payload)
 
-	rejectedPage, err := store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageOutcomeRejected)
+	rejectedPage, err := store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeRejected})
 	require.NoError(t, err)
 	require.Len(t, rejectedPage.Messages, 1)
 	assert.Equal(t, `{"message":"rejected"}`, rejectedPage.Messages[0].Payload)
@@ -289,10 +290,436 @@ func TestSQLiteStorePrunesMessagesBeyondMaximum(t *testing.T) {
 		require.NoError(t, store.InsertMessage(webhookID, model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", payload, nil)))
 	}
 
-	page, err := store.GetMessagePageForWebhook(webhookID, 1, 100, model.MessageOutcomeAll)
+	page, err := store.GetMessagePageForWebhook(webhookID, 1, 100, model.MessageFilter{Outcome: model.MessageOutcomeAll})
 	require.NoError(t, err)
 	require.Len(t, page.Messages, 100)
 	assert.Equal(t, 100, page.TotalMessages)
 	assert.Equal(t, `{"message":"100"}`, page.Messages[0].Payload)
 	assert.Equal(t, `{"message":"001"}`, page.Messages[len(page.Messages)-1].Payload)
 }
+
+func TestSQLiteStorePrunesMessagesBeyondWebhookMessageCap(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "webhook-receiver.db")
+	store, err := storage.NewSQLiteStore(storePath, testEncryptionKey)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+
+	webhook := model.NewWebhook("", "", "", "", "", "")
+	webhook.MaxMessages = 150
+	webhookID, err := store.InsertWebhook(webhook)
+	require.NoError(t, err)
+
+	for i := 0; i < 151; i++ {
+		payload := fmt.Sprintf(`{"message":"%03d"}`, i)
+		require.NoError(t, store.InsertMessage(webhookID, model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", payload, nil)))
+	}
+
+	storedWebhook, err := store.GetWebhook(webhookID)
+	require.NoError(t, err)
+	assert.Equal(t, 150, storedWebhook.MaxMessages)
+
+	page, err := store.GetMessagePageForWebhook(webhookID, 1, 100, model.MessageFilter{Outcome: model.MessageOutcomeAll})
+	require.NoError(t, err)
+	assert.Equal(t, 150, page.TotalMessages)
+	assert.Equal(t, `{"message":"150"}`, page.Messages[0].Payload)
+
+	storedWebhook.MaxMessages = 2
+	require.NoError(t, store.UpdateWebhook(storedWebhook))
+
+	page, err = store.GetMessagePageForWebhook(webhookID, 1, 100, model.MessageFilter{Outcome: model.MessageOutcomeAll})
+	require.NoError(t, err)
+	require.Len(t, page.Messages, 2)
+	assert.Equal(t, `{"message":"150"}`, page.Messages[0].Payload)
+	assert.Equal(t, `{"message":"149"}`, page.Messages[1].Payload)
+}
+
+func TestSQLiteStorePersistsConfiguredResponse(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "webhook-receiver.db")
+	store, err := storage.NewSQLiteStore(storePath, testEncryptionKey)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+
+	webhook := model.NewWebhookFromInput(&model.WebhookInput{
+		Response: &model.WebhookResponse{
+			StatusCode:  http.StatusAccepted,
+			ContentType: "application/json",
+			Headers:     map[string]string{"X-Ack": "received"},
+			Body:        `{"ok":true}`,
+		},
+	})
+	webhookID, err := store.InsertWebhook(webhook)
+	require.NoError(t, err)
+
+	reloadedWebhook, err := store.GetWebhook(webhookID)
+	require.NoError(t, err)
+	require.NotNil(t, reloadedWebhook.Response)
+	assert.Equal(t, http.StatusAccepted, reloadedWebhook.Response.StatusCode)
+	assert.Equal(t, "application/json", reloadedWebhook.Response.ContentType)
+	assert.Equal(t, map[string]string{"X-Ack": "received"}, reloadedWebhook.Response.Headers)
+	assert.Equal(t, `{"ok":true}`, reloadedWebhook.Response.Body)
+
+	plainWebhookID, err := store.InsertWebhook(model.NewWebhook("", "", "", "", "", ""))
+	require.NoError(t, err)
+	plainWebhook, err := store.GetWebhook(plainWebhookID)
+	require.NoError(t, err)
+	assert.Nil(t, plainWebhook.Response)
+}
+
+func TestSQLiteStorePersistsForwardingResults(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "webhook-receiver.db")
+	store, err := storage.NewSQLiteStore(storePath, testEncryptionKey)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+
+	webhookID, err := store.InsertWebhook(model.NewWebhookFromInput(&model.WebhookInput{ForwardURL: "https://example.com/webhooks"}))
+	require.NoError(t, err)
+	reloadedWebhook, err := store.GetWebhook(webhookID)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/webhooks", reloadedWebhook.ForwardURL)
+
+	forwardedMessage := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", `{"message":"forwarded"}`, nil)
+	forwardedMessage.MarkForwarded(&model.ForwardResult{
+		TargetURL:     "https://example.com/webhooks",
+		StatusCode:    http.StatusAccepted,
+		Headers:       map[string][]string{"X-Upstream": {"yes"}},
+		Body:          "queued",
+		LatencyMillis: 15,
+	})
+	failedMessage := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", `{"message":"failed"}`, nil)
+	failedMessage.MarkForwarded(&model.ForwardResult{TargetURL: "https://example.com/webhooks", ErrorMessage: "connection refused"})
+	require.NoError(t, store.InsertMessage(webhookID, forwardedMessage))
+	require.NoError(t, store.InsertMessage(webhookID, failedMessage))
+
+	storedMessage, err := store.GetMessage(webhookID, forwardedMessage.ID)
+	require.NoError(t, err)
+	require.NotNil(t, storedMessage.Forward)
+	assert.Equal(t, http.StatusAccepted, storedMessage.StatusCode)
+	assert.Equal(t, http.StatusAccepted, storedMessage.Forward.StatusCode)
+	assert.Equal(t, map[string][]string{"X-Upstream": {"yes"}}, storedMessage.Forward.Headers)
+	assert.Equal(t, "queued", storedMessage.Forward.Body)
+	assert.Equal(t, int64(15), storedMessage.Forward.LatencyMillis)
+
+	failedPage, err := store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeFailed})
+	require.NoError(t, err)
+	require.Len(t, failedPage.Messages, 1)
+	assert.Equal(t, `{"message":"failed"}`, failedPage.Messages[0].Payload)
+	assert.True(t, failedPage.Messages[0].ForwardFailed())
+
+	rejectedPage, err := store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeRejected})
+	require.NoError(t, err)
+	assert.Empty(t, rejectedPage.Messages)
+
+	acceptedPage, err := store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAccepted})
+	require.NoError(t, err)
+	require.Len(t, acceptedPage.Messages, 1)
+	assert.Equal(t, `{"message":"forwarded"}`, acceptedPage.Messages[0].Payload)
+}
+
+func TestNewSQLiteStoreAddsColumnsToExistingDatabase(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "webhook-receiver.db")
+	db, err := sql.Open("sqlite3", storePath)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+CREATE TABLE webhooks (
+	row_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	id TEXT NOT NULL UNIQUE,
+	username TEXT NOT NULL DEFAULT '',
+	password_hash TEXT NOT NULL DEFAULT '',
+	token_name TEXT NOT NULL DEFAULT '',
+	token_value_hash TEXT NOT NULL DEFAULT '',
+	hmac_header TEXT NOT NULL DEFAULT '',
+	hmac_secret_ciphertext BLOB,
+	expires_at TEXT NOT NULL
+);
+CREATE TABLE messages (
+	row_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	webhook_id TEXT NOT NULL,
+	method TEXT NOT NULL,
+	path TEXT NOT NULL,
+	query TEXT NOT NULL DEFAULT '',
+	payload TEXT NOT NULL,
+	headers_json TEXT NOT NULL DEFAULT '{}',
+	status_code INTEGER NOT NULL DEFAULT 200,
+	error_message TEXT NOT NULL DEFAULT '',
+	received_at TEXT NOT NULL,
+	FOREIGN KEY (webhook_id) REFERENCES webhooks(id)
+);
+INSERT INTO webhooks (id, expires_at) VALUES ('legacy', '2999-01-01T00:00:00Z');
+INSERT INTO messages (webhook_id, method, path, payload, received_at) VALUES ('legacy', 'POST', '/hooks/legacy', '{}', '2026-01-01T00:00:00Z');
+INSERT INTO messages (webhook_id, method, path, payload, received_at) VALUES ('legacy', 'POST', '/hooks/legacy', CAST(X'89504E47FF' AS TEXT), '2026-01-01T00:00:01Z');`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	store, err := storage.NewSQLiteStore(storePath, testEncryptionKey)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+
+	webhook, err := store.GetWebhook("legacy")
+	require.NoError(t, err)
+	assert.Nil(t, webhook.Response)
+	assert.Empty(t, webhook.ForwardURL)
+
+	page, err := store.GetMessagePageForWebhook("legacy", 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAccepted})
+	require.NoError(t, err)
+	require.Len(t, page.Messages, 2)
+	assert.Nil(t, page.Messages[1].Forward)
+	assert.Equal(t, model.PayloadEncodingUTF8, page.Messages[1].PayloadEncoding)
+	assert.Equal(t, "{}", page.Messages[1].Payload)
+	assert.Equal(t, model.PayloadEncodingBase64, page.Messages[0].PayloadEncoding)
+	assert.Equal(t, []byte{0x89, 'P', 'N', 'G', 0xff}, page.Messages[0].Body())
+
+	var payloadType, payloadEncoding string
+	require.NoError(t, store.Close())
+	db, err = sql.Open("sqlite3", storePath)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+	require.NoError(t, db.QueryRow(`SELECT typeof(payload), payload_encoding FROM messages WHERE received_at = '2026-01-01T00:00:01Z'`).Scan(&payloadType, &payloadEncoding))
+	assert.Equal(t, "blob", payloadType)
+	assert.Equal(t, "base64", payloadEncoding)
+}
+
+func TestSQLiteStorePersistsBinaryPayloads(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "webhook-receiver.db")
+	store, err := storage.NewSQLiteStore(storePath, testEncryptionKey)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+
+	webhookID, err := store.InsertWebhook(model.NewWebhook("", "", "", "", "", ""))
+	require.NoError(t, err)
+	body := []byte{0x00, 0x01, 0xfe, 0xff, 'a', 0x00}
+	message := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", string(body), nil)
+	require.NoError(t, store.InsertMessage(webhookID, message))
+
+	storedMessage, err := store.GetMessage(webhookID, message.ID)
+	require.NoError(t, err)
+	assert.True(t, storedMessage.Binary())
+	assert.Equal(t, body, storedMessage.Body())
+}
+
+func TestSQLiteStoreGetsSingleMessageByID(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "webhook-receiver.db")
+	store, err := storage.NewSQLiteStore(storePath, testEncryptionKey)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+
+	webhookID, err := store.InsertWebhook(model.NewWebhook("", "", "", "", "", ""))
+	require.NoError(t, err)
+	otherWebhookID, err := store.InsertWebhook(model.NewWebhook("", "", "", "", "", ""))
+	require.NoError(t, err)
+
+	message := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", `{"message":"first"}`, nil)
+	require.NoError(t, store.InsertMessage(webhookID, message))
+	require.NotZero(t, message.ID)
+
+	storedMessage, err := store.GetMessage(webhookID, message.ID)
+	require.NoError(t, err)
+	assert.Equal(t, message.ID, storedMessage.ID)
+	assert.Equal(t, `{"message":"first"}`, storedMessage.Payload)
+
+	_, err = store.GetMessage(otherWebhookID, message.ID)
+	var messageNotFound *storage.MessageNotFoundError
+	assert.ErrorAs(t, err, &messageNotFound)
+
+	_, err = store.GetMessage("missing", message.ID)
+	var webhookNotFound *storage.WebhookNotFoundError
+	assert.ErrorAs(t, err, &webhookNotFound)
+}
+
+func TestSQLiteStoreRecordsReplayAttempts(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "webhook-receiver.db")
+	store, err := storage.NewSQLiteStore(storePath, testEncryptionKey)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+
+	webhookID, err := store.InsertWebhook(model.NewWebhook("", "", "", "", "", ""))
+	require.NoError(t, err)
+	message := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", `{"message":"first"}`, nil)
+	require.NoError(t, store.InsertMessage(webhookID, message))
+
+	firstAttempt := model.NewReplayAttempt(message.ID, "https://example.com/first")
+	firstAttempt.StatusCode = http.StatusOK
+	firstAttempt.LatencyMillis = 12
+	firstAttempt.ResponseBody = "ok"
+	require.NoError(t, store.InsertReplayAttempt(webhookID, firstAttempt))
+	secondAttempt := model.NewReplayAttempt(message.ID, "https://example.com/second")
+	secondAttempt.ErrorMessage = "connection refused"
+	require.NoError(t, store.InsertReplayAttempt(webhookID, secondAttempt))
+
+	attempts, err := store.ListReplayAttempts(webhookID, message.ID)
+	require.NoError(t, err)
+	require.Len(t, attempts, 2)
+	assert.Equal(t, "https://example.com/second", attempts[0].TargetURL)
+	assert.Equal(t, "connection refused", attempts[0].ErrorMessage)
+	assert.Equal(t, "https://example.com/first", attempts[1].TargetURL)
+	assert.Equal(t, http.StatusOK, attempts[1].StatusCode)
+	assert.Equal(t, int64(12), attempts[1].LatencyMillis)
+	assert.Equal(t, "ok", attempts[1].ResponseBody)
+	assert.Equal(t, message.ID, attempts[1].MessageID)
+
+	err = store.InsertReplayAttempt(webhookID, model.NewReplayAttempt(message.ID+1, "https://example.com"))
+	var messageNotFound *storage.MessageNotFoundError
+	assert.ErrorAs(t, err, &messageNotFound)
+}
+
+func TestSQLiteStoreDeletesReplayAttemptsWithPrunedMessages(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "webhook-receiver.db")
+	store, err := storage.NewSQLiteStore(storePath, testEncryptionKey)
+	require.NoError(t, err)
+
+	webhookID, err := store.InsertWebhook(model.NewWebhook("", "", "", "", "", ""))
+	require.NoError(t, err)
+	oldestMessage := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", `{"message":"oldest"}`, nil)
+	require.NoError(t, store.InsertMessage(webhookID, oldestMessage))
+	require.NoError(t, store.InsertReplayAttempt(webhookID, model.NewReplayAttempt(oldestMessage.ID, "https://example.com")))
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, store.InsertMessage(webhookID, model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", "{}", nil)))
+	}
+	require.NoError(t, store.Close())
+
+	db, err := sql.Open("sqlite3", storePath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, db.Close())
+	})
+	var remainingAttempts int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM replay_attempts`).Scan(&remainingAttempts))
+	assert.Zero(t, remainingAttempts)
+}
+
+func TestSQLiteStoreDeletesSingleMessage(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "webhook-receiver.db")
+	store, err := storage.NewSQLiteStore(storePath, testEncryptionKey)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+
+	webhookID, err := store.InsertWebhook(model.NewWebhook("", "", "", "", "", ""))
+	require.NoError(t, err)
+	otherWebhookID, err := store.InsertWebhook(model.NewWebhook("", "", "", "", "", ""))
+	require.NoError(t, err)
+
+	deletedMessage := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", `{"message":"deleted"}`, nil)
+	keptMessage := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", `{"message":"kept"}`, nil)
+	require.NoError(t, store.InsertMessage(webhookID, deletedMessage))
+	require.NoError(t, store.InsertMessage(webhookID, keptMessage))
+	require.NoError(t, store.InsertReplayAttempt(webhookID, model.NewReplayAttempt(deletedMessage.ID, "https://example.com")))
+
+	var messageNotFound *storage.MessageNotFoundError
+	assert.ErrorAs(t, store.DeleteMessage(otherWebhookID, deletedMessage.ID), &messageNotFound)
+
+	require.NoError(t, store.DeleteMessage(webhookID, deletedMessage.ID))
+	_, err = store.GetMessage(webhookID, deletedMessage.ID)
+	assert.ErrorAs(t, err, &messageNotFound)
+	assert.ErrorAs(t, store.DeleteMessage(webhookID, deletedMessage.ID), &messageNotFound)
+
+	page, err := store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAll})
+	require.NoError(t, err)
+	require.Len(t, page.Messages, 1)
+	assert.Equal(t, keptMessage.ID, page.Messages[0].ID)
+
+	var webhookNotFound *storage.WebhookNotFoundError
+	assert.ErrorAs(t, store.DeleteMessage("missing", keptMessage.ID), &webhookNotFound)
+}
+
+func TestSQLiteStoreUpdatesWebhook(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "webhook-receiver.db")
+	store, err := storage.NewSQLiteStore(storePath, testEncryptionKey)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+
+	webhook := model.NewWebhook("alice", "password", "", "", "X-Signature", "secret")
+	managementSecret, err := webhook.IssueManagementSecret()
+	require.NoError(t, err)
+	webhookID, err := store.InsertWebhook(webhook)
+	require.NoError(t, err)
+
+	storedWebhook, err := store.GetWebhook(webhookID)
+	require.NoError(t, err)
+	assert.True(t, storedWebhook.ValidateManagementSecret(managementSecret))
+
+	newExpiry := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	emptyValue := ""
+	patch := &model.WebhookPatch{
+		Username:   &emptyValue,
+		Password:   &emptyValue,
+		ForwardURL: func() *string { value := "https://example.com/upstream"; return &value }(),
+	}
+	require.NoError(t, patch.Apply(storedWebhook, model.DefaultRetentionLimits(), time.Now()))
+	storedWebhook.ExpiresAt = newExpiry
+	storedWebhook.HMACHeader = "X-Rotated"
+	require.NoError(t, storedWebhook.Validate())
+	require.NoError(t, store.UpdateWebhook(storedWebhook))
+
+	updatedWebhook, err := store.GetWebhook(webhookID)
+	require.NoError(t, err)
+	assert.False(t, updatedWebhook.HasBasicAuth())
+	assert.Equal(t, "X-Rotated", updatedWebhook.HMACHeader)
+	assert.Equal(t, "secret", updatedWebhook.HMACSecret())
+	assert.Equal(t, "https://example.com/upstream", updatedWebhook.ForwardURL)
+	assert.True(t, updatedWebhook.ExpiresAt.Equal(newExpiry))
+	assert.True(t, updatedWebhook.ValidateManagementSecret(managementSecret))
+
+	missingWebhook := model.NewWebhook("", "", "", "", "", "")
+	missingWebhook.ID = "missing"
+	missingWebhook.ExpiresAt = newExpiry
+	var webhookNotFound *storage.WebhookNotFoundError
+	assert.ErrorAs(t, store.UpdateWebhook(missingWebhook), &webhookNotFound)
+}
+
+func TestSQLiteStoreDeletesWebhookWithMessages(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "webhook-receiver.db")
+	store, err := storage.NewSQLiteStore(storePath, testEncryptionKey)
+	require.NoError(t, err)
+
+	webhookID, err := store.InsertWebhook(model.NewWebhook("", "", "", "", "", ""))
+	require.NoError(t, err)
+	keptWebhookID, err := store.InsertWebhook(model.NewWebhook("", "", "", "", "", ""))
+	require.NoError(t, err)
+	message := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", "{}", nil)
+	require.NoError(t, store.InsertMessage(webhookID, message))
+	require.NoError(t, store.InsertReplayAttempt(webhookID, model.NewReplayAttempt(message.ID, "https://example.com")))
+	require.NoError(t, store.InsertMessage(keptWebhookID, model.NewMessage(http.MethodPost, "/hooks/"+keptWebhookID, "", "{}", nil)))
+
+	require.NoError(t, store.DeleteWebhook(webhookID))
+
+	var webhookNotFound *storage.WebhookNotFoundError
+	_, err = store.GetWebhook(webhookID)
+	assert.ErrorAs(t, err, &webhookNotFound)
+	assert.ErrorAs(t, store.DeleteWebhook(webhookID), &webhookNotFound)
+
+	keptPage, err := store.GetMessagePageForWebhook(keptWebhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAll})
+	require.NoError(t, err)
+	assert.Len(t, keptPage.Messages, 1)
+	require.NoError(t, store.Close())
+
+	db, err := sql.Open("sqlite3", storePath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, db.Close())
+	})
+	var remainingMessages, remainingAttempts int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM messages WHERE webhook_id = ?`, webhookID).Scan(&remainingMessages))
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM replay_attempts`).Scan(&remainingAttempts))
+	assert.Zero(t, remainingMessages)
+	assert.Zero(t, remainingAttempts)
+}
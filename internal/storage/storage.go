@@ -13,8 +13,24 @@ type WebhookStorage interface {
 	InsertWebhook(webhook *model.Webhook) (string, error)
 	GetWebhook(id string) (*model.Webhook, error)
 	ListWebhooks() ([]*model.Webhook, error)
+	UpdateWebhook(webhook *model.Webhook) error
+	DeleteWebhook(id string) error
 	InsertMessage(webhookID string, message *model.Message) error
-	GetMessagePageForWebhook(webhookID string, page int, pageSize int, outcome model.MessageOutcome) (*model.MessagePage, error)
+	GetMessage(webhookID string, messageID int64) (*model.Message, error)
+	DeleteMessage(webhookID string, messageID int64) error
+	GetMessagePageForWebhook(webhookID string, page int, pageSize int, filter model.MessageFilter) (*model.MessagePage, error)
+	InsertReplayAttempt(webhookID string, attempt *model.ReplayAttempt) error
+	ListReplayAttempts(webhookID string, messageID int64) ([]*model.ReplayAttempt, error)
+	RememberDeliveryID(webhookID string, deliveryID string) (bool, error)
+	ForgetDeliveryID(webhookID string, deliveryID string) error
+}
+
+// Store is a WebhookStorage owned by the server, which also expires webhooks
+// periodically and closes the store on shutdown.
+type Store interface {
+	WebhookStorage
+	DeleteExpiredWebhooks() (int, error)
+	Close() error
 }
 
 // WebhookNotFoundError indicates that a webhook does not exist.
@@ -26,3 +42,14 @@ type WebhookNotFoundError struct {
 func (e *WebhookNotFoundError) Error() string {
 	return fmt.Sprintf("Webhook with ID %s not found", e.WebhookId)
 }
+
+// MessageNotFoundError indicates that a captured message does not exist for a webhook.
+type MessageNotFoundError struct {
+	WebhookId string
+	MessageId int64
+}
+
+// Error implements the error interface.
+func (e *MessageNotFoundError) Error() string {
+	return fmt.Sprintf("Message with ID %d not found for webhook %s", e.MessageId, e.WebhookId)
+}
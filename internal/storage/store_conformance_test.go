@@ -0,0 +1,751 @@
+package storage_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/achawki/webhook-receiver/internal/model"
+	"github.com/achawki/webhook-receiver/internal/storage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// postgresDSNEnvName points the conformance suite at an existing PostgreSQL
+// server. Without it, a throwaway instance is started when initdb and pg_ctl
+// are installed.
+const postgresDSNEnvName = "WEBHOOK_RECEIVER_TEST_POSTGRES_DSN"
+
+type storeFactory func(t *testing.T) storage.Store
+
+func TestSQLiteStoreConformance(t *testing.T) {
+	runStoreConformance(t, func(t *testing.T) storage.Store {
+		store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "webhook-receiver.db"), testEncryptionKey)
+		require.NoError(t, err)
+		return store
+	})
+}
+
+func TestSQLiteStoreConformanceWithMessageEncryption(t *testing.T) {
+	runStoreConformance(t, func(t *testing.T) storage.Store {
+		store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "webhook-receiver.db"), testEncryptionKey, storage.WithMessageEncryption(true))
+		require.NoError(t, err)
+		return store
+	})
+}
+
+func TestMemoryStoreConformance(t *testing.T) {
+	runStoreConformance(t, func(t *testing.T) storage.Store {
+		return storage.NewMemoryStore()
+	})
+}
+
+func TestPostgresStoreConformance(t *testing.T) {
+	dsn := postgresTestDSN(t)
+	admin := openPostgres(t, dsn)
+
+	runStoreConformance(t, func(t *testing.T) storage.Store {
+		store, err := storage.NewPostgresStore(postgresTestSchema(t, admin, dsn), testEncryptionKey)
+		require.NoError(t, err)
+		return store
+	})
+}
+
+func runStoreConformance(t *testing.T, newStore storeFactory) {
+	open := func(t *testing.T) storage.Store {
+		store := newStore(t)
+		t.Cleanup(func() {
+			require.NoError(t, store.Close())
+		})
+		return store
+	}
+
+	t.Run("persists webhooks with encrypted HMAC secret", func(t *testing.T) {
+		store := open(t)
+		webhook := model.NewWebhookFromInput(&model.WebhookInput{
+			Username:   "alice",
+			Password:   "password",
+			HMACHeader: "X-Signature",
+			HMACSecret: "secret",
+			HMACOptions: &model.HMACOptions{
+				Algorithm: model.HMACSHA512,
+				Encoding:  model.SignatureEncodingBase64,
+				Template:  "{method}:{body}",
+			},
+			Response: &model.WebhookResponse{StatusCode: http.StatusAccepted, Body: "ok"},
+		})
+		managementSecret, err := webhook.IssueManagementSecret()
+		require.NoError(t, err)
+
+		webhookID, err := store.InsertWebhook(webhook)
+		require.NoError(t, err)
+
+		storedWebhook, err := store.GetWebhook(webhookID)
+		require.NoError(t, err)
+		assert.Equal(t, "alice", storedWebhook.Username)
+		assert.Equal(t, "secret", storedWebhook.HMACSecret())
+		assert.Equal(t, webhook.HMACOptions, storedWebhook.HMACOptions)
+		assert.Equal(t, http.StatusAccepted, storedWebhook.Response.StatusCode)
+		assert.Equal(t, model.DefaultMaxMessages, storedWebhook.MaxMessages)
+		assert.True(t, storedWebhook.ValidateManagementSecret(managementSecret))
+		assert.WithinDuration(t, webhook.ExpiresAt, storedWebhook.ExpiresAt, time.Millisecond)
+
+		webhooks, err := store.ListWebhooks()
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, webhookID, webhooks[0].ID)
+	})
+
+	t.Run("persists the signature verifier", func(t *testing.T) {
+		store := open(t)
+		webhook := model.NewWebhookFromInput(&model.WebhookInput{Verifier: model.VerifierStripe, HMACSecret: "whsec_secret"})
+		require.NoError(t, webhook.Validate())
+
+		webhookID, err := store.InsertWebhook(webhook)
+		require.NoError(t, err)
+
+		storedWebhook, err := store.GetWebhook(webhookID)
+		require.NoError(t, err)
+		assert.Equal(t, model.VerifierStripe, storedWebhook.Verifier)
+		assert.Equal(t, "Stripe-Signature", storedWebhook.HMACHeader)
+
+		storedWebhook.Verifier = model.VerifierGitHub
+		storedWebhook.HMACHeader = "X-Hub-Signature-256"
+		require.NoError(t, store.UpdateWebhook(storedWebhook))
+		updatedWebhook, err := store.GetWebhook(webhookID)
+		require.NoError(t, err)
+		assert.Equal(t, model.VerifierGitHub, updatedWebhook.Verifier)
+	})
+
+	t.Run("persists the public key signature", func(t *testing.T) {
+		store := open(t)
+		webhook := model.NewWebhookFromInput(&model.WebhookInput{
+			PublicKeySignature: &model.PublicKeySignature{
+				Algorithm: model.SignatureEd25519,
+				PublicKey: "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a",
+				Header:    "X-Signature-Ed25519",
+				Template:  "{header:X-Signature-Timestamp}{body}",
+			},
+		})
+		require.NoError(t, webhook.Validate())
+
+		webhookID, err := store.InsertWebhook(webhook)
+		require.NoError(t, err)
+
+		storedWebhook, err := store.GetWebhook(webhookID)
+		require.NoError(t, err)
+		assert.Equal(t, webhook.PublicKeySignature, storedWebhook.PublicKeySignature)
+
+		storedWebhook.PublicKeySignature = nil
+		require.NoError(t, store.UpdateWebhook(storedWebhook))
+		updatedWebhook, err := store.GetWebhook(webhookID)
+		require.NoError(t, err)
+		assert.Nil(t, updatedWebhook.PublicKeySignature)
+	})
+
+	t.Run("persists JWT validation with encrypted secret", func(t *testing.T) {
+		store := open(t)
+		webhook := model.NewWebhookFromInput(&model.WebhookInput{
+			JWTSecret: "jwt-secret",
+			JWT:       &model.JWTValidation{Issuer: "https://issuer.example", Audience: "webhooks"},
+		})
+		require.NoError(t, webhook.Validate())
+
+		webhookID, err := store.InsertWebhook(webhook)
+		require.NoError(t, err)
+
+		storedWebhook, err := store.GetWebhook(webhookID)
+		require.NoError(t, err)
+		assert.Equal(t, webhook.JWT, storedWebhook.JWT)
+		assert.Equal(t, "jwt-secret", storedWebhook.JWTSecret())
+
+		storedWebhook.JWT = &model.JWTValidation{JWKS: json.RawMessage(`{"keys":[{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}]}`)}
+		storedWebhook.SetJWTSecret("")
+		require.NoError(t, storedWebhook.Validate())
+		require.NoError(t, store.UpdateWebhook(storedWebhook))
+		updatedWebhook, err := store.GetWebhook(webhookID)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(storedWebhook.JWT.JWKS), string(updatedWebhook.JWT.JWKS))
+		assert.Empty(t, updatedWebhook.JWTSecret())
+	})
+
+	t.Run("persists mutual TLS and client certificates", func(t *testing.T) {
+		store := open(t)
+		fingerprint := strings.Repeat("ab", 32)
+		webhook := model.NewWebhookFromInput(&model.WebhookInput{
+			MutualTLS: &model.MutualTLS{Fingerprints: []string{fingerprint}},
+		})
+		webhookID, err := store.InsertWebhook(webhook)
+		require.NoError(t, err)
+
+		storedWebhook, err := store.GetWebhook(webhookID)
+		require.NoError(t, err)
+		assert.Equal(t, &model.MutualTLS{Required: true, Fingerprints: []string{fingerprint}}, storedWebhook.MutualTLS)
+
+		message := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", "payload", nil)
+		message.ClientCertificate = &model.ClientCertificate{Subject: "CN=sender", Issuer: "CN=ca", Fingerprint: fingerprint}
+		require.NoError(t, store.InsertMessage(webhookID, message))
+		storedMessage, err := store.GetMessage(webhookID, message.ID)
+		require.NoError(t, err)
+		assert.Equal(t, message.ClientCertificate, storedMessage.ClientCertificate)
+
+		storedWebhook.MutualTLS = nil
+		require.NoError(t, store.UpdateWebhook(storedWebhook))
+		updatedWebhook, err := store.GetWebhook(webhookID)
+		require.NoError(t, err)
+		assert.Nil(t, updatedWebhook.MutualTLS)
+	})
+
+	t.Run("persists allowed CIDRs", func(t *testing.T) {
+		store := open(t)
+		webhook := model.NewWebhookFromInput(&model.WebhookInput{AllowedCIDRs: []string{"192.0.2.0/24", "2001:db8::1"}})
+		webhookID, err := store.InsertWebhook(webhook)
+		require.NoError(t, err)
+
+		storedWebhook, err := store.GetWebhook(webhookID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"192.0.2.0/24", "2001:db8::1/128"}, storedWebhook.AllowedCIDRs)
+
+		storedWebhook.AllowedCIDRs = nil
+		require.NoError(t, store.UpdateWebhook(storedWebhook))
+		updatedWebhook, err := store.GetWebhook(webhookID)
+		require.NoError(t, err)
+		assert.Empty(t, updatedWebhook.AllowedCIDRs)
+	})
+
+	t.Run("persists redaction", func(t *testing.T) {
+		store := open(t)
+		webhook := model.NewWebhookFromInput(&model.WebhookInput{Redaction: &model.Redaction{
+			DropHeaders:  []string{"x-internal-*"},
+			MaskHeaders:  []string{"X-Api-Key"},
+			PayloadPaths: []string{"$.card.number"},
+		}})
+		webhookID, err := store.InsertWebhook(webhook)
+		require.NoError(t, err)
+
+		storedWebhook, err := store.GetWebhook(webhookID)
+		require.NoError(t, err)
+		assert.Equal(t, &model.Redaction{
+			DropHeaders:  []string{"X-Internal-*"},
+			MaskHeaders:  []string{"X-Api-Key"},
+			PayloadPaths: []string{"$.card.number"},
+		}, storedWebhook.Redaction)
+
+		storedWebhook.Redaction = nil
+		require.NoError(t, store.UpdateWebhook(storedWebhook))
+		updatedWebhook, err := store.GetWebhook(webhookID)
+		require.NoError(t, err)
+		assert.Nil(t, updatedWebhook.Redaction)
+	})
+
+	t.Run("persists request metadata", func(t *testing.T) {
+		store := open(t)
+		webhookID, err := store.InsertWebhook(model.NewWebhookFromInput(&model.WebhookInput{}))
+		require.NoError(t, err)
+
+		message := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", "payload", nil)
+		message.RemoteIP = "2001:db8::7"
+		message.Protocol = "HTTP/2.0"
+		message.Host = "receiver.example"
+		message.ContentLength = 7
+		message.TLS = &model.TLSDetails{Version: "TLS 1.3", CipherSuite: "TLS_AES_128_GCM_SHA256", ServerName: "receiver.example"}
+		message.ProcessingMicros = 1500
+		require.NoError(t, store.InsertMessage(webhookID, message))
+
+		plainMessage := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", "", nil)
+		plainMessage.Protocol = "HTTP/1.1"
+		require.NoError(t, store.InsertMessage(webhookID, plainMessage))
+
+		storedMessage, err := store.GetMessage(webhookID, message.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "2001:db8::7", storedMessage.RemoteIP)
+		assert.Equal(t, "HTTP/2.0", storedMessage.Protocol)
+		assert.Equal(t, "receiver.example", storedMessage.Host)
+		assert.Equal(t, int64(7), storedMessage.ContentLength)
+		assert.Equal(t, message.TLS, storedMessage.TLS)
+		assert.Equal(t, int64(1500), storedMessage.ProcessingMicros)
+
+		storedPlainMessage, err := store.GetMessage(webhookID, plainMessage.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "HTTP/1.1", storedPlainMessage.Protocol)
+		assert.Nil(t, storedPlainMessage.TLS)
+	})
+
+	t.Run("remembers delivery IDs per webhook", func(t *testing.T) {
+		store := open(t)
+		webhook := model.NewWebhookFromInput(&model.WebhookInput{
+			ReplayProtection: &model.ReplayProtection{DeliveryIDHeader: "X-Delivery", FlagDuplicates: true},
+		})
+		webhookID, err := store.InsertWebhook(webhook)
+		require.NoError(t, err)
+		otherID, err := store.InsertWebhook(model.NewWebhook("", "", "", "", "", ""))
+		require.NoError(t, err)
+
+		storedWebhook, err := store.GetWebhook(webhookID)
+		require.NoError(t, err)
+		assert.Equal(t, webhook.ReplayProtection, storedWebhook.ReplayProtection)
+
+		firstSeen, err := store.RememberDeliveryID(webhookID, "delivery-1")
+		require.NoError(t, err)
+		assert.True(t, firstSeen)
+		firstSeen, err = store.RememberDeliveryID(webhookID, "delivery-1")
+		require.NoError(t, err)
+		assert.False(t, firstSeen)
+		firstSeen, err = store.RememberDeliveryID(otherID, "delivery-1")
+		require.NoError(t, err)
+		assert.True(t, firstSeen)
+
+		require.NoError(t, store.ForgetDeliveryID(webhookID, "delivery-1"))
+		firstSeen, err = store.RememberDeliveryID(webhookID, "delivery-1")
+		require.NoError(t, err)
+		assert.True(t, firstSeen)
+		firstSeen, err = store.RememberDeliveryID(otherID, "delivery-1")
+		require.NoError(t, err)
+		assert.False(t, firstSeen)
+
+		var notFound *storage.WebhookNotFoundError
+		_, err = store.RememberDeliveryID("missing", "delivery-1")
+		assert.ErrorAs(t, err, &notFound)
+
+		duplicate := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", "again", nil)
+		duplicate.Duplicate = true
+		require.NoError(t, store.InsertMessage(webhookID, duplicate))
+		storedMessage, err := store.GetMessage(webhookID, duplicate.ID)
+		require.NoError(t, err)
+		assert.True(t, storedMessage.Duplicate)
+
+		require.NoError(t, store.DeleteWebhook(webhookID))
+		_, err = store.RememberDeliveryID(webhookID, "delivery-1")
+		assert.ErrorAs(t, err, &notFound)
+	})
+
+	t.Run("reports unknown webhooks", func(t *testing.T) {
+		store := open(t)
+		var notFound *storage.WebhookNotFoundError
+
+		_, err := store.GetWebhook("missing")
+		assert.ErrorAs(t, err, &notFound)
+		assert.ErrorAs(t, store.InsertMessage("missing", model.NewMessage(http.MethodPost, "/hooks/missing", "", "", nil)), &notFound)
+		_, err = store.GetMessagePageForWebhook("missing", 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAll})
+		assert.ErrorAs(t, err, &notFound)
+		assert.ErrorAs(t, store.DeleteWebhook("missing"), &notFound)
+	})
+
+	t.Run("expires webhooks", func(t *testing.T) {
+		store := open(t)
+		expired := model.NewWebhook("", "", "", "", "", "")
+		expired.ExpiresAt = time.Now().UTC().Add(-time.Minute)
+		expiredID, err := store.InsertWebhook(expired)
+		require.NoError(t, err)
+		activeID, err := store.InsertWebhook(model.NewWebhook("", "", "", "", "", ""))
+		require.NoError(t, err)
+
+		var notFound *storage.WebhookNotFoundError
+		_, err = store.GetWebhook(expiredID)
+		assert.ErrorAs(t, err, &notFound)
+
+		deletedCount, err := store.DeleteExpiredWebhooks()
+		require.NoError(t, err)
+		assert.Equal(t, 1, deletedCount)
+
+		_, err = store.GetWebhook(activeID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("pages messages newest first and filters by outcome", func(t *testing.T) {
+		store := open(t)
+		webhookID, err := store.InsertWebhook(model.NewWebhook("", "", "", "", "", ""))
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			message := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "a=b", fmt.Sprintf("accepted-%d", i), map[string][]string{"X-Index": {fmt.Sprint(i)}})
+			require.NoError(t, store.InsertMessage(webhookID, message))
+		}
+		rejected := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", "rejected", nil)
+		rejected.MarkRejected(http.StatusUnauthorized, "Missing basic auth credentials")
+		require.NoError(t, store.InsertMessage(webhookID, rejected))
+		failed := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", "failed", nil)
+		failed.MarkForwarded(&model.ForwardResult{TargetURL: "https://example.com", ErrorMessage: "connection refused"})
+		require.NoError(t, store.InsertMessage(webhookID, failed))
+
+		page, err := store.GetMessagePageForWebhook(webhookID, 1, 2, model.MessageFilter{Outcome: model.MessageOutcomeAll})
+		require.NoError(t, err)
+		assert.Equal(t, 5, page.TotalMessages)
+		assert.Equal(t, 3, page.TotalPages)
+		require.Len(t, page.Messages, 2)
+		assert.Equal(t, "failed", page.Messages[0].Payload)
+		assert.Equal(t, "rejected", page.Messages[1].Payload)
+
+		accepted, err := store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAccepted})
+		require.NoError(t, err)
+		require.Len(t, accepted.Messages, 3)
+		assert.Equal(t, "accepted-2", accepted.Messages[0].Payload)
+		assert.Equal(t, "a=b", accepted.Messages[0].Query)
+		assert.Equal(t, []string{"2"}, accepted.Messages[0].Headers["X-Index"])
+
+		rejectedPage, err := store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeRejected})
+		require.NoError(t, err)
+		require.Len(t, rejectedPage.Messages, 1)
+		assert.Equal(t, "Missing basic auth credentials", rejectedPage.Messages[0].ErrorMessage)
+
+		failedPage, err := store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeFailed})
+		require.NoError(t, err)
+		require.Len(t, failedPage.Messages, 1)
+		require.NotNil(t, failedPage.Messages[0].Forward)
+		assert.Equal(t, "connection refused", failedPage.Messages[0].Forward.ErrorMessage)
+	})
+
+	t.Run("trims messages to the webhook message cap", func(t *testing.T) {
+		store := open(t)
+		webhook := model.NewWebhook("", "", "", "", "", "")
+		webhook.MaxMessages = 3
+		webhookID, err := store.InsertWebhook(webhook)
+		require.NoError(t, err)
+
+		for i := 0; i < 5; i++ {
+			require.NoError(t, store.InsertMessage(webhookID, model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", fmt.Sprint(i), nil)))
+		}
+
+		page, err := store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAll})
+		require.NoError(t, err)
+		require.Len(t, page.Messages, 3)
+		assert.Equal(t, "4", page.Messages[0].Payload)
+		assert.Equal(t, "2", page.Messages[2].Payload)
+
+		storedWebhook, err := store.GetWebhook(webhookID)
+		require.NoError(t, err)
+		storedWebhook.MaxMessages = 1
+		require.NoError(t, store.UpdateWebhook(storedWebhook))
+
+		page, err = store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageFilter{Outcome: model.MessageOutcomeAll})
+		require.NoError(t, err)
+		require.Len(t, page.Messages, 1)
+		assert.Equal(t, "4", page.Messages[0].Payload)
+	})
+
+	t.Run("searches messages by request fields and payload text", func(t *testing.T) {
+		store := open(t)
+		webhookID, err := store.InsertWebhook(model.NewWebhook("", "", "", "", "", ""))
+		require.NoError(t, err)
+
+		start := time.Now().UTC().Add(-time.Hour)
+		deliveries := []struct {
+			method  string
+			path    string
+			payload string
+			headers map[string][]string
+			status  int
+		}{
+			{method: http.MethodPost, path: "/hooks/" + webhookID + "/stripe", payload: `{"type":"invoice.paid","amount":1200}`, headers: map[string][]string{"Stripe-Signature": {"t=1,v1=abc"}}, status: http.StatusOK},
+			{method: http.MethodPut, path: "/hooks/" + webhookID + "/github", payload: `{"action":"opened","title":"Invoice template"}`, headers: map[string][]string{"X-Github-Event": {"pull_request"}}, status: http.StatusOK},
+			{method: http.MethodPost, path: "/hooks/" + webhookID + "/stripe", payload: `{"type":"invoice.voided"}`, headers: map[string][]string{"Stripe-Signature": {"t=2,v1=def"}}, status: http.StatusUnauthorized},
+		}
+		for index, delivery := range deliveries {
+			message := model.NewMessage(delivery.method, delivery.path, "", delivery.payload, delivery.headers)
+			message.Time = start.Add(time.Duration(index) * 10 * time.Minute)
+			if delivery.status != http.StatusOK {
+				message.MarkRejected(delivery.status, "Invalid signature")
+			}
+			require.NoError(t, store.InsertMessage(webhookID, message))
+		}
+		binary := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", string([]byte{0xff, 'i', 'n', 'v'}), nil)
+		require.NoError(t, store.InsertMessage(webhookID, binary))
+
+		search := func(filter model.MessageFilter) []string {
+			page, err := store.GetMessagePageForWebhook(webhookID, 1, 25, filter)
+			require.NoError(t, err)
+			assert.Equal(t, len(page.Messages), page.TotalMessages)
+			payloads := []string{}
+			for _, message := range page.Messages {
+				payloads = append(payloads, message.Payload)
+			}
+			return payloads
+		}
+
+		assert.Equal(t, []string{deliveries[1].payload}, search(model.MessageFilter{Method: "put"}))
+		assert.Equal(t, []string{deliveries[2].payload, deliveries[0].payload}, search(model.MessageFilter{PathPrefix: "/hooks/" + webhookID + "/str"}))
+		assert.Empty(t, search(model.MessageFilter{PathPrefix: "/hooks/" + webhookID + "/%"}))
+		assert.Equal(t, []string{deliveries[2].payload}, search(model.MessageFilter{StatusCode: http.StatusUnauthorized}))
+		assert.Equal(t, []string{deliveries[2].payload, deliveries[0].payload}, search(model.MessageFilter{HeaderName: "stripe-signature"}))
+		assert.Equal(t, []string{deliveries[2].payload}, search(model.MessageFilter{HeaderName: "Stripe-Signature", HeaderValue: "V1=DEF"}))
+		assert.Equal(t, []string{deliveries[1].payload}, search(model.MessageFilter{Since: start.Add(5 * time.Minute), Until: start.Add(10 * time.Minute)}))
+		assert.Equal(t, []string{deliveries[2].payload, deliveries[1].payload, deliveries[0].payload}, search(model.MessageFilter{Text: "invoice"}))
+		assert.Equal(t, []string{deliveries[0].payload}, search(model.MessageFilter{Text: "invoice.paid"}))
+		assert.Equal(t, []string{deliveries[2].payload}, search(model.MessageFilter{Text: "INVOICE vo"}))
+		assert.Empty(t, search(model.MessageFilter{Text: "oice"}))
+		assert.Equal(t, []string{deliveries[0].payload}, search(model.MessageFilter{Outcome: model.MessageOutcomeAccepted, Text: "invoice", HeaderName: "Stripe-Signature"}))
+
+		page, err := store.GetMessagePageForWebhook(webhookID, 2, 1, model.MessageFilter{Text: "invoice"})
+		require.NoError(t, err)
+		assert.Equal(t, 3, page.TotalMessages)
+		assert.Equal(t, 3, page.TotalPages)
+		require.Len(t, page.Messages, 1)
+		assert.Equal(t, deliveries[1].payload, page.Messages[0].Payload)
+	})
+
+	t.Run("bounds searches matched after loading candidates", func(t *testing.T) {
+		store := open(t)
+		webhook := model.NewWebhook("", "", "", "", "", "")
+		webhook.MaxMessages = 2000
+		webhookID, err := store.InsertWebhook(webhook)
+		require.NoError(t, err)
+
+		headers := map[string][]string{"X-Event": {"ping"}}
+		require.NoError(t, store.InsertMessage(webhookID, model.NewMessage(http.MethodPut, "/hooks/"+webhookID, "", "oldest", headers)))
+		for i := 0; i < 1000; i++ {
+			require.NoError(t, store.InsertMessage(webhookID, model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", "newer", headers)))
+		}
+
+		page, err := store.GetMessagePageForWebhook(webhookID, 40, 25, model.MessageFilter{HeaderName: "X-Event"})
+		require.NoError(t, err)
+		assert.True(t, page.SearchTruncated)
+		assert.Equal(t, 1000, page.TotalMessages)
+		assert.Equal(t, "newer", page.Messages[len(page.Messages)-1].Payload)
+
+		page, err = store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageFilter{Method: http.MethodPut, HeaderName: "X-Event"})
+		require.NoError(t, err)
+		assert.False(t, page.SearchTruncated)
+		require.Len(t, page.Messages, 1)
+		assert.Equal(t, "oldest", page.Messages[0].Payload)
+
+		page, err = store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageFilter{})
+		require.NoError(t, err)
+		assert.False(t, page.SearchTruncated)
+		assert.Equal(t, 1001, page.TotalMessages)
+	})
+
+	t.Run("filters JSON payloads", func(t *testing.T) {
+		store := open(t)
+		webhookID, err := store.InsertWebhook(model.NewWebhook("", "", "", "", "", ""))
+		require.NoError(t, err)
+
+		payloads := []string{
+			`{"event":{"type":"invoice.paid"},"amount":1200,"live":true,"items":[{"sku":"a"},{"sku":"b"}]}`,
+			`{"event":{"type":"invoice.voided"},"amount":99.5,"live":false,"note":null,"key.with.dots":"x"}`,
+			`{"event":{"type":1},"amount":"1200"}`,
+			`invoice.paid`,
+			string([]byte{0xff, '{', '}'}),
+		}
+		for _, payload := range payloads {
+			require.NoError(t, store.InsertMessage(webhookID, model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", payload, nil)))
+		}
+
+		filterJSON := func(expression string) []string {
+			jsonFilter, err := model.ParseJSONFilter(expression)
+			require.NoError(t, err, expression)
+			page, err := store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageFilter{JSON: jsonFilter})
+			require.NoError(t, err, expression)
+			assert.Equal(t, len(page.Messages), page.TotalMessages, expression)
+			matched := []string{}
+			for _, message := range page.Messages {
+				matched = append(matched, message.Payload)
+			}
+			return matched
+		}
+
+		assert.Equal(t, []string{payloads[0]}, filterJSON(`event.type == "invoice.paid"`))
+		assert.Equal(t, []string{payloads[2], payloads[1]}, filterJSON(`$.event.type != "invoice.paid"`))
+		assert.Equal(t, []string{payloads[1], payloads[0]}, filterJSON(`event.type >= "invoice"`))
+		assert.Equal(t, []string{payloads[0]}, filterJSON(`amount > 100`))
+		assert.Equal(t, []string{payloads[1], payloads[0]}, filterJSON(`amount <= 1200.0`))
+		assert.Equal(t, []string{payloads[2]}, filterJSON(`amount == "1200"`))
+		assert.Equal(t, []string{payloads[1]}, filterJSON(`live == false`))
+		assert.Equal(t, []string{payloads[1]}, filterJSON(`note == null`))
+		assert.Empty(t, filterJSON(`note != null`))
+		assert.Equal(t, []string{payloads[1]}, filterJSON(`live != true`))
+		assert.Equal(t, []string{payloads[1]}, filterJSON(`$['key.with.dots']`))
+		assert.Equal(t, []string{payloads[0]}, filterJSON(`items[1].sku == "b"`))
+		assert.Equal(t, []string{payloads[0]}, filterJSON(`items[*].sku == "a"`))
+		assert.Equal(t, []string{payloads[2], payloads[1], payloads[0]}, filterJSON(`event`))
+		assert.Empty(t, filterJSON(`missing == null`))
+
+		jsonFilter, err := model.ParseJSONFilter(`amount > 0`)
+		require.NoError(t, err)
+		page, err := store.GetMessagePageForWebhook(webhookID, 2, 1, model.MessageFilter{JSON: jsonFilter})
+		require.NoError(t, err)
+		assert.Equal(t, 2, page.TotalMessages)
+		require.Len(t, page.Messages, 1)
+		assert.Equal(t, payloads[0], page.Messages[0].Payload)
+	})
+
+	t.Run("keeps payload search in step with trimmed and deleted messages", func(t *testing.T) {
+		store := open(t)
+		webhook := model.NewWebhook("", "", "", "", "", "")
+		webhook.MaxMessages = 2
+		webhookID, err := store.InsertWebhook(webhook)
+		require.NoError(t, err)
+
+		for _, payload := range []string{"alpha", "bravo", "charlie"} {
+			require.NoError(t, store.InsertMessage(webhookID, model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", payload, nil)))
+		}
+
+		page, err := store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageFilter{Text: "alpha"})
+		require.NoError(t, err)
+		assert.Empty(t, page.Messages)
+
+		page, err = store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageFilter{Text: "bravo"})
+		require.NoError(t, err)
+		require.Len(t, page.Messages, 1)
+		require.NoError(t, store.DeleteMessage(webhookID, page.Messages[0].ID))
+
+		page, err = store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageFilter{Text: "bravo"})
+		require.NoError(t, err)
+		assert.Empty(t, page.Messages)
+
+		page, err = store.GetMessagePageForWebhook(webhookID, 1, 25, model.MessageFilter{Text: "charlie"})
+		require.NoError(t, err)
+		assert.Len(t, page.Messages, 1)
+	})
+
+	t.Run("updates and deletes webhooks", func(t *testing.T) {
+		store := open(t)
+		webhook := model.NewWebhook("", "", "", "", "X-Signature", "secret")
+		webhookID, err := store.InsertWebhook(webhook)
+		require.NoError(t, err)
+		message := model.NewMessage(http.MethodPost, "/hooks/"+webhookID, "", "{}", nil)
+		require.NoError(t, store.InsertMessage(webhookID, message))
+		require.NoError(t, store.InsertReplayAttempt(webhookID, model.NewReplayAttempt(message.ID, "https://example.com")))
+
+		rotated := "rotated"
+		require.NoError(t, (&model.WebhookPatch{HMACSecret: &rotated}).Apply(webhook, model.DefaultRetentionLimits(), time.Now()))
+		newExpiry := time.Now().UTC().Add(time.Hour)
+		webhook.ExpiresAt = newExpiry
+		require.NoError(t, store.UpdateWebhook(webhook))
+
+		storedWebhook, err := store.GetWebhook(webhookID)
+		require.NoError(t, err)
+		assert.Equal(t, "rotated", storedWebhook.HMACSecret())
+		assert.WithinDuration(t, newExpiry, storedWebhook.ExpiresAt, time.Millisecond)
+
+		require.NoError(t, store.DeleteWebhook(webhookID))
+		var notFound *storage.WebhookNotFoundError
+		_, err = store.GetWebhook(webhookID)
+		assert.ErrorAs(t, err, &notFound)
+		assert.ErrorAs(t, store.UpdateWebhook(webhook), &notFound)
+	})
+
+	t.Run("gets and deletes single messages with replay attempts", func(t *testing.T) {
+		store := open(t)
+		webhookID, err := store.InsertWebhook(model.NewWebhook("", "", "", "", "", ""))
+		require.NoError(t, err)
+		body := []byte{0x00, 0xff, 0x10}
+		message := model.NewMessage(http.MethodPut, "/hooks/"+webhookID+"/sub", "", string(body), nil)
+		require.NoError(t, store.InsertMessage(webhookID, message))
+		require.NotZero(t, message.ID)
+
+		attempt := model.NewReplayAttempt(message.ID, "https://example.com")
+		attempt.StatusCode = http.StatusOK
+		attempt.ResponseBody = "ok"
+		require.NoError(t, store.InsertReplayAttempt(webhookID, attempt))
+		require.NotZero(t, attempt.ID)
+
+		storedMessage, err := store.GetMessage(webhookID, message.ID)
+		require.NoError(t, err)
+		assert.Equal(t, http.MethodPut, storedMessage.Method)
+		assert.Equal(t, body, storedMessage.Body())
+		assert.True(t, storedMessage.Binary())
+		assert.WithinDuration(t, message.Time, storedMessage.Time, time.Millisecond)
+
+		attempts, err := store.ListReplayAttempts(webhookID, message.ID)
+		require.NoError(t, err)
+		require.Len(t, attempts, 1)
+		assert.Equal(t, "ok", attempts[0].ResponseBody)
+
+		require.NoError(t, store.DeleteMessage(webhookID, message.ID))
+		var messageNotFound *storage.MessageNotFoundError
+		_, err = store.GetMessage(webhookID, message.ID)
+		assert.ErrorAs(t, err, &messageNotFound)
+		assert.ErrorAs(t, store.DeleteMessage(webhookID, message.ID), &messageNotFound)
+		_, err = store.ListReplayAttempts(webhookID, message.ID)
+		assert.ErrorAs(t, err, &messageNotFound)
+	})
+}
+
+// postgresTestDSN returns the configured test DSN or starts a throwaway local
+// PostgreSQL instance. The test is skipped when neither is available.
+func postgresTestDSN(t *testing.T) string {
+	if dsn := strings.TrimSpace(os.Getenv(postgresDSNEnvName)); dsn != "" {
+		return dsn
+	}
+
+	initdb, initdbErr := exec.LookPath("initdb")
+	pgCtl, pgCtlErr := exec.LookPath("pg_ctl")
+	if initdbErr != nil || pgCtlErr != nil {
+		t.Skipf("set %s or install initdb and pg_ctl to run the PostgreSQL conformance suite", postgresDSNEnvName)
+	}
+
+	dataDir := t.TempDir()
+	if output, err := exec.Command(initdb, "-D", dataDir, "-U", "postgres", "--auth=trust").CombinedOutput(); err != nil {
+		t.Skipf("could not initialize local PostgreSQL: %s: %s", err, output)
+	}
+
+	port := freeTCPPort(t)
+	options := fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1", port, dataDir)
+	if output, err := exec.Command(pgCtl, "-D", dataDir, "-o", options, "-w", "start").CombinedOutput(); err != nil {
+		t.Skipf("could not start local PostgreSQL: %s: %s", err, output)
+	}
+	t.Cleanup(func() {
+		_ = exec.Command(pgCtl, "-D", dataDir, "-m", "immediate", "stop").Run()
+	})
+
+	return fmt.Sprintf("host=127.0.0.1 port=%d user=postgres dbname=postgres sslmode=disable", port)
+}
+
+func openPostgres(t *testing.T, dsn string) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("pgx", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, db.Close())
+	})
+
+	return db
+}
+
+// postgresTestSchema creates a schema that is dropped after the test and
+// returns a DSN that uses it as search path.
+func postgresTestSchema(t *testing.T, admin *sql.DB, dsn string) string {
+	t.Helper()
+
+	schema := "conformance_" + strings.ReplaceAll(uuid.New().String(), "-", "")
+	_, err := admin.Exec(`CREATE SCHEMA ` + schema)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`)
+		assert.NoError(t, err)
+	})
+
+	return withSearchPath(dsn, schema)
+}
+
+func freeTCPPort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+	return port
+}
+
+func withSearchPath(dsn string, schema string) string {
+	if strings.Contains(dsn, "://") {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		return dsn + separator + "search_path=" + schema
+	}
+
+	return dsn + " search_path=" + schema
+}
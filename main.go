@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"os"
 	"os/signal"
 	"syscall"
 
@@ -10,6 +11,15 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "rotate-keys" {
+		rotated, err := receiver.RotateKeys(receiver.LoadConfigFromEnv())
+		if err != nil {
+			log.Fatalf("Could not rotate encryption keys: %s", err)
+		}
+		log.Printf("Re-encrypted %d stored row(s) with the current encryption key", rotated)
+		return
+	}
+
 	server := receiver.Setup()
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()